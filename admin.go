@@ -0,0 +1,243 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"gonav/internal/analyzer"
+	"gonav/internal/repo"
+)
+
+// finishedJobTTL is how long finished dependency jobs are kept before a cleanup
+// removes them
+const finishedJobTTL = time.Hour
+
+// maxFinishedJobs is the number of finished dependency jobs kept between eviction passes
+const maxFinishedJobs = 100
+
+// maxAnalysisEntries is the number of package and file analyses kept between eviction
+// passes
+const maxAnalysisEntries = 10000
+
+// AdminStats is the body of GET /api/admin/stats
+type AdminStats struct {
+	Repositories    map[string]interface{}        `json:"repositories"`    // Manager statistics
+	LoadedModules   int                           `json:"loadedModules"`   // Modules kept in memory by the analyzer
+	DependencyJobs  int                           `json:"dependencyJobs"`  // Dependency loading jobs, running or finished
+	AnalysisCache   analyzer.CacheStats           `json:"analysisCache"`   // Cached package and file analyses
+	DependencyQueue analyzer.DependencyQueueStats `json:"dependencyQueue"` // Downloads of the dependencies missing from analyses
+}
+
+// CleanupResult is the body of POST /api/admin/cleanup
+type CleanupResult struct {
+	Cache           *repo.EvictionResult `json:"cache"`
+	RemovedJobs     int                  `json:"removedJobs"`     // Finished dependency jobs removed
+	RemovedAnalyses int                  `json:"removedAnalyses"` // Analyses evicted from the analysis cache
+}
+
+// handleAdmin serves the administration endpoints under /api/admin/:
+//
+//	GET    /api/admin/stats                       server statistics
+//	GET    /api/admin/repos                       loaded repositories
+//	DELETE /api/admin/repos/{module@version}      unload a repository, ?evict=true deletes its files
+//	DELETE /api/admin/module-loads                drop loaded modules, ?repo={module@version} for one repository
+//	DELETE /api/admin/analysis-cache              clear cached analyses, ?repo={module@version} for one repository
+//	GET    /api/admin/jobs                        dependency loading jobs
+//	DELETE /api/admin/jobs/{id}                   cancel a dependency loading job
+//	POST   /api/admin/cleanup                     apply the cache policy, ?all=true clears the cache
+//
+// Requests must carry the admin token, if one is configured, as a bearer token.
+func (s *Server) handleAdmin(w http.ResponseWriter, r *http.Request) {
+	if !s.authorizeAdmin(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="gonav admin"`)
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Missing or invalid admin token", nil)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/admin/")
+	resource, arg, _ := strings.Cut(path, "/")
+	arg, err := url.QueryUnescape(arg)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Invalid URL encoding", nil)
+		return
+	}
+
+	switch {
+	case resource == "stats" && arg == "":
+		s.adminEndpoint(w, r, http.MethodGet, s.handleAdminStats)
+	case resource == "repos" && arg == "":
+		s.adminEndpoint(w, r, http.MethodGet, s.handleAdminRepos)
+	case resource == "repos":
+		s.adminEndpoint(w, r, http.MethodDelete, func(w http.ResponseWriter, r *http.Request) {
+			s.handleAdminUnload(w, r, arg)
+		})
+	case resource == "module-loads" && arg == "":
+		s.adminEndpoint(w, r, http.MethodDelete, s.handleAdminDropModuleLoads)
+	case resource == "analysis-cache" && arg == "":
+		s.adminEndpoint(w, r, http.MethodDelete, s.handleAdminClearAnalyses)
+	case resource == "jobs" && arg == "":
+		s.adminEndpoint(w, r, http.MethodGet, s.handleAdminJobs)
+	case resource == "jobs":
+		s.adminEndpoint(w, r, http.MethodDelete, func(w http.ResponseWriter, r *http.Request) {
+			s.handleAdminCancelJob(w, r, arg)
+		})
+	case resource == "cleanup" && arg == "":
+		s.adminEndpoint(w, r, http.MethodPost, s.handleAdminCleanup)
+	default:
+		writeError(w, http.StatusNotFound, CodeNotFound, fmt.Sprintf("Unknown admin endpoint: %s", r.URL.Path), nil)
+	}
+}
+
+// authorizeAdmin reports whether r may use the admin endpoints
+func (s *Server) authorizeAdmin(r *http.Request) bool {
+	if s.adminToken == "" {
+		return true
+	}
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return found && subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) == 1
+}
+
+// adminEndpoint calls handler if r uses method
+func (s *Server) adminEndpoint(w http.ResponseWriter, r *http.Request, method string, handler http.HandlerFunc) {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeMethodNotAllowed(w)
+		return
+	}
+	handler(w, r)
+}
+
+func (s *Server) handleAdminStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, AdminStats{
+		Repositories:    s.repoManager.Stats(),
+		LoadedModules:   s.analyzer.LoadedModules(),
+		DependencyJobs:  len(s.dependencyLoader.ListActiveJobs()),
+		AnalysisCache:   s.analysisCache.GetStats(),
+		DependencyQueue: s.dependencyQueue.GetStats(),
+	})
+}
+
+func (s *Server) handleAdminRepos(w http.ResponseWriter, r *http.Request) {
+	repos, err := s.repoManager.Repositories()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, fmt.Sprintf("Failed to list repositories: %v", err), nil)
+		return
+	}
+	writeJSON(w, repos)
+}
+
+func (s *Server) handleAdminUnload(w http.ResponseWriter, r *http.Request, moduleAtVersion string) {
+	if r.URL.Query().Get("evict") == "true" {
+		result, err := s.repoManager.EvictRepository(moduleAtVersion)
+		if err != nil {
+			writeAdminRepoError(w, moduleAtVersion, err)
+			return
+		}
+		s.logger.InfoContext(r.Context(), "Evicted repository", "module", moduleAtVersion, "freed_bytes", result.FreedBytes)
+		writeJSON(w, result)
+		return
+	}
+
+	if err := s.repoManager.Unload(moduleAtVersion); err != nil {
+		writeAdminRepoError(w, moduleAtVersion, err)
+		return
+	}
+	s.logger.InfoContext(r.Context(), "Unloaded repository", "module", moduleAtVersion)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleAdminDropModuleLoads(w http.ResponseWriter, r *http.Request) {
+	repoPath, ok := s.adminRepoPath(w, r)
+	if !ok {
+		return
+	}
+
+	dropped := s.analyzer.DropModuleLoads(repoPath)
+	s.logger.InfoContext(r.Context(), "Dropped module loads", "repo_path", repoPath, "modules", dropped)
+	writeJSON(w, map[string]int{"droppedModules": dropped})
+}
+
+func (s *Server) handleAdminClearAnalyses(w http.ResponseWriter, r *http.Request) {
+	repoPath, ok := s.adminRepoPath(w, r)
+	if !ok {
+		return
+	}
+
+	removed := s.analysisCache.Clear(repoPath)
+	s.logger.InfoContext(r.Context(), "Cleared analysis cache", "repo_path", repoPath, "analyses", removed)
+	writeJSON(w, map[string]int{"removedAnalyses": removed})
+}
+
+// adminRepoPath returns the path of the repository selected by the repo parameter of
+// r, or "" for every repository. It reports false, after writing the error, if the
+// repository is not loaded.
+func (s *Server) adminRepoPath(w http.ResponseWriter, r *http.Request) (string, bool) {
+	moduleAtVersion := r.URL.Query().Get("repo")
+	if moduleAtVersion == "" {
+		return "", true
+	}
+	repoPath := s.repoManager.GetRepositoryPath(moduleAtVersion)
+	if repoPath == "" {
+		writeRepoNotLoaded(w, moduleAtVersion)
+		return "", false
+	}
+	return repoPath, true
+}
+
+func (s *Server) handleAdminJobs(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.dependencyLoader.ListActiveJobs())
+}
+
+func (s *Server) handleAdminCancelJob(w http.ResponseWriter, r *http.Request, id string) {
+	if err := s.dependencyLoader.CancelLoading(id); err != nil {
+		if errors.Is(err, analyzer.ErrJobNotFound) {
+			writeError(w, http.StatusNotFound, CodeJobNotFound, err.Error(), nil)
+			return
+		}
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error(), nil)
+		return
+	}
+	s.logger.InfoContext(r.Context(), "Canceled dependency job", "job", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleAdminCleanup(w http.ResponseWriter, r *http.Request) {
+	evict := s.repoManager.Evict
+	if r.URL.Query().Get("all") == "true" {
+		evict = s.repoManager.ClearCache
+	}
+	result, err := evict()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, fmt.Sprintf("Failed to clean up the cache: %v", err), nil)
+		return
+	}
+	removedJobs := s.dependencyLoader.EvictJobs(finishedJobTTL, maxFinishedJobs)
+	removedAnalyses := s.analysisCache.Evict(s.repoManager.Policy().TTL, maxAnalysisEntries)
+	s.logger.InfoContext(r.Context(), "Cleaned up cache", "removed", len(result.Removed),
+		"freed_bytes", result.FreedBytes, "removed_jobs", removedJobs, "removed_analyses", removedAnalyses)
+	writeJSON(w, CleanupResult{Cache: result, RemovedJobs: removedJobs, RemovedAnalyses: removedAnalyses})
+}
+
+// writeAdminRepoError reports a failure to unload or evict a repository
+func writeAdminRepoError(w http.ResponseWriter, moduleAtVersion string, err error) {
+	switch {
+	case errors.Is(err, repo.ErrRepositoryNotLoaded):
+		writeRepoNotLoaded(w, moduleAtVersion)
+	case errors.Is(err, repo.ErrRepositoryInUse):
+		writeError(w, http.StatusConflict, CodeRepoInUse, fmt.Sprintf("Repository in use: %s", moduleAtVersion), nil)
+	default:
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error(), nil)
+	}
+}
+
+// writeJSON writes value as a JSON response
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(value)
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gonav/internal/logging"
+	"gonav/internal/repo"
+	"gonav/internal/testutil"
+)
+
+// newAdminTestServer returns a server with an empty cache requiring token for the
+// admin endpoints
+func newAdminTestServer(t *testing.T, token string) *Server {
+	t.Setenv("GOPROXY", "off")
+	t.Setenv("GOFLAGS", "-mod=mod")
+
+	manager, err := repo.NewManager(repo.WithIsolation(true), repo.WithCacheDir(t.TempDir()), repo.WithLogger(logging.Discard()))
+	require.NoError(t, err)
+	t.Cleanup(func() { manager.Cleanup() })
+
+	server := NewServer(manager, logging.Discard())
+	server.adminToken = token
+	return server
+}
+
+// newFixtureServer returns a server with moduleAtVersion, made of files, loaded from
+// a local proxy through the repository endpoint, like a client would
+func newFixtureServer(t *testing.T, moduleAtVersion string, files map[string]string) *Server {
+	modulePath, version, _ := strings.Cut(moduleAtVersion, "@")
+	proxyDir := t.TempDir()
+	testutil.WriteProxyModuleFiles(t, proxyDir, modulePath, version, files)
+	testutil.UseLocalProxy(t, proxyDir)
+
+	manager, err := repo.NewManager(repo.WithIsolation(true), repo.WithCacheDir(t.TempDir()), repo.WithLogger(logging.Discard()))
+	require.NoError(t, err)
+	t.Cleanup(func() { manager.Cleanup() })
+
+	server := NewServer(manager, logging.Discard())
+	var info repo.RepositoryInfo
+	getJSON(t, server, "/api/repo/"+moduleAtVersion, &info)
+	return server
+}
+
+// getJSON serves a GET request for path and decodes the JSON response into v
+func getJSON(t *testing.T, server *Server, path string, v any) {
+	recorder := httptest.NewRecorder()
+	server.setupRoutes().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(v))
+}
+
+// adminRequest serves an admin request with the given bearer token, if any
+func adminRequest(server *Server, method, path, token string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	recorder := httptest.NewRecorder()
+	server.setupRoutes().ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestAdminAuthorization(t *testing.T) {
+	server := newAdminTestServer(t, "s3cret")
+
+	recorder := adminRequest(server, http.MethodGet, "/api/admin/stats", "")
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	assert.Equal(t, CodeUnauthorized, decodeError(t, recorder).Code)
+	assert.NotEmpty(t, recorder.Header().Get("WWW-Authenticate"))
+
+	recorder = adminRequest(server, http.MethodGet, "/api/admin/stats", "wrong")
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+	recorder = adminRequest(server, http.MethodGet, "/api/admin/stats", "s3cret")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var stats AdminStats
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&stats))
+	assert.Equal(t, float64(0), stats.Repositories["loaded_repositories"])
+
+	// Without a token the endpoints are open
+	server.adminToken = ""
+	recorder = adminRequest(server, http.MethodGet, "/api/admin/stats", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestAdminEndpoints(t *testing.T) {
+	server := newAdminTestServer(t, "")
+
+	recorder := adminRequest(server, http.MethodGet, "/api/admin/repos", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, "[]", recorder.Body.String())
+
+	recorder = adminRequest(server, http.MethodDelete, "/api/admin/repos/example.com/lib@v1.0.0", "")
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Equal(t, CodeRepoNotLoaded, decodeError(t, recorder).Code)
+
+	recorder = adminRequest(server, http.MethodDelete, "/api/admin/repos/example.com/lib@v1.0.0?evict=true", "")
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	recorder = adminRequest(server, http.MethodDelete, "/api/admin/module-loads", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"droppedModules": 0}`, recorder.Body.String())
+
+	recorder = adminRequest(server, http.MethodDelete, "/api/admin/module-loads?repo=example.com/lib@v1.0.0", "")
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	recorder = adminRequest(server, http.MethodPost, "/api/admin/cleanup?all=true", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var cleanup CleanupResult
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&cleanup))
+	require.NotNil(t, cleanup.Cache)
+	assert.Empty(t, cleanup.Cache.Removed)
+
+	recorder = adminRequest(server, http.MethodGet, "/api/admin/cleanup", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+	assert.Equal(t, http.MethodPost, recorder.Header().Get("Allow"))
+
+	recorder = adminRequest(server, http.MethodGet, "/api/admin/unknown", "")
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Equal(t, CodeNotFound, decodeError(t, recorder).Code)
+}
+
+func TestAdminJobs(t *testing.T) {
+	server := newAdminTestServer(t, "")
+
+	_, err := server.dependencyLoader.StartDependencyLoading("job-1", []string{"example.com/missing@v1.0.0"})
+	require.NoError(t, err)
+
+	recorder := adminRequest(server, http.MethodGet, "/api/admin/jobs", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var jobs []map[string]interface{}
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&jobs))
+	require.Len(t, jobs, 1)
+	assert.Equal(t, "job-1", jobs[0]["id"])
+
+	recorder = adminRequest(server, http.MethodDelete, "/api/admin/jobs/job-1", "")
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+
+	recorder = adminRequest(server, http.MethodDelete, "/api/admin/jobs/job-1", "")
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Equal(t, CodeJobNotFound, decodeError(t, recorder).Code)
+
+	// Let the canceled job finish before the module cache is removed
+	time.Sleep(50 * time.Millisecond)
+}
+
+func TestAdminAnalysisCache(t *testing.T) {
+	server := newFixtureServer(t, "example.com/lib@v1.0.0", map[string]string{
+		"lib.go": "package lib\n\nfunc Hello() string { return \"hello\" }\n",
+	})
+	var fileInfo map[string]interface{}
+	getJSON(t, server, "/api/file/example.com/lib@v1.0.0/lib.go", &fileInfo)
+
+	var stats AdminStats
+	getJSON(t, server, "/api/admin/stats", &stats)
+	assert.Equal(t, 1, stats.AnalysisCache.TotalEntries)
+	assert.Equal(t, int64(0), stats.DependencyQueue.TotalRequests)
+
+	recorder := adminRequest(server, http.MethodDelete, "/api/admin/analysis-cache?repo=example.com/other@v1.0.0", "")
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Equal(t, CodeRepoNotLoaded, decodeError(t, recorder).Code)
+
+	recorder = adminRequest(server, http.MethodDelete, "/api/admin/analysis-cache?repo=example.com/lib@v1.0.0", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"removedAnalyses": 1}`, recorder.Body.String())
+
+	recorder = adminRequest(server, http.MethodDelete, "/api/admin/analysis-cache", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"removedAnalyses": 0}`, recorder.Body.String())
+	assert.Equal(t, 0, server.analysisCache.GetStats().TotalEntries)
+}
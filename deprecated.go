@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"gonav/internal/analyzer"
+)
+
+// DeprecatedReport is the body of GET /api/deprecated/{module@version}
+type DeprecatedReport struct {
+	Module string                    `json:"module"`
+	Filter string                    `json:"filter,omitempty"` // Module path filter, if any
+	Count  int                       `json:"count"`
+	Uses   []*analyzer.DeprecatedUse `json:"uses"`
+}
+
+// handleDeprecated lists the uses of deprecated declarations in a loaded repository,
+// in every module of the repository. ?module=example.com/dep restricts the report to
+// declarations of that module, to plan a migration before upgrading it.
+func (s *Server) handleDeprecated(w http.ResponseWriter, r *http.Request) {
+	if !beginGet(w, r) {
+		return
+	}
+
+	moduleAtVersion, err := decodeAPIPath(r, "/api/deprecated/")
+	if err != nil || !strings.Contains(moduleAtVersion, "@") {
+		writeError(w, http.StatusBadRequest, CodeInvalidModuleVersion, "Invalid module@version format", nil)
+		return
+	}
+	filter := r.URL.Query().Get("module")
+
+	s.logger.InfoContext(r.Context(), "Reporting deprecated API uses", "module", moduleAtVersion, "filter", filter)
+	defer s.repoManager.Acquire(moduleAtVersion)()
+
+	repoPath := s.repoManager.GetRepositoryPath(moduleAtVersion)
+	if repoPath == "" {
+		writeRepoNotLoaded(w, moduleAtVersion)
+		return
+	}
+
+	uses, err := s.analyzer.DeprecatedUses(r.Context(), repoPath, filter)
+	if err != nil {
+		s.writeOperationError(w, r, CodeAnalysisFailed, "Failed to analyze module", err)
+		return
+	}
+
+	writeJSON(w, DeprecatedReport{
+		Module: moduleAtVersion,
+		Filter: filter,
+		Count:  len(uses),
+		Uses:   uses,
+	})
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeprecatedEndpoint(t *testing.T) {
+	server := newFixtureServer(t, "example.com/lib@v1.0.0", map[string]string{
+		"legacy/legacy.go": `package legacy
+
+// Deprecated: use Connect.
+func Dial(addr string) {}
+
+func Connect(addr string) {}
+`,
+		"lib.go": `package lib
+
+import "example.com/lib/legacy"
+
+func Open() {
+	legacy.Dial("addr")
+	legacy.Connect("addr")
+}
+`,
+	})
+
+	var report DeprecatedReport
+	getJSON(t, server, "/api/deprecated/example.com/lib@v1.0.0", &report)
+	assert.Equal(t, "example.com/lib@v1.0.0", report.Module)
+	assert.Equal(t, 1, report.Count)
+	require.Len(t, report.Uses, 1)
+	use := report.Uses[0]
+	assert.Equal(t, "lib.go", use.File)
+	assert.Equal(t, 6, use.Line)
+	assert.Equal(t, "Dial", use.Name)
+	require.NotNil(t, use.Target)
+	assert.Equal(t, "Dial", use.Target.Name)
+
+	// Filtering on another module leaves nothing
+	getJSON(t, server, "/api/deprecated/example.com/lib@v1.0.0?module=example.com/dep", &report)
+	assert.Equal(t, "example.com/dep", report.Filter)
+	assert.Equal(t, 0, report.Count)
+	assert.Empty(t, report.Uses)
+}
+
+func TestDeprecatedEndpointErrors(t *testing.T) {
+	server := newAdminTestServer(t, "")
+	checkEndpointErrors(t, server.setupRoutes(), []endpointError{
+		{http.MethodGet, "/api/deprecated/example.com/lib@v1.0.0?module=example.com/dep", http.StatusNotFound, CodeRepoNotLoaded},
+		{http.MethodGet, "/api/deprecated/example.com/lib", http.StatusBadRequest, CodeInvalidModuleVersion},
+		{http.MethodPost, "/api/deprecated/example.com/lib@v1.0.0", http.StatusMethodNotAllowed, CodeMethodNotAllowed},
+	})
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+
+	"gonav/internal/analyzer"
+	"gonav/internal/env"
+	"gonav/internal/flight"
+	"gonav/internal/repo"
+)
+
+// Error codes returned in the "code" field of error responses. They are stable, so
+// clients can react to them instead of parsing messages.
+const (
+	CodeRepoNotLoaded        = "repo_not_loaded"        // The repository must be loaded with /api/repo first
+	CodeModuleNotFound       = "module_not_found"       // The module or version does not exist, or is not available offline
+	CodeInvalidModuleVersion = "invalid_module_version" // Malformed module path or module@version
+	CodeAnalysisFailed       = "analysis_failed"        // Packages could not be loaded or analyzed
+	CodeTimeout              = "timeout"                // The operation exceeded its configured timeout
+	CodeDownloadFailed       = "download_failed"        // Downloading the module failed for another reason
+	CodeListVersionsFailed   = "list_versions_failed"   // Listing the versions of a module failed
+	CodeInvalidRequest       = "invalid_request"        // Malformed URL
+	CodeInvalidPath          = "invalid_path"           // Malformed file or package path, or not a file or directory
+	CodeForbiddenPath        = "forbidden_path"         // Path resolving outside the repository
+	CodeFileNotFound         = "file_not_found"         // No such file or directory in the repository
+	CodeRepoInUse            = "repo_in_use"            // The repository is being served and cannot be unloaded
+	CodeJobNotFound          = "job_not_found"          // No dependency loading job with this ID
+	CodeTypeNotFound         = "type_not_found"         // No struct or interface with this name in the package
+	CodeUnauthorized         = "unauthorized"           // Missing or invalid admin token
+	CodeNotFound             = "not_found"              // Unknown endpoint
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeInternal             = "internal_error"
+)
+
+// ErrorResponse is the body of every error response
+type ErrorResponse struct {
+	Error APIError `json:"error"`
+}
+
+// APIError describes why a request failed
+type APIError struct {
+	Code    string        `json:"code"`
+	Message string        `json:"message"`
+	Details *ErrorDetails `json:"details,omitempty"`
+}
+
+// ErrorDetails carries the underlying cause of an error, when known
+type ErrorDetails struct {
+	Stderr       string                 `json:"stderr,omitempty"`       // Output of the failed go command
+	Command      string                 `json:"command,omitempty"`      // The failed go command
+	ImportErrors []analyzer.ImportError `json:"importErrors,omitempty"` // Errors reported while loading packages
+	Path         string                 `json:"path,omitempty"`         // Requested file or package path
+}
+
+// writeError writes an error response with the given status, code and message
+func writeError(w http.ResponseWriter, status int, code, message string, details *ErrorDetails) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: APIError{Code: code, Message: message, Details: details}})
+}
+
+// writeMethodNotAllowed rejects requests with an unsupported method
+func writeMethodNotAllowed(w http.ResponseWriter) {
+	writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed", nil)
+}
+
+// writeRepoNotLoaded rejects requests for a repository that is not loaded
+func writeRepoNotLoaded(w http.ResponseWriter, moduleAtVersion string) {
+	writeError(w, http.StatusNotFound, CodeRepoNotLoaded, fmt.Sprintf("Repository not loaded: %s", moduleAtVersion), nil)
+}
+
+// writePathError reports a path rejected by repo.ResolvePath. Malformed paths are bad
+// requests and paths leading outside the repository are forbidden.
+func (s *Server) writePathError(w http.ResponseWriter, r *http.Request, relPath string, err error) {
+	details := &ErrorDetails{Path: relPath}
+	switch {
+	case errors.Is(err, repo.ErrInvalidPath):
+		writeError(w, http.StatusBadRequest, CodeInvalidPath, err.Error(), details)
+	case errors.Is(err, repo.ErrPathOutsideRepository):
+		s.logger.WarnContext(r.Context(), "Refused access outside repository", "path", relPath, "error", err)
+		writeError(w, http.StatusForbidden, CodeForbiddenPath, fmt.Sprintf("Access outside repository is forbidden: %s", relPath), details)
+	case errors.Is(err, fs.ErrNotExist):
+		writeError(w, http.StatusNotFound, CodeFileNotFound, fmt.Sprintf("File not found: %s", relPath), details)
+	default:
+		writeError(w, http.StatusInternalServerError, CodeInternal, fmt.Sprintf("Failed to access %s: %v", relPath, err), details)
+	}
+}
+
+// writeOperationError reports a failed operation. Operations that ran out of time
+// are reported as 504 Gateway Timeout, and nothing is written for requests canceled
+// by the client, which is no longer listening. Errors without a more specific code
+// are reported with code. Panics are logged with their stack and reported as internal
+// errors, without revealing anything about the server.
+func (s *Server) writeOperationError(w http.ResponseWriter, r *http.Request, code, message string, err error) {
+	if errors.Is(err, context.Canceled) {
+		s.logger.InfoContext(r.Context(), "Request canceled by the client", "operation", message)
+		return
+	}
+
+	var panicErr *flight.PanicError
+	if errors.As(err, &panicErr) {
+		s.logger.ErrorContext(r.Context(), message, "code", CodeInternal, "status", http.StatusInternalServerError,
+			"error", err, "stack", string(panicErr.Stack))
+		writeError(w, http.StatusInternalServerError, CodeInternal, fmt.Sprintf("%s: internal error", message), nil)
+		return
+	}
+
+	status, code := operationStatus(code, err)
+	s.logger.ErrorContext(r.Context(), message, "code", code, "status", status, "error", err)
+	writeError(w, status, code, fmt.Sprintf("%s: %v", message, err), errorDetails(err))
+}
+
+// operationStatus returns the status and code reporting err
+func operationStatus(code string, err error) (int, string) {
+	var analysisErr *analyzer.AnalysisError
+	var panicErr *flight.PanicError
+	switch {
+	case errors.As(err, &panicErr):
+		return http.StatusInternalServerError, CodeInternal
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, CodeTimeout
+	case errors.Is(err, repo.ErrInvalidModuleVersion):
+		return http.StatusBadRequest, CodeInvalidModuleVersion
+	case errors.Is(err, repo.ErrModuleNotFound), errors.Is(err, repo.ErrNotAvailableOffline):
+		return http.StatusNotFound, CodeModuleNotFound
+	case errors.Is(err, analyzer.ErrTypeNotFound):
+		return http.StatusNotFound, CodeTypeNotFound
+	case errors.As(err, &analysisErr):
+		return http.StatusInternalServerError, CodeAnalysisFailed
+	}
+	return http.StatusInternalServerError, code
+}
+
+// errorDetails extracts the go command output and package errors wrapped in err
+func errorDetails(err error) *ErrorDetails {
+	details := &ErrorDetails{}
+
+	var cmdErr *env.CommandError
+	if errors.As(err, &cmdErr) {
+		details.Command = cmdErr.Command
+		details.Stderr = cmdErr.Stderr
+	}
+
+	var analysisErr *analyzer.AnalysisError
+	if errors.As(err, &analysisErr) && len(analysisErr.ImportErrors) > 0 {
+		details.ImportErrors = analysisErr.ImportErrors
+	}
+
+	if details.Command == "" && details.Stderr == "" && details.ImportErrors == nil {
+		return nil
+	}
+	return details
+}
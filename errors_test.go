@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gonav/internal/analyzer"
+	"gonav/internal/env"
+	"gonav/internal/flight"
+	"gonav/internal/logging"
+	"gonav/internal/repo"
+)
+
+// decodeError decodes the error envelope written to recorder
+func decodeError(t *testing.T, recorder *httptest.ResponseRecorder) APIError {
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	var response ErrorResponse
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+	return response.Error
+}
+
+// endpointError is a request expected to fail with status and an error code
+type endpointError struct {
+	method, path string
+	status       int
+	code         string
+}
+
+// checkEndpointErrors serves each request with handler and checks the error returned
+func checkEndpointErrors(t *testing.T, handler http.Handler, tests []endpointError) {
+	for _, tt := range tests {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(tt.method, tt.path, nil))
+		assert.Equal(t, tt.status, recorder.Code, tt.path)
+		assert.Equal(t, tt.code, decodeError(t, recorder).Code, tt.path)
+	}
+}
+
+func TestWriteOperationError(t *testing.T) {
+	var output bytes.Buffer
+	logger, err := logging.New(&output, "info", logging.FormatJSON)
+	require.NoError(t, err)
+	server := &Server{logger: logger}
+
+	cmdErr := &env.CommandError{
+		Command: "go mod download -json example.com/lib@v9.9.9",
+		Stderr:  "example.com/lib@v9.9.9: invalid version: unknown revision v9.9.9",
+		Err:     errors.New("exit status 1"),
+	}
+	importErrors := []analyzer.ImportError{
+		{ImportPath: "example.com/missing", Error: "could not import example.com/missing", Severity: "error"},
+	}
+
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		code    string
+		details *ErrorDetails
+	}{
+		{
+			name:   "timeout",
+			err:    env.ContextError(timedOutContext(t), "download of example.com/lib@v1.0.0", 0, nil),
+			status: http.StatusGatewayTimeout,
+			code:   CodeTimeout,
+		},
+		{
+			name:   "invalid module version",
+			err:    fmt.Errorf("%w: example.com/lib", repo.ErrInvalidModuleVersion),
+			status: http.StatusBadRequest,
+			code:   CodeInvalidModuleVersion,
+		},
+		{
+			name:    "module not found",
+			err:     fmt.Errorf("failed to download repository: %w: %w", repo.ErrModuleNotFound, cmdErr),
+			status:  http.StatusNotFound,
+			code:    CodeModuleNotFound,
+			details: &ErrorDetails{Command: cmdErr.Command, Stderr: cmdErr.Stderr},
+		},
+		{
+			name:   "not available offline",
+			err:    fmt.Errorf("module example.com/lib@v1.0.0 is %w", repo.ErrNotAvailableOffline),
+			status: http.StatusNotFound,
+			code:   CodeModuleNotFound,
+		},
+		{
+			name:   "type not found",
+			err:    fmt.Errorf("%w: Shape in package shapes", analyzer.ErrTypeNotFound),
+			status: http.StatusNotFound,
+			code:   CodeTypeNotFound,
+		},
+		{
+			name:    "analysis failed",
+			err:     fmt.Errorf("failed to load package util: %w", &analyzer.AnalysisError{Err: errors.New("no packages found"), ImportErrors: importErrors}),
+			status:  http.StatusInternalServerError,
+			code:    CodeAnalysisFailed,
+			details: &ErrorDetails{ImportErrors: importErrors},
+		},
+		{
+			name:    "other download failure",
+			err:     fmt.Errorf("failed to download repository: %w", cmdErr),
+			status:  http.StatusInternalServerError,
+			code:    CodeDownloadFailed,
+			details: &ErrorDetails{Command: cmdErr.Command, Stderr: cmdErr.Stderr},
+		},
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/api/repo/example.com/lib@v9.9.9", nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			server.writeOperationError(recorder, request, CodeDownloadFailed, "Failed to load repository", tt.err)
+
+			assert.Equal(t, tt.status, recorder.Code)
+			apiErr := decodeError(t, recorder)
+			assert.Equal(t, tt.code, apiErr.Code)
+			assert.Equal(t, "Failed to load repository: "+tt.err.Error(), apiErr.Message)
+			assert.Equal(t, tt.details, apiErr.Details)
+		})
+	}
+
+	// Panics are reported as internal errors, their stack only goes to the log
+	panicErr := &flight.PanicError{Value: "index out of range", Stack: []byte("goroutine 7 [running]:\n/srv/gonav/internal/analyzer/analyzer.go:42")}
+	recorder := httptest.NewRecorder()
+	server.writeOperationError(recorder, request, CodeAnalysisFailed, "Failed to analyze file", fmt.Errorf("loading module: %w", panicErr))
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.NotContains(t, recorder.Body.String(), "goroutine")
+	assert.NotContains(t, recorder.Body.String(), "/srv/gonav")
+	apiErr := decodeError(t, recorder)
+	assert.Equal(t, CodeInternal, apiErr.Code)
+	assert.Equal(t, "Failed to analyze file: internal error", apiErr.Message)
+	assert.Contains(t, output.String(), `"stack":"goroutine 7 [running]:\n/srv/gonav/internal/analyzer/analyzer.go:42"`)
+
+	// Nothing is written for canceled requests
+	recorder = httptest.NewRecorder()
+	server.writeOperationError(recorder, request, CodeAnalysisFailed, "Failed to analyze file", fmt.Errorf("loading canceled: %w", context.Canceled))
+	assert.Equal(t, 0, recorder.Body.Len())
+
+	// Failures are logged by the server's logger
+	assert.Contains(t, output.String(), `"msg":"Failed to load repository"`)
+	assert.Contains(t, output.String(), `"msg":"Request canceled by the client"`)
+}
+
+func TestWritePathError(t *testing.T) {
+	_, notExist := os.Stat(filepath.Join(t.TempDir(), "missing.go"))
+
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{fmt.Errorf("%w: contains \"..\"", repo.ErrInvalidPath), http.StatusBadRequest, CodeInvalidPath},
+		{fmt.Errorf("%w: secret.go", repo.ErrPathOutsideRepository), http.StatusForbidden, CodeForbiddenPath},
+		{fmt.Errorf("failed to resolve missing.go: %w", notExist), http.StatusNotFound, CodeFileNotFound},
+	}
+
+	server := &Server{logger: slog.New(slog.DiscardHandler)}
+	request := httptest.NewRequest(http.MethodGet, "/api/file/example.com/lib@v1.0.0/some/path.go", nil)
+	for _, tt := range tests {
+		recorder := httptest.NewRecorder()
+		server.writePathError(recorder, request, "some/path.go", tt.err)
+
+		assert.Equal(t, tt.status, recorder.Code, tt.err.Error())
+		apiErr := decodeError(t, recorder)
+		assert.Equal(t, tt.code, apiErr.Code)
+		require.NotNil(t, apiErr.Details)
+		assert.Equal(t, "some/path.go", apiErr.Details.Path)
+	}
+}
+
+// timedOutContext returns a context whose deadline has passed
+func timedOutContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	t.Cleanup(cancel)
+	<-ctx.Done()
+	return ctx
+}
+
+func TestPathConfinementEndpoints(t *testing.T) {
+	server := newFixtureServer(t, "example.com/lib@v1.0.0", map[string]string{
+		"lib.go":     "package lib\n",
+		"pkg/pkg.go": "package pkg\n",
+	})
+
+	// Links escaping the repository, as a malicious module could ship them
+	repoPath := server.repoManager.GetRepositoryPath("example.com/lib@v1.0.0")
+	require.NotEmpty(t, repoPath)
+	secret := filepath.Join(t.TempDir(), "secret.go")
+	require.NoError(t, os.WriteFile(secret, []byte("package secret\n"), 0644))
+	require.NoError(t, os.Chmod(repoPath, 0755))
+	require.NoError(t, os.Symlink(secret, filepath.Join(repoPath, "secret.go")))
+	require.NoError(t, os.Symlink(filepath.Dir(secret), filepath.Join(repoPath, "outside")))
+
+	const file = "/api/file/example.com/lib@v1.0.0/"
+	const pkg = "/api/package/example.com/lib@v1.0.0/"
+	checkEndpointErrors(t, server.setupRoutes(), []endpointError{
+		// Encoded dot segments reach the handlers, which decode them again
+		{http.MethodGet, file + "%2e%2e/%2e%2e/secret.go", http.StatusBadRequest, CodeInvalidPath},
+		{http.MethodGet, file + "%252e%252e%252fsecret.go", http.StatusBadRequest, CodeInvalidPath},
+		{http.MethodGet, file + "pkg%252f..%252f..%252fsecret.go", http.StatusBadRequest, CodeInvalidPath},
+		{http.MethodGet, file + "%252Fetc%252Fpasswd", http.StatusBadRequest, CodeInvalidPath},
+		{http.MethodGet, file + "%2Fetc%2Fpasswd", http.StatusBadRequest, CodeInvalidPath},
+		{http.MethodGet, file + "..%5Csecret.go", http.StatusBadRequest, CodeInvalidPath},
+		{http.MethodGet, file + "secret.go", http.StatusForbidden, CodeForbiddenPath},
+		{http.MethodGet, file + "outside/secret.go", http.StatusForbidden, CodeForbiddenPath},
+		{http.MethodGet, file + "missing+..%252fsecret.go", http.StatusNotFound, CodeFileNotFound},
+		{http.MethodGet, pkg + "%2e%2e", http.StatusBadRequest, CodeInvalidPath},
+		{http.MethodGet, pkg + "%252e%252e", http.StatusBadRequest, CodeInvalidPath},
+		{http.MethodGet, pkg + "pkg%252f..%252f..", http.StatusBadRequest, CodeInvalidPath},
+		{http.MethodGet, pkg + "%252Fetc", http.StatusBadRequest, CodeInvalidPath},
+		{http.MethodGet, pkg + "outside", http.StatusForbidden, CodeForbiddenPath},
+	})
+
+	// The router redirects literal dot segments to the clean path instead of serving them
+	for _, path := range []string{file + "../secret.go", pkg + "../.."} {
+		recorder := httptest.NewRecorder()
+		server.setupRoutes().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusTemporaryRedirect, recorder.Code, path)
+	}
+
+	// Mounted without the router, the handlers reject them themselves
+	for path, handler := range map[string]http.HandlerFunc{
+		file + "../secret.go": server.handleFile,
+		pkg + "../..":         server.handlePackage,
+	} {
+		recorder := httptest.NewRecorder()
+		handler(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, path)
+		assert.Equal(t, CodeInvalidPath, decodeError(t, recorder).Code, path)
+	}
+}
@@ -112,7 +112,7 @@ function App() {
         console.log(`Cached package: ${packagePath} with ${Object.keys(packageInfo.symbols || {}).length} symbols`)
         return packageInfo
       } else {
-        console.error('Failed to analyze package:', packageInfo.error)
+        console.error('Failed to analyze package:', packageInfo.error?.message)
         return null
       }
     } catch (error) {
@@ -194,7 +194,7 @@ function App() {
         // Note: Don't add breadcrumb here - this is for history navigation
       } else {
         console.error('Failed to load file:', data.error)
-        alert(`Failed to load file: ${data.error}`)
+        alert(`Failed to load file: ${data.error?.message}`)
       }
     } catch (error) {
       console.error('Failed to load file:', error)
@@ -226,7 +226,7 @@ function App() {
           await navigateWithoutHistory(breadcrumb.filePath, breadcrumb.line, breadcrumb.moduleAtVersion)
         }, 100)
       } else {
-        alert(`Failed to switch to external repository: ${repoData.error}`)
+        alert(`Failed to switch to external repository: ${repoData.error?.message}`)
       }
     } catch (error) {
       console.error('Error navigating to external repo from history:', error)
@@ -261,7 +261,7 @@ function App() {
         }
       } else {
         console.error('Failed to load file:', data.error)
-        alert(`Failed to load file: ${data.error}`)
+        alert(`Failed to load file: ${data.error?.message}`)
       }
     } catch (error) {
       console.error('Failed to load file:', error)
@@ -352,7 +352,7 @@ function App() {
               await handleFileSelect(symbol.file, symbol.line, true, moduleAtVersion, symbolName, clickLine)
             }, 100) // Small delay to ensure state updates
           } else {
-            alert(`Failed to switch to external repository: ${repoData.error}`)
+            alert(`Failed to switch to external repository: ${repoData.error?.message}`)
           }
         }
       } else {
@@ -403,7 +403,7 @@ function App() {
           await handleFileSelect(targetFile, targetLine, true, moduleAtVersion, symbolName, clickLine)
         }, 100) // Small delay to ensure state updates
       } else {
-        alert(`Failed to switch to external repository: ${repoData.error}`)
+        alert(`Failed to switch to external repository: ${repoData.error?.message}`)
       }
     } catch (error) {
       console.error('Error in direct external navigation:', error)
@@ -24,7 +24,7 @@ function RepositoryLoader({ onRepositoryLoad, currentModule = '' }) {
           moduleAtVersion: moduleInput
         })
       } else {
-        alert(`Error loading repository: ${data.error}`)
+        alert(`Error loading repository: ${data.error?.message || response.statusText}`)
       }
     } catch (error) {
       console.error('Failed to load repository:', error)
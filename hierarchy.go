@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"gonav/internal/analyzer"
+	"gonav/internal/repo"
+)
+
+// handleHierarchy serves the embedding hierarchy of a struct or interface type:
+// GET /api/hierarchy/{module@version}/{package_path}?type=Name[&depth=N]. The types it
+// embeds and the types of its module embedding it are expanded to depth levels.
+func (s *Server) handleHierarchy(w http.ResponseWriter, r *http.Request) {
+	if !beginGet(w, r) {
+		return
+	}
+
+	decodedPath, err := decodeAPIPath(r, "/api/hierarchy/")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Invalid URL encoding", nil)
+		return
+	}
+
+	// The package path starts at the first / after the version
+	atIndex := strings.Index(decodedPath, "@")
+	if atIndex == -1 {
+		writeError(w, http.StatusBadRequest, CodeInvalidModuleVersion, "Invalid module@version format", nil)
+		return
+	}
+	moduleAtVersion, packagePath := decodedPath, ""
+	if slash := strings.Index(decodedPath[atIndex:], "/"); slash != -1 {
+		moduleAtVersion = decodedPath[:atIndex+slash]
+		packagePath = decodedPath[atIndex+slash+1:]
+	}
+
+	typeName := r.URL.Query().Get("type")
+	if typeName == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Missing type parameter", nil)
+		return
+	}
+	depth := 0
+	if value := r.URL.Query().Get("depth"); value != "" {
+		depth, err = strconv.Atoi(value)
+		if err != nil || depth < 1 || depth > analyzer.MaxHierarchyDepth {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest,
+				fmt.Sprintf("Invalid depth: %s, must be between 1 and %d", value, analyzer.MaxHierarchyDepth), nil)
+			return
+		}
+	}
+
+	s.logger.InfoContext(r.Context(), "Building type hierarchy", "module", moduleAtVersion, "package", packagePath, "type", typeName)
+	defer s.repoManager.Acquire(moduleAtVersion)()
+
+	repoPath := s.repoManager.GetRepositoryPath(moduleAtVersion)
+	if repoPath == "" {
+		writeRepoNotLoaded(w, moduleAtVersion)
+		return
+	}
+
+	// The package directory must be inside the repository
+	packageDir, err := repo.ResolvePath(repoPath, packagePath)
+	if err != nil {
+		s.writePathError(w, r, packagePath, err)
+		return
+	}
+	if info, err := os.Stat(packageDir); err != nil || !info.IsDir() {
+		writeError(w, http.StatusBadRequest, CodeInvalidPath, fmt.Sprintf("Not a package directory: %s", packagePath), &ErrorDetails{Path: packagePath})
+		return
+	}
+
+	hierarchy, err := s.analyzer.TypeHierarchy(r.Context(), repoPath, packagePath, typeName, depth)
+	if err != nil {
+		s.writeOperationError(w, r, CodeAnalysisFailed, "Failed to build type hierarchy", err)
+		return
+	}
+
+	writeJSON(w, hierarchy)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gonav/internal/analyzer"
+)
+
+func TestHierarchyEndpoint(t *testing.T) {
+	server := newFixtureServer(t, "example.com/lib@v1.0.0", map[string]string{
+		"shapes/shapes.go": `package shapes
+
+type Shape interface {
+	Area() float64
+}
+
+type Base struct{}
+
+func (Base) Name() string { return "base" }
+
+type Square struct {
+	Base
+	Side float64
+}
+
+func (s Square) Area() float64 { return s.Side * s.Side }
+`,
+	})
+
+	var hierarchy analyzer.TypeHierarchy
+	getJSON(t, server, "/api/hierarchy/example.com/lib@v1.0.0/shapes?type=Square", &hierarchy)
+	require.NotNil(t, hierarchy.Type)
+	assert.Equal(t, "Square", hierarchy.Type.Name)
+	assert.Equal(t, "struct", hierarchy.Kind)
+	require.Len(t, hierarchy.Embeds, 1)
+	assert.Equal(t, "Base", hierarchy.Embeds[0].Type.Name)
+	assert.Equal(t, "Base", hierarchy.Embeds[0].Field)
+
+	var methods []string
+	for _, method := range hierarchy.Methods {
+		methods = append(methods, method.Name)
+	}
+	assert.ElementsMatch(t, []string{"Area", "Name"}, methods)
+}
+
+func TestHierarchyEndpointErrors(t *testing.T) {
+	server := newAdminTestServer(t, "")
+	checkEndpointErrors(t, server.setupRoutes(), []endpointError{
+		{http.MethodGet, "/api/hierarchy/example.com/lib@v1.0.0/shapes?type=Shape", http.StatusNotFound, CodeRepoNotLoaded},
+		{http.MethodGet, "/api/hierarchy/example.com/lib/shapes?type=Shape", http.StatusBadRequest, CodeInvalidModuleVersion},
+		{http.MethodGet, "/api/hierarchy/example.com/lib@v1.0.0/shapes", http.StatusBadRequest, CodeInvalidRequest},
+		{http.MethodGet, "/api/hierarchy/example.com/lib@v1.0.0/shapes?type=Shape&depth=0", http.StatusBadRequest, CodeInvalidRequest},
+		{http.MethodGet, "/api/hierarchy/example.com/lib@v1.0.0/shapes?type=Shape&depth=1000000", http.StatusBadRequest, CodeInvalidRequest},
+		{http.MethodPost, "/api/hierarchy/example.com/lib@v1.0.0/shapes?type=Shape", http.StatusMethodNotAllowed, CodeMethodNotAllowed},
+	})
+}
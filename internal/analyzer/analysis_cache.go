@@ -3,16 +3,21 @@ package analyzer
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"os/exec"
+	"sort"
 	"strings"
 	"sync"
 	"time"
+
+	"gonav/internal/logging"
 )
 
 // CacheKey represents the key for caching analysis results
 type CacheKey struct {
-	Type        CacheKeyType `json:"type"`        // "package" or "file"
-	PackagePath string       `json:"package_path"` // e.g. "github.com/gin-gonic/gin@v1.9.1"
+	Type        CacheKeyType `json:"type"`                // "package" or "file"
+	Dir         string       `json:"dir,omitempty"`       // Module directory, when the cache is shared by several modules
+	PackagePath string       `json:"package_path"`        // e.g. "github.com/gin-gonic/gin@v1.9.1"
 	FilePath    string       `json:"file_path,omitempty"` // e.g. "gin.go" (only for file cache)
 }
 
@@ -25,10 +30,14 @@ const (
 
 // String returns a string representation of the cache key
 func (k CacheKey) String() string {
+	key := fmt.Sprintf("package:%s", k.PackagePath)
 	if k.Type == CacheKeyTypeFile {
-		return fmt.Sprintf("file:%s:%s", k.PackagePath, k.FilePath)
+		key = fmt.Sprintf("file:%s:%s", k.PackagePath, k.FilePath)
+	}
+	if k.Dir != "" {
+		return k.Dir + "|" + key
 	}
-	return fmt.Sprintf("package:%s", k.PackagePath)
+	return key
 }
 
 // CachedAnalysis represents a cached analysis result with revision tracking
@@ -48,8 +57,34 @@ type CachedAnalysis struct {
 	MissingDependencies  []string  `json:"missing_dependencies"`
 	DependencyLoadingInProgress bool `json:"dependency_loading_in_progress"`
 	
-	// Complete analyses are kept indefinitely
+	// Complete analyses are kept until evicted
 	IsComplete bool `json:"is_complete"`
+	
+	// LastAccess is updated on every lookup and drives eviction
+	LastAccess time.Time `json:"last_access"`
+}
+
+// newCachedAnalysis returns the analysis of path reported by AnalyzePackageWithQuality
+// or AnalyzeSingleFileWithQuality, with a revision reflecting its quality
+func newCachedAnalysis(path string, response *EnhancedAnalysisResponse) *CachedAnalysis {
+	symbolCount, refCount := 0, 0 // Package analysis doesn't have references
+	if response.PackageInfo != nil {
+		symbolCount = len(response.PackageInfo.Symbols)
+	}
+	if response.FileInfo != nil {
+		symbolCount = len(response.FileInfo.Symbols)
+		refCount = len(response.FileInfo.References)
+	}
+
+	return &CachedAnalysis{
+		Revision:            GenerateRevision(path, response.Quality, symbolCount, refCount),
+		PackageInfo:         response.PackageInfo,
+		FileInfo:            response.FileInfo,
+		Quality:             response.Quality,
+		Timestamp:           time.Now(),
+		MissingDependencies: response.Quality.MissingDependencies,
+		IsComplete:          response.Quality.IsComplete,
+	}
 }
 
 // AnalysisCache manages cached analysis results with revision-based updates
@@ -63,7 +98,7 @@ type AnalysisCache struct {
 
 // DependencyChecker interface for checking dependency availability
 type DependencyChecker interface {
-	AreDependenciesAvailable(workDir string, dependencies []string) ([]string, error)
+	AreDependenciesAvailable(ctx context.Context, workDir string, dependencies []string) ([]string, error)
 }
 
 // NewAnalysisCache creates a new analysis cache
@@ -76,8 +111,15 @@ func NewAnalysisCache(dependencyChecker DependencyChecker) *AnalysisCache {
 
 // Get retrieves a cached analysis, considering the client's current revision
 func (ac *AnalysisCache) Get(key CacheKey, clientRevision string) (*CachedAnalysis, CacheResult) {
-	ac.mutex.RLock()
-	defer ac.mutex.RUnlock()
+	cached, result := ac.get(key, clientRevision)
+	analysisCacheResults.With(string(result)).Inc()
+	return cached, result
+}
+
+// get looks up key for Get
+func (ac *AnalysisCache) get(key CacheKey, clientRevision string) (*CachedAnalysis, CacheResult) {
+	ac.mutex.Lock()
+	defer ac.mutex.Unlock()
 	
 	keyStr := key.String()
 	cached, exists := ac.cache[keyStr]
@@ -85,6 +127,7 @@ func (ac *AnalysisCache) Get(key CacheKey, clientRevision string) (*CachedAnalys
 	if !exists {
 		return nil, CacheResultMiss
 	}
+	cached.LastAccess = time.Now()
 	
 	// If client has no revision (initial request), return cached version
 	if clientRevision == "" {
@@ -106,6 +149,7 @@ func (ac *AnalysisCache) Set(key CacheKey, analysis *CachedAnalysis) {
 	defer ac.mutex.Unlock()
 	
 	keyStr := key.String()
+	analysis.LastAccess = time.Now()
 	
 	// Remove previous revision if this is an update (unless previous was complete)
 	if existing, exists := ac.cache[keyStr]; exists && !existing.IsComplete {
@@ -118,7 +162,7 @@ func (ac *AnalysisCache) Set(key CacheKey, analysis *CachedAnalysis) {
 }
 
 // ShouldRecalculate determines if we should recalculate analysis based on dependency availability
-func (ac *AnalysisCache) ShouldRecalculate(key CacheKey, workDir string) (bool, []string, error) {
+func (ac *AnalysisCache) ShouldRecalculate(ctx context.Context, key CacheKey, workDir string) (bool, []string, error) {
 	ac.mutex.RLock()
 	cached, exists := ac.cache[key.String()]
 	ac.mutex.RUnlock()
@@ -136,7 +180,7 @@ func (ac *AnalysisCache) ShouldRecalculate(key CacheKey, workDir string) (bool,
 	}
 	
 	// Check if any previously missing dependencies are now available
-	availableDeps, err := ac.dependencyChecker.AreDependenciesAvailable(workDir, cached.MissingDependencies)
+	availableDeps, err := ac.dependencyChecker.AreDependenciesAvailable(ctx, workDir, cached.MissingDependencies)
 	if err != nil {
 		return false, nil, err
 	}
@@ -202,6 +246,67 @@ func (ac *AnalysisCache) Cleanup(maxAge time.Duration) int {
 	return removed
 }
 
+// Evict removes entries not accessed within ttl, then the least recently accessed
+// entries until at most maxEntries remain. Entries waiting for dependency loading are
+// never evicted. A zero ttl or maxEntries disables that limit. It returns the number
+// of entries removed.
+func (ac *AnalysisCache) Evict(ttl time.Duration, maxEntries int) int {
+	ac.mutex.Lock()
+	defer ac.mutex.Unlock()
+	
+	removed := 0
+	now := time.Now()
+	candidates := make([]string, 0, len(ac.cache))
+	
+	for keyStr, cached := range ac.cache {
+		if cached.DependencyLoadingInProgress {
+			continue
+		}
+		if ttl > 0 && now.Sub(cached.LastAccess) > ttl {
+			delete(ac.cache, keyStr)
+			removed++
+			continue
+		}
+		candidates = append(candidates, keyStr)
+	}
+	
+	if maxEntries <= 0 || len(ac.cache) <= maxEntries {
+		return removed
+	}
+	
+	// Least recently accessed first
+	sort.Slice(candidates, func(i, j int) bool {
+		return ac.cache[candidates[i]].LastAccess.Before(ac.cache[candidates[j]].LastAccess)
+	})
+	for _, keyStr := range candidates {
+		if len(ac.cache) <= maxEntries {
+			break
+		}
+		delete(ac.cache, keyStr)
+		removed++
+	}
+	
+	return removed
+}
+
+// Clear removes the entries of the modules in dir or below it, keyed with CacheKey.Dir,
+// or every entry if dir is empty. It returns the number of entries removed.
+func (ac *AnalysisCache) Clear(dir string) int {
+	ac.mutex.Lock()
+	defer ac.mutex.Unlock()
+
+	removed := 0
+	for keyStr := range ac.cache {
+		keyDir, _, found := strings.Cut(keyStr, "|")
+		if dir != "" && (!found || !isWithinRepository(keyDir, dir)) {
+			continue
+		}
+		delete(ac.cache, keyStr)
+		removed++
+	}
+	return removed
+}
+
 // CacheResult represents the result of a cache lookup
 type CacheResult string
 
@@ -221,16 +326,18 @@ type CacheStats struct {
 }
 
 // SimpleDependencyChecker implements basic dependency availability checking
-type SimpleDependencyChecker struct{}
+type SimpleDependencyChecker struct {
+	Logger *slog.Logger // Receives failed checks, slog.Default() if nil
+}
 
 // AreDependenciesAvailable checks which dependencies are now available in the module cache
-func (sdc *SimpleDependencyChecker) AreDependenciesAvailable(workDir string, dependencies []string) ([]string, error) {
+func (sdc *SimpleDependencyChecker) AreDependenciesAvailable(ctx context.Context, workDir string, dependencies []string) ([]string, error) {
 	available := make([]string, 0)
 	
 	for _, dep := range dependencies {
-		if isAvailable, err := sdc.checkSingleDependency(workDir, dep); err != nil {
+		if isAvailable, err := sdc.checkSingleDependency(ctx, workDir, dep); err != nil {
 			// Log error but continue checking other dependencies
-			fmt.Printf("Error checking dependency %s: %v\n", dep, err)
+			logging.OrDefault(sdc.Logger).WarnContext(ctx, "Error checking dependency", "dependency", dep, "error", err)
 		} else if isAvailable {
 			available = append(available, dep)
 		}
@@ -240,16 +347,14 @@ func (sdc *SimpleDependencyChecker) AreDependenciesAvailable(workDir string, dep
 }
 
 // checkSingleDependency checks if a single dependency is available
-func (sdc *SimpleDependencyChecker) checkSingleDependency(workDir, dependency string) (bool, error) {
+func (sdc *SimpleDependencyChecker) checkSingleDependency(ctx context.Context, workDir, dependency string) (bool, error) {
 	// Use `go list` to check if the module can be resolved
 	// This is faster than `go mod download` and doesn't modify the module cache
-	cmd := exec.Command("go", "list", "-m", dependency)
-	cmd.Dir = workDir
 	
 	// Set a timeout to prevent hanging
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
-	cmd = exec.CommandContext(ctx, "go", "list", "-m", dependency)
+	cmd := exec.CommandContext(ctx, "go", "list", "-m", dependency)
 	cmd.Dir = workDir
 	
 	output, err := cmd.CombinedOutput()
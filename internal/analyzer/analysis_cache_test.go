@@ -0,0 +1,140 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gonav/internal/testutil"
+)
+
+func TestAnalysisCache_Evict(t *testing.T) {
+	cache := NewAnalysisCache(&SimpleDependencyChecker{})
+	key := func(name string) CacheKey {
+		return CacheKey{Type: CacheKeyTypePackage, PackagePath: name}
+	}
+
+	for _, name := range []string{"a", "b", "c", "loading"} {
+		cache.Set(key(name), &CachedAnalysis{Revision: "rev1", IsComplete: true})
+	}
+	cache.MarkDependencyLoadingInProgress(key("loading"), true)
+
+	// Make "a" the least recently used entry and "c" the most recently used
+	time.Sleep(5 * time.Millisecond)
+	cache.Get(key("b"), "")
+	time.Sleep(5 * time.Millisecond)
+	cache.Get(key("c"), "")
+
+	// Size limit: least recently accessed entries go first
+	removed := cache.Evict(0, 3)
+	assert.Equal(t, 1, removed)
+	_, result := cache.Get(key("a"), "")
+	assert.Equal(t, CacheResultMiss, result)
+
+	// TTL: everything idle for too long goes, except entries still loading dependencies
+	time.Sleep(5 * time.Millisecond)
+	removed = cache.Evict(time.Millisecond, 0)
+	assert.Equal(t, 2, removed)
+	assert.Equal(t, 1, cache.GetStats().TotalEntries)
+	_, result = cache.Get(key("loading"), "")
+	assert.Equal(t, CacheResultHit, result)
+
+	// No limits, nothing evicted
+	assert.Equal(t, 0, cache.Evict(0, 0))
+}
+
+func TestAnalysisCache_Clear(t *testing.T) {
+	cache := NewAnalysisCache(&SimpleDependencyChecker{})
+	for _, dir := range []string{"/repos/a", "/repos/a/tools", "/repos/ab", ""} {
+		cache.Set(CacheKey{Type: CacheKeyTypePackage, Dir: dir, PackagePath: "p"}, &CachedAnalysis{IsComplete: true})
+	}
+
+	// Entries of the modules below the directory go, not those of a sibling
+	assert.Equal(t, 2, cache.Clear("/repos/a"))
+	_, result := cache.Get(CacheKey{Type: CacheKeyTypePackage, Dir: "/repos/ab", PackagePath: "p"}, "")
+	assert.Equal(t, CacheResultHit, result)
+
+	assert.Equal(t, 2, cache.Clear(""))
+	assert.Equal(t, 0, cache.GetStats().TotalEntries)
+}
+
+func TestPackageAnalyzer_AnalysisCache(t *testing.T) {
+	repoDir := testutil.TempFiles(t, map[string]string{
+		"go.mod":       "module example.com/app\n\ngo 1.21\n",
+		"app.go":       "package app\n\nfunc Run() {}\n",
+		"tools/go.mod": "module example.com/app/tools\n\ngo 1.21\n",
+		"tools/gen.go": "package tools\n\nfunc Gen() {}\n",
+	})
+	cache := NewAnalysisCache(&SimpleDependencyChecker{})
+	analyzer := New().SetAnalysisCache(cache)
+	analyzer.SetRepositoryContext(repoDir, nil)
+
+	// Analyses are served from the cache, keyed by module
+	packageInfo, err := analyzer.AnalyzePackage(context.Background(), repoDir, "")
+	require.NoError(t, err)
+	cached, err := analyzer.AnalyzePackage(context.Background(), repoDir, "")
+	require.NoError(t, err)
+	assert.Same(t, packageInfo, cached)
+
+	_, err = analyzer.AnalyzeSingleFile(context.Background(), repoDir, "app.go")
+	require.NoError(t, err)
+	_, err = analyzer.AnalyzePackage(context.Background(), repoDir, "tools")
+	require.NoError(t, err)
+	assert.Equal(t, 3, cache.GetStats().TotalEntries)
+	assert.Equal(t, 3, cache.GetStats().CompleteEntries)
+	_, result := cache.Get(CacheKey{Type: CacheKeyTypePackage, Dir: filepath.Join(repoDir, "tools"), PackagePath: "tools"}, "")
+	assert.Equal(t, CacheResultHit, result)
+
+	// Dropping the module loads drops the analyses made from them
+	analyzer.DropModuleLoads(repoDir)
+	assert.Equal(t, 0, cache.GetStats().TotalEntries)
+	reloaded, err := analyzer.AnalyzePackage(context.Background(), repoDir, "")
+	require.NoError(t, err)
+	assert.NotSame(t, packageInfo, reloaded)
+
+	// So does releasing the repository
+	analyzer.ReleaseRepository(repoDir)
+	assert.Equal(t, 0, cache.GetStats().TotalEntries)
+}
+
+func TestPackageAnalyzer_DownloadsMissingDependencies(t *testing.T) {
+	proxyDir := t.TempDir()
+	testutil.WriteProxyModule(t, proxyDir, "example.com/lib", "v1.0.0")
+	repoDir := testutil.TempFiles(t, map[string]string{
+		"go.mod":  "module example.com/app\n\ngo 1.21\n\nrequire example.com/lib v1.0.0\n",
+		"main.go": "package main\n\nimport \"example.com/lib\"\n\nfunc main() { lib.Hello() }\n",
+	})
+
+	// Packages are loaded offline, the dependency is downloaded from the proxy
+	goEnv := append(os.Environ(), "GOMODCACHE="+t.TempDir(), "GOSUMDB=off", "GOFLAGS=-mod=mod")
+	queueConfig := DefaultDependencyQueueConfig()
+	queueConfig.Env = slices.Concat(goEnv, []string{"GOPROXY=file://" + filepath.ToSlash(proxyDir)})
+	queue := NewDependencyQueue(queueConfig)
+	t.Cleanup(func() { queue.Shutdown(time.Second) })
+
+	cache := NewAnalysisCache(&SimpleDependencyChecker{})
+	analyzer := New().SetAnalysisCache(cache).SetDependencyQueue(queue)
+	analyzer.SetRepositoryContext(repoDir, slices.Concat(goEnv, []string{"GOPROXY=off"}))
+
+	_, err := analyzer.AnalyzePackage(context.Background(), repoDir, "")
+	require.NoError(t, err)
+	key := CacheKey{Type: CacheKeyTypePackage, Dir: repoDir, PackagePath: ""}
+	cached, _ := cache.Get(key, "")
+	require.NotNil(t, cached)
+	assert.Equal(t, []string{"example.com/lib"}, cached.MissingDependencies)
+
+	// The incomplete analysis is dropped once the dependency is downloaded, and the
+	// module loaded again
+	require.Eventually(t, func() bool {
+		_, err := analyzer.AnalyzePackage(context.Background(), repoDir, "")
+		return err == nil && cache.GetStats().CompleteEntries == 1
+	}, 30*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int64(1), queue.GetStats().CompletedRequests)
+	assert.Equal(t, int64(0), queue.GetStats().FailedRequests)
+}
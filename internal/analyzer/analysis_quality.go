@@ -2,7 +2,6 @@ package analyzer
 
 import (
 	"fmt"
-	"strings"
 
 	"golang.org/x/tools/go/packages"
 )
@@ -29,10 +28,11 @@ type AnalysisQuality struct {
 }
 
 type ImportError struct {
-	ImportPath string `json:"import_path"`
+	ImportPath string `json:"import_path"` // Set for errors importing a package
 	Error      string `json:"error"`
 	Position   string `json:"position,omitempty"`
-	Severity   string `json:"severity"` // "error", "warning"
+	Severity   string `json:"severity"`       // "error", "warning"
+	Kind       string `json:"kind,omitempty"` // "list", "parse", "type" or "unknown"
 }
 
 type AnalysisMode string
@@ -63,47 +63,34 @@ func AssessAnalysisQuality(pkg *packages.Package) *AnalysisQuality {
 	quality.AnalysisMode = AnalysisModeComplete
 	quality.QualityScore = 1.0
 	
-	// Analyze package errors to determine completeness
-	importErrors := 0
+	// Imports the go command found no package for are missing
+	quality.MissingDependencies = missingImports(pkg)
+	importErrors := len(quality.MissingDependencies)
 	totalImports := len(pkg.Imports)
-	
+	if importErrors > 0 {
+		quality.IsComplete = false
+	}
+
+	// The type checker reports failed imports at their import spec
+	importPaths := make(map[string]string)
+	if pkg.Fset != nil {
+		for pos, path := range importSpecPaths(pkg.Syntax) {
+			importPaths[pkg.Fset.Position(pos).String()] = path
+		}
+	}
 	for _, pkgErr := range pkg.Errors {
 		importErr := ImportError{
 			Error:    pkgErr.Error(),
 			Position: pkgErr.Pos,
 			Severity: "error",
+			Kind:     errorKind(pkgErr.Kind),
 		}
-		
-		// Parse import path from error message
-		if strings.Contains(pkgErr.Error(), "could not import") {
-			importErr.ImportPath = extractImportPathFromError(pkgErr.Error())
-			quality.MissingDependencies = append(quality.MissingDependencies, importErr.ImportPath)
-			importErrors++
-			quality.IsComplete = false
+		if pkgErr.Kind == packages.TypeError {
+			importErr.ImportPath = importPaths[pkgErr.Pos]
 		}
-		
 		quality.ImportErrors = append(quality.ImportErrors, importErr)
 	}
 	
-	// Check import status for additional missing dependencies
-	for importPath, importedPkg := range pkg.Imports {
-		if importedPkg != nil && len(importedPkg.Errors) > 0 {
-			// This import has errors, but may not have been counted above
-			hasImportError := false
-			for _, existing := range quality.MissingDependencies {
-				if existing == importPath {
-					hasImportError = true
-					break
-				}
-			}
-			if !hasImportError {
-				quality.MissingDependencies = append(quality.MissingDependencies, importPath)
-				importErrors++
-				quality.IsComplete = false
-			}
-		}
-	}
-	
 	// Determine analysis mode based on what succeeded
 	if pkg.Types == nil || pkg.TypesInfo == nil {
 		quality.AnalysisMode = AnalysisModeFailed
@@ -132,20 +119,6 @@ func AssessAnalysisQuality(pkg *packages.Package) *AnalysisQuality {
 	return quality
 }
 
-// extractImportPathFromError parses import path from packages error message
-func extractImportPathFromError(errorMsg string) string {
-	// Error format: "could not import github.com/gin-gonic/gin (invalid package name: \"\")"
-	if strings.Contains(errorMsg, "could not import ") {
-		start := strings.Index(errorMsg, "could not import ") + len("could not import ")
-		end := strings.Index(errorMsg[start:], " ")
-		if end == -1 {
-			end = len(errorMsg) - start
-		}
-		return errorMsg[start : start+end]
-	}
-	return ""
-}
-
 // DependencyLoadingStatus represents the status of dependency loading operation
 type DependencyLoadingStatus struct {
 	// Status indicates current state
@@ -1,6 +1,7 @@
 package analyzer
 
 import (
+	"context"
 	"fmt"
 	"go/ast"
 	"go/build"
@@ -8,11 +9,18 @@ import (
 	"go/parser"
 	"go/token"
 	"go/types"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"golang.org/x/mod/modfile"
+
+	"gonav/internal/flight"
+	"gonav/internal/logging"
 )
 
 type PackageAnalyzer struct {
@@ -20,6 +28,23 @@ type PackageAnalyzer struct {
 	packages       map[string]*PackageInfo
 	stdLibCache    map[string]bool // Cache for standard library detection
 	packagesAnalyzer *PackagesAnalyzer // Enhanced analyzer using golang.org/x/tools/go/packages
+
+	// Analyzers keep their module load in memory, so there is one per module directory
+	// of every repository (module@version) analyzed
+	mutex       sync.Mutex
+	env         []string
+	loadTimeout time.Duration
+	analyzers map[string]*PackagesAnalyzer // Absolute module dir -> analyzer in that module's context
+
+	dependencyLoader *DependencyLoader // Shared by the analyzers, nil if not set
+	deprecations     *deprecationIndex // Shared by the analyzers
+	logger           *slog.Logger
+
+	// Analyses by module dir and cache key, kept in analysisCache if set and shared by
+	// concurrent requests while in flight
+	analysisCache   *AnalysisCache
+	dependencyQueue *DependencyQueue // Downloads the missing dependencies of analyses, nil if not set
+	analyses        flight.Group[*CachedAnalysis]
 }
 
 type PackageDiscovery struct {
@@ -27,6 +52,9 @@ type PackageDiscovery struct {
 	Path         string   `json:"path"`         // Relative path from repo root
 	AbsolutePath string   `json:"absolutePath"` // Full filesystem path
 	Files        []string `json:"files"`        // List of Go files in this package
+	Module       string   `json:"module,omitempty"`     // Path of the module containing this package
+	ImportPath   string   `json:"importPath,omitempty"` // Full import path of this package
+	Vendored     bool     `json:"vendored,omitempty"`   // True for packages in a vendor directory
 }
 
 // FileEntry represents a file in the package with metadata
@@ -50,25 +78,39 @@ type FileInfo struct {
 	Imports     []*ImportInfo       `json:"imports"`     // Import statements in this file
 	Scopes      []*ScopeInfo        `json:"scopes,omitempty"`      // Scope information for scope-aware features
 	Definitions []*Definition       `json:"definitions,omitempty"` // Local definitions for scope-aware features
+	Outline     []*OutlineSymbol    `json:"outline,omitempty"`     // Declarations of the file, as a document symbol tree
+	// Classification of identifiers and literals, for highlighting
+	SemanticTokens *SemanticTokens `json:"semanticTokens,omitempty"`
+	// Errors reported for the file while loading or type checking its package
+	Diagnostics []*Diagnostic `json:"diagnostics,omitempty"`
 }
 
 // ScopeInfo represents a lexical scope in Go code
 type ScopeInfo struct {
-	ID    string    `json:"id"`
-	Type  string    `json:"type"`
-	Name  string    `json:"name,omitempty"`
-	Range Range     `json:"range"`
+	ID       string   `json:"id"`
+	Type     string   `json:"type"`
+	Name     string   `json:"name,omitempty"`
+	Range    Range    `json:"range"`
+	Parent   string   `json:"parent"`             // ID of the enclosing scope, "/" for top-level scopes
+	Children []string `json:"children,omitempty"` // IDs of the nested scopes, in source order
 }
 
 // Definition represents a local symbol definition
 type Definition struct {
-	ID        string `json:"id"`
-	Name      string `json:"name"`
-	Type      string `json:"type"`
-	Line      int    `json:"line"`
-	Column    int    `json:"column"`
-	ScopeID   string `json:"scopeId"`
-	Signature string `json:"signature"`
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Type      string     `json:"type"`
+	Line      int        `json:"line"`
+	Column    int        `json:"column"`
+	ScopeID   string     `json:"scopeId"`
+	Signature string     `json:"signature"`
+	Shadows   *Shadowing `json:"shadows,omitempty"` // Outer declaration hidden by this definition, if any
+}
+
+// definitionID returns the ID of the definition of name at pos. It only depends on
+// the source, so every analyzer gives the same definition the same ID.
+func definitionID(name string, pos token.Position) string {
+	return fmt.Sprintf("def_%s_%d_%d", name, pos.Line, pos.Column)
 }
 
 // Range represents a position range in source code
@@ -85,7 +127,7 @@ type Position struct {
 
 type Symbol struct {
 	Name        string `json:"name"`
-	Type        string `json:"type"` // "function", "type", "var", "const", "method", "field"
+	Type        string `json:"type"` // "function", "type", "typeparam", "var", "const", "method", "field"
 	File        string `json:"file"`
 	Line        int    `json:"line"`
 	Column      int    `json:"column"`
@@ -97,16 +139,25 @@ type Symbol struct {
 	IsExternal  bool   `json:"isExternal,omitempty"`  // True if this is a cross-repository reference
 	IsStdLib    bool   `json:"isStdLib,omitempty"`    // True if this is a Go standard library symbol
 	Version     string `json:"version,omitempty"`     // Version from go.mod if available
+	Module      string `json:"module,omitempty"`      // Module path of a vendored symbol
+	IsVendored  bool   `json:"isVendored,omitempty"`  // True if the symbol is in the vendor tree of the repository
+	// Signature of the instantiation referenced, for uses of generic symbols
+	InstanceSignature string `json:"instanceSignature,omitempty"`
+	// Type declaring a field, as "Server", or "Config.Limits" for a nested struct
+	Parent string `json:"parent,omitempty"`
+	// Deprecation notice of the declaration, the text of its "Deprecated:" paragraph
+	Deprecated string `json:"deprecated,omitempty"`
 }
 
 type Reference struct {
-	Name         string  `json:"name"`
-	File         string  `json:"file"`
-	Line         int     `json:"line"`
-	Column       int     `json:"column"`
-	Target       *Symbol `json:"target,omitempty"`       // The symbol this references (legacy)
-	Type         string  `json:"type,omitempty"`         // Reference type: "local", "internal", "external"
-	DefinitionID string  `json:"definitionId,omitempty"` // For local references - ID of local definition
+	Name         string    `json:"name"`
+	File         string    `json:"file"`
+	Line         int       `json:"line"`
+	Column       int       `json:"column"`
+	Target       *Symbol   `json:"target,omitempty"`       // The symbol this references (legacy)
+	Type         string    `json:"type,omitempty"`         // Reference type: "local", "internal", "external"
+	DefinitionID string    `json:"definitionId,omitempty"` // For local references - ID of local definition
+	Promotion    []*Symbol `json:"promotion,omitempty"`    // Embedded fields traversed to reach a promoted field or method, outermost first
 }
 
 type ImportInfo struct {
@@ -123,16 +174,87 @@ type ModuleInfo struct {
 
 func New() *PackageAnalyzer {
 	return &PackageAnalyzer{
-		fset:        token.NewFileSet(),
-		packages:    make(map[string]*PackageInfo),
-		stdLibCache: make(map[string]bool),
+		fset:         token.NewFileSet(),
+		packages:     make(map[string]*PackageInfo),
+		stdLibCache:  make(map[string]bool),
+		deprecations: newDeprecationIndex(),
+		logger:       slog.Default(),
 		// packagesAnalyzer will be configured when repository context is available
 	}
 }
 
+// SetLogger sets the logger receiving the analyzer's log records, including the ones
+// of the packages analyzers it creates
+func (a *PackageAnalyzer) SetLogger(logger *slog.Logger) *PackageAnalyzer {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.logger = logger
+	for _, pa := range a.analyzers {
+		pa.SetLogger(logger)
+	}
+	return a
+}
+
+// SetLoadTimeout bounds the duration of every packages.Load, 0 for no limit
+func (a *PackageAnalyzer) SetLoadTimeout(timeout time.Duration) *PackageAnalyzer {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.loadTimeout = timeout
+	for _, pa := range a.analyzers {
+		pa.SetLoadTimeout(timeout)
+	}
+	return a
+}
+
+// SetDependencyLoader sets the loader downloading missing dependencies in the
+// background, shared by the packages analyzers
+func (a *PackageAnalyzer) SetDependencyLoader(loader *DependencyLoader) *PackageAnalyzer {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.dependencyLoader = loader
+	for _, pa := range a.analyzers {
+		pa.SetDependencyLoader(loader)
+	}
+	return a
+}
+
+// SetAnalysisCache sets the cache keeping package and file analyses of every module
+// until they are evicted from it, or until their repository is released. Analyses
+// are not cached if no cache is set.
+func (a *PackageAnalyzer) SetAnalysisCache(cache *AnalysisCache) *PackageAnalyzer {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.analysisCache = cache
+	return a
+}
+
+// SetDependencyQueue sets the queue downloading the missing dependencies of incomplete
+// analyses. Once some are downloaded, their module is loaded again and its analyses
+// are dropped from the analysis cache.
+func (a *PackageAnalyzer) SetDependencyQueue(queue *DependencyQueue) *PackageAnalyzer {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.dependencyQueue = queue
+	return a
+}
+
 // SetRepositoryContext configures the analyzer with repository context for enhanced analysis
 func (a *PackageAnalyzer) SetRepositoryContext(repoPath string, env []string) *PackageAnalyzer {
-	a.packagesAnalyzer = NewPackagesAnalyzer(repoPath, env)
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	// Loaded modules are reused as long as they were loaded with the same environment
+	if a.analyzers == nil || !slices.Equal(a.env, env) {
+		a.analyzers = make(map[string]*PackagesAnalyzer)
+	}
+	a.env = env
+
+	a.packagesAnalyzer = a.analyzerForDir(repoPath, repoPath)
 	return a
 }
 
@@ -177,8 +299,7 @@ func (a *PackageAnalyzer) ParseModuleInfo(repoPath string) (*ModuleInfo, error)
 		info.Replaces[rep.Old.Path] = rep.New.Path
 	}
 
-	fmt.Printf("Parsed module info: %s with %d dependencies and %d replaces\n", 
-		info.ModulePath, len(info.Dependencies), len(info.Replaces))
+	a.logger.Debug("Parsed module info", "module", info.ModulePath, "dependencies", len(info.Dependencies), "replaces", len(info.Replaces))
 
 	return info, nil
 }
@@ -290,18 +411,37 @@ func (info *ModuleInfo) ResolveImport(importPath string) (resolvedPath, version
 
 // DiscoverPackages finds all Go packages in the repository without analyzing them
 func (a *PackageAnalyzer) DiscoverPackages(repoPath string) (map[string]*PackageDiscovery, error) {
-	fmt.Printf("Discovering packages in repository: %s\n", repoPath)
+	a.logger.Debug("Discovering packages", "repo", repoPath)
 
 	packages := make(map[string]*PackageDiscovery)
 
-	err := filepath.Walk(repoPath, func(path string, info os.FileInfo, err error) error {
+	// Packages belong to the closest enclosing module
+	modules, err := a.DiscoverModules(repoPath)
+	if err != nil {
+		a.logger.Warn("Failed to discover modules, continuing anyway", "repo", repoPath, "error", err)
+	}
+
+	// Vendor directory relative path -> vendored modules
+	vendors := make(map[string]*VendorInfo)
+
+	err = filepath.Walk(repoPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 
-		// Skip hidden directories, vendor, and common non-Go directories
+		// Skip hidden directories, vendor, and common non-Go directories. Vendor
+		// directories created by "go mod vendor" are kept so vendored code can be browsed.
 		if info.IsDir() {
 			name := info.Name()
+			if name == "vendor" && HasVendorModules(filepath.Dir(path)) {
+				vendorInfo, err := ParseVendorModules(filepath.Dir(path))
+				if err == nil {
+					relDir, _ := filepath.Rel(repoPath, path)
+					vendors[filepath.ToSlash(relDir)] = vendorInfo
+					return nil
+				}
+				a.logger.Warn("Failed to parse vendor/modules.txt", "dir", path, "error", err)
+			}
 			if strings.HasPrefix(name, ".") || name == "vendor" || name == "node_modules" || name == "testdata" {
 				return filepath.SkipDir
 			}
@@ -328,7 +468,7 @@ func (a *PackageAnalyzer) DiscoverPackages(repoPath string) (map[string]*Package
 					// Find all Go files in this package
 					files, err := a.findFilesInPackage(dir)
 					if err != nil {
-						fmt.Printf("Failed to find files in package %s: %v\n", dir, err)
+						a.logger.Warn("Failed to find files in package", "dir", dir, "error", err)
 						return nil
 					}
 
@@ -338,7 +478,16 @@ func (a *PackageAnalyzer) DiscoverPackages(repoPath string) (map[string]*Package
 						AbsolutePath: dir,
 						Files:       files,
 					}
-					fmt.Printf("Discovered package '%s' at %s (%d files)\n", file.Name.Name, relDir, len(files))
+					if vendorDir, vendorInfo := vendorForDir(vendors, relDir); vendorInfo != nil {
+						importPath := strings.TrimPrefix(relDir, vendorDir+"/")
+						packages[relDir].ImportPath = importPath
+						packages[relDir].Module, _, _ = vendorInfo.ModuleForPackage(importPath)
+						packages[relDir].Vendored = true
+					} else if module := moduleForDir(modules, relDir); module != nil {
+						packages[relDir].Module = module.Path
+						packages[relDir].ImportPath = module.ImportPath(relDir)
+					}
+					a.logger.Debug("Discovered package", "name", file.Name.Name, "dir", relDir, "files", len(files))
 				}
 			}
 		}
@@ -346,7 +495,7 @@ func (a *PackageAnalyzer) DiscoverPackages(repoPath string) (map[string]*Package
 		return nil
 	})
 
-	fmt.Printf("Discovered %d packages\n", len(packages))
+	a.logger.Info("Discovered packages", "repo", repoPath, "count", len(packages))
 	return packages, err
 }
 
@@ -367,41 +516,121 @@ func (a *PackageAnalyzer) findFilesInPackage(packageDir string) ([]string, error
 	return files, nil
 }
 
-// AnalyzePackage analyzes a specific package on-demand
-func (a *PackageAnalyzer) AnalyzePackage(repoPath, packagePath string) (*PackageInfo, error) {
-	fmt.Printf("Analyzing package: %s in %s\n", packagePath, repoPath)
+// usesPackages reports whether packages are analyzed with golang.org/x/tools/go/packages,
+// which is the case once a repository context is set
+func (a *PackageAnalyzer) usesPackages() bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.packagesAnalyzer != nil
+}
 
-	// Use packages analyzer if available
-	if a.packagesAnalyzer != nil {
-		fmt.Printf("Using golang.org/x/tools/go/packages for analysis\n")
-		
-		// Parse module information for context
-		moduleInfo, err := a.ParseModuleInfo(repoPath)
-		if err != nil {
-			fmt.Printf("Warning: failed to parse module info for packages analyzer: %v\n", err)
-			moduleInfo = &ModuleInfo{
-				ModulePath:   "",
-				Dependencies: make(map[string]string),
-				Replaces:     make(map[string]string),
-			}
+// cachedAnalysis returns the analysis of key from the analysis cache, or runs analyze
+// once for every concurrent request of key and caches its result. The missing
+// dependencies of an incomplete analysis are queued for download.
+func (a *PackageAnalyzer) cachedAnalysis(ctx context.Context, pa *PackagesAnalyzer, key CacheKey, analyze func(ctx context.Context) (*CachedAnalysis, error)) (*CachedAnalysis, error) {
+	a.mutex.Lock()
+	cache, queue := a.analysisCache, a.dependencyQueue
+	a.mutex.Unlock()
+
+	if cache != nil {
+		if cached, result := cache.Get(key, ""); result == CacheResultHit {
+			a.loadMissingDependencies(ctx, cache, queue, pa, key, cached)
+			return cached, nil
 		}
-		
-		// Set module context for proper external reference resolution
-		a.packagesAnalyzer.SetModuleContext(moduleInfo)
-		
-		return a.packagesAnalyzer.AnalyzePackageWithPackages(packagePath)
 	}
 
-	// Parse module information
-	moduleInfo, err := a.ParseModuleInfo(repoPath)
+	analysis, err, _ := a.analyses.Do(ctx, key.String(), func(ctx context.Context) (*CachedAnalysis, error) {
+		analysis, err := analyze(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if cache != nil {
+			cache.Set(key, analysis)
+		}
+		return analysis, nil
+	})
 	if err != nil {
-		fmt.Printf("Warning: failed to parse module info: %v\n", err)
-		// Continue without module info
-		moduleInfo = &ModuleInfo{
-			ModulePath:   "",
-			Dependencies: make(map[string]string),
-			Replaces:     make(map[string]string),
+		return nil, err
+	}
+	a.loadMissingDependencies(ctx, cache, queue, pa, key, analysis)
+	return analysis, nil
+}
+
+// loadMissingDependencies submits the missing dependencies of the analysis of key to
+// queue, unless they are already being downloaded. Once some are downloaded, the
+// module of pa is loaded again on the next request and its analyses are dropped from
+// cache.
+func (a *PackageAnalyzer) loadMissingDependencies(ctx context.Context, cache *AnalysisCache, queue *DependencyQueue, pa *PackagesAnalyzer, key CacheKey, analysis *CachedAnalysis) {
+	if queue == nil || analysis.IsComplete || len(analysis.MissingDependencies) == 0 || queue.IsActive(key) {
+		return
+	}
+
+	requestID := logging.RequestID(ctx)
+	if requestID == "" {
+		requestID = fmt.Sprintf("%s_%d", key.String(), time.Now().Unix())
+	}
+	req := DependencyDownloadRequest{
+		WorkDir:      pa.config.Dir,
+		Dependencies: analysis.MissingDependencies,
+		CacheKey:     key,
+		RequestID:    requestID,
+		ResultChan:   make(chan DependencyDownloadResult, 1),
+	}
+	if cache != nil {
+		cache.MarkDependencyLoadingInProgress(key, true)
+	}
+	if err := queue.SubmitDownloadRequest(req); err != nil {
+		a.logger.WarnContext(ctx, "Failed to submit dependency download request", "key", key.String(), "error", err)
+		if cache != nil {
+			cache.MarkDependencyLoadingInProgress(key, false)
 		}
+		return
+	}
+	a.logger.InfoContext(ctx, "Queued missing dependencies", "key", key.String(), "dependencies", analysis.MissingDependencies)
+
+	go func() {
+		result := <-req.ResultChan
+		if cache != nil {
+			cache.MarkDependencyLoadingInProgress(key, false)
+		}
+		if len(result.Successful) == 0 {
+			return
+		}
+		a.logger.Info("Reloading module with downloaded dependencies", "dir", pa.config.Dir,
+			logging.RequestIDKey, result.RequestID, "dependencies", result.Successful)
+		pa.InvalidateModuleLoad()
+		if cache != nil {
+			cache.Clear(pa.config.Dir)
+		}
+	}()
+}
+
+// AnalyzePackage analyzes a specific package on-demand. Loading packages stops when
+// ctx is done or the load timeout expires.
+func (a *PackageAnalyzer) AnalyzePackage(ctx context.Context, repoPath, packagePath string) (*PackageInfo, error) {
+	a.logger.DebugContext(ctx, "Analyzing package", "package", packagePath, "repo", repoPath)
+
+	// Packages of nested modules are analyzed in the context of their own go.mod
+	moduleDir, moduleInfo := a.moduleContext(repoPath, packagePath)
+
+	// Use packages analyzer if available
+	if a.usesPackages() {
+		a.logger.DebugContext(ctx, "Using golang.org/x/tools/go/packages for analysis")
+		
+		// The analyzer of the module resolves external references with its go.mod
+		packagesAnalyzer := a.packagesAnalyzerFor(repoPath, moduleDir)
+		key := CacheKey{Type: CacheKeyTypePackage, Dir: packagesAnalyzer.config.Dir, PackagePath: packagePath}
+		analysis, err := a.cachedAnalysis(ctx, packagesAnalyzer, key, func(ctx context.Context) (*CachedAnalysis, error) {
+			response, err := packagesAnalyzer.AnalyzePackageWithQuality(ctx, packagePath)
+			if err != nil {
+				return nil, err
+			}
+			return newCachedAnalysis(packagePath, response), nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return analysis.PackageInfo, nil
 	}
 
 	// Determine absolute path of package
@@ -441,14 +670,14 @@ func (a *PackageAnalyzer) AnalyzePackage(repoPath, packagePath string) (*Package
 	}
 
 	// Analyze the package
-	packageInfo, err := a.analyzePackage(packageName, astPackage, repoPath, moduleInfo)
+	packageInfo, err := a.analyzePackage(ctx, packageName, astPackage, repoPath, moduleInfo)
 	if err != nil {
 		return nil, err
 	}
 
 	// Cache the analyzed package
 	a.packages[cacheKey] = packageInfo
-	fmt.Printf("Successfully analyzed package '%s' with %d symbols\n", packageName, len(packageInfo.Symbols))
+	a.logger.InfoContext(ctx, "Analyzed package", "package", packageName, "symbols", len(packageInfo.Symbols))
 
 	return packageInfo, nil
 }
@@ -477,7 +706,7 @@ func (a *PackageAnalyzer) findAllPackages(rootPath string) (map[string]string, e
 				file, err := parser.ParseFile(a.fset, path, nil, parser.PackageClauseOnly)
 				if err == nil && file.Name != nil {
 					packages[dir] = file.Name.Name
-					fmt.Printf("Found package '%s' in %s\n", file.Name.Name, dir)
+					a.logger.Debug("Found package", "name", file.Name.Name, "dir", dir)
 				}
 			}
 		}
@@ -488,7 +717,7 @@ func (a *PackageAnalyzer) findAllPackages(rootPath string) (map[string]string, e
 	return packages, err
 }
 
-func (a *PackageAnalyzer) analyzeSinglePackage(pkgName, pkgPath, repoRoot string) (*PackageInfo, error) {
+func (a *PackageAnalyzer) analyzeSinglePackage(ctx context.Context, pkgName, pkgPath, repoRoot string) (*PackageInfo, error) {
 	// Parse all Go files in this specific directory
 	fileFilter := func(info os.FileInfo) bool {
 		name := info.Name()
@@ -508,7 +737,7 @@ func (a *PackageAnalyzer) analyzeSinglePackage(pkgName, pkgPath, repoRoot string
 	// Parse module information for this call too
 	moduleInfo, err := a.ParseModuleInfo(repoRoot)
 	if err != nil {
-		fmt.Printf("Warning: failed to parse module info: %v\n", err)
+		a.logger.Warn("Failed to parse module info", "error", err)
 		// Continue without module info
 		moduleInfo = &ModuleInfo{
 			ModulePath:   "",
@@ -517,18 +746,18 @@ func (a *PackageAnalyzer) analyzeSinglePackage(pkgName, pkgPath, repoRoot string
 		}
 	}
 
-	return a.analyzePackage(pkgName, pkg, repoRoot, moduleInfo)
+	return a.analyzePackage(ctx, pkgName, pkg, repoRoot, moduleInfo)
 }
 
-func (a *PackageAnalyzer) analyzePackage(pkgName string, pkg *ast.Package, basePath string, moduleInfo *ModuleInfo) (*PackageInfo, error) {
-	fmt.Printf("Analyzing package: %s\n", pkgName)
+func (a *PackageAnalyzer) analyzePackage(ctx context.Context, pkgName string, pkg *ast.Package, basePath string, moduleInfo *ModuleInfo) (*PackageInfo, error) {
+	a.logger.DebugContext(ctx, "Analyzing package", "package", pkgName)
 
 	// Prepare for type checking
 	config := &types.Config{
 		Importer: importer.Default(),
 		Error: func(err error) {
 			// Ignore errors for now - we want to analyze as much as possible
-			fmt.Printf("Type checker error: %v\n", err)
+			a.logger.DebugContext(ctx, "Type checker error", "error", err)
 		},
 	}
 
@@ -550,7 +779,7 @@ func (a *PackageAnalyzer) analyzePackage(pkgName string, pkg *ast.Package, baseP
 
 	typesPackage, err := config.Check(pkgName, a.fset, files, info)
 	if err != nil {
-		fmt.Printf("Type checking failed (continuing anyway): %v\n", err)
+		a.logger.DebugContext(ctx, "Type checking failed, continuing anyway", "error", err)
 	}
 
 	// Create package info
@@ -567,9 +796,9 @@ func (a *PackageAnalyzer) analyzePackage(pkgName string, pkg *ast.Package, baseP
 		relPath, _ := filepath.Rel(basePath, filePath)
 		relPath = filepath.ToSlash(relPath)
 
-		fileInfo, err := a.analyzeFile(file, relPath, info, typesPackage, basePath, moduleInfo)
+		fileInfo, err := a.analyzeFile(ctx, file, relPath, info, typesPackage, basePath, moduleInfo)
 		if err != nil {
-			fmt.Printf("Failed to analyze file %s: %v\n", relPath, err)
+			a.logger.WarnContext(ctx, "Failed to analyze file", "file", relPath, "error", err)
 			continue
 		}
 
@@ -584,7 +813,7 @@ func (a *PackageAnalyzer) analyzePackage(pkgName string, pkg *ast.Package, baseP
 			packageInfo.Symbols[symbol.Name] = symbol
 		}
 		
-		fmt.Printf("File %s has %d symbols and %d references\n", relPath, len(fileInfo.Symbols), len(fileInfo.References))
+		a.logger.DebugContext(ctx, "Analyzed file", "file", relPath, "symbols", len(fileInfo.Symbols), "references", len(fileInfo.References))
 	}
 
 	// Reference resolution no longer needed - handled during file analysis
@@ -593,8 +822,8 @@ func (a *PackageAnalyzer) analyzePackage(pkgName string, pkg *ast.Package, baseP
 	return packageInfo, nil
 }
 
-func (a *PackageAnalyzer) analyzeFile(file *ast.File, relPath string, info *types.Info, pkg *types.Package, basePath string, moduleInfo *ModuleInfo) (*FileInfo, error) {
-	fmt.Printf("Analyzing file: %s\n", relPath)
+func (a *PackageAnalyzer) analyzeFile(ctx context.Context, file *ast.File, relPath string, info *types.Info, pkg *types.Package, basePath string, moduleInfo *ModuleInfo) (*FileInfo, error) {
+	a.logger.DebugContext(ctx, "Analyzing file", "file", relPath)
 
 	fileInfo := &FileInfo{
 		Path:       relPath,
@@ -610,7 +839,7 @@ func (a *PackageAnalyzer) analyzeFile(file *ast.File, relPath string, info *type
 		if sourceContent, err := os.ReadFile(position.Filename); err == nil {
 			fileInfo.Source = string(sourceContent)
 		} else {
-			fmt.Printf("Failed to read source for %s: %v\n", relPath, err)
+			a.logger.Warn("Failed to read source", "file", relPath, "error", err)
 		}
 	}
 
@@ -640,7 +869,6 @@ func (a *PackageAnalyzer) analyzeFile(file *ast.File, relPath string, info *type
 				symbol := a.createSymbolFromObject(obj, relPath, pos, moduleInfo)
 				if symbol != nil {
 					fileInfo.Symbols[symbol.Name] = symbol
-					fmt.Printf("Found definition: %s at %s:%d (isStdLib=%t) pkg=%s\n", symbol.Name, relPath, pos.Line, symbol.IsStdLib, symbol.Package)
 				}
 			}
 
@@ -653,14 +881,9 @@ func (a *PackageAnalyzer) analyzeFile(file *ast.File, relPath string, info *type
 					Column: pos.Column,
 				}
 				
-				
 				// Try to create target symbol information from the type checker
 				if targetSymbol := a.createSymbolFromObjectWithBase(obj, "", a.fset.Position(obj.Pos()), basePath, moduleInfo); targetSymbol != nil {
 					ref.Target = targetSymbol
-					fmt.Printf("Found reference with target: %s -> %s:%d (%s)\n", 
-						node.Name, targetSymbol.File, targetSymbol.Line, targetSymbol.Package)
-				} else {
-					fmt.Printf("Found reference without target: %s at %s:%d\n", node.Name, relPath, pos.Line)
 				}
 				
 				fileInfo.References = append(fileInfo.References, ref)
@@ -670,10 +893,6 @@ func (a *PackageAnalyzer) analyzeFile(file *ast.File, relPath string, info *type
 			// Handle selector expressions like pkg.Symbol
 			pos := a.fset.Position(node.Sel.Pos())
 			
-			if ident, ok := node.X.(*ast.Ident); ok {
-				fmt.Printf("SelectorExpr: Processing %s.%s at %s:%d\n", ident.Name, node.Sel.Name, relPath, pos.Line)
-			}
-			
 			// First try to resolve using type checker (for internal references)
 			if obj := info.Uses[node.Sel]; obj != nil {
 				ref := &Reference{
@@ -686,10 +905,6 @@ func (a *PackageAnalyzer) analyzeFile(file *ast.File, relPath string, info *type
 				// Try to create target symbol information from the type checker
 				if targetSymbol := a.createSymbolFromObjectWithBase(obj, "", a.fset.Position(obj.Pos()), basePath, moduleInfo); targetSymbol != nil {
 					ref.Target = targetSymbol
-					fmt.Printf("Found selector reference with target: %s -> %s:%d (%s)\n", 
-						node.Sel.Name, targetSymbol.File, targetSymbol.Line, targetSymbol.Package)
-				} else {
-					fmt.Printf("Found selector reference without target: %s at %s:%d\n", node.Sel.Name, relPath, pos.Line)
 				}
 				
 				fileInfo.References = append(fileInfo.References, ref)
@@ -708,10 +923,6 @@ func (a *PackageAnalyzer) analyzeFile(file *ast.File, relPath string, info *type
 						// Try to create target symbol information from the type
 						if targetSymbol := a.createSymbolFromObjectWithBase(obj, "", a.fset.Position(obj.Pos()), basePath, moduleInfo); targetSymbol != nil {
 							ref.Target = targetSymbol
-							fmt.Printf("SelectorExpr: Found selector type reference with target: %s -> %s:%d (%s)\n", 
-								node.Sel.Name, targetSymbol.File, targetSymbol.Line, targetSymbol.Package)
-						} else {
-							fmt.Printf("SelectorExpr: Found selector type reference without target: %s at %s:%d\n", node.Sel.Name, relPath, pos.Line)
 						}
 						
 						fileInfo.References = append(fileInfo.References, ref)
@@ -765,14 +976,6 @@ func (a *PackageAnalyzer) analyzeFile(file *ast.File, relPath string, info *type
 									},
 								}
 								
-								if isExternal {
-									fmt.Printf("SelectorExpr: Found cross-repository reference: %s.%s -> %s@%s (external)\n", 
-										packageName, node.Sel.Name, resolvedPath, version)
-								} else {
-									fmt.Printf("SelectorExpr: Found same-repository reference: %s.%s -> %s (internal)\n", 
-										packageName, node.Sel.Name, importPath)
-								}
-								
 								fileInfo.References = append(fileInfo.References, ref)
 								break
 							}
@@ -784,10 +987,8 @@ func (a *PackageAnalyzer) analyzeFile(file *ast.File, relPath string, info *type
 				// Check if the left side (X) is an identifier that corresponds to an import
 				if ident, ok := node.X.(*ast.Ident); ok {
 					packageName := ident.Name
-					fmt.Printf("Fallback: Processing selector %s.%s at %s:%d\n", packageName, node.Sel.Name, relPath, pos.Line)
 					
 					// Check if this package name corresponds to an import
-					found := false
 					for _, importInfo := range fileInfo.Imports {
 						var importAlias string
 						if importInfo.Alias != "" {
@@ -830,23 +1031,10 @@ func (a *PackageAnalyzer) analyzeFile(file *ast.File, relPath string, info *type
 								},
 							}
 							
-							if isExternal {
-								fmt.Printf("Found cross-repository reference: %s.%s -> %s@%s (external)\n", 
-									packageName, node.Sel.Name, resolvedPath, version)
-							} else {
-								fmt.Printf("Found same-repository reference: %s.%s -> %s (internal)\n", 
-									packageName, node.Sel.Name, importPath)
-							}
-							
 							fileInfo.References = append(fileInfo.References, ref)
-							found = true
 							break
 						}
 					}
-					// If we get here, no matching import was found
-					if !found {
-						fmt.Printf("Fallback: No matching import found for package '%s' in selector %s.%s\n", packageName, packageName, node.Sel.Name)
-					}
 				}
 			}
 		
@@ -904,14 +1092,6 @@ func (a *PackageAnalyzer) analyzeFile(file *ast.File, relPath string, info *type
 								},
 							}
 							
-							if isExternal {
-								fmt.Printf("Found cross-repository reference in composite literal: %s.%s -> %s@%s (external)\n", 
-									packageName, selectorType.Sel.Name, resolvedPath, version)
-							} else {
-								fmt.Printf("Found same-repository reference in composite literal: %s.%s -> %s (internal)\n", 
-									packageName, selectorType.Sel.Name, importPath)
-							}
-							
 							fileInfo.References = append(fileInfo.References, ref)
 							break
 						}
@@ -926,16 +1106,8 @@ func (a *PackageAnalyzer) analyzeFile(file *ast.File, relPath string, info *type
 				// This is a pointer to a selector type (*pkg.Type)
 				pos := a.fset.Position(selectorExpr.Sel.Pos())
 				
-				if ident, ok := selectorExpr.X.(*ast.Ident); ok {
-					fmt.Printf("StarExpr: Processing pointer selector *%s.%s at %s:%d\n", 
-						ident.Name, selectorExpr.Sel.Name, relPath, pos.Line)
-				} else {
-					fmt.Printf("StarExpr: Processing pointer selector (complex) at %s:%d\n", relPath, pos.Line)
-				}
-				
 				// First try to resolve using type checker (for internal references)
 				if obj := info.Uses[selectorExpr.Sel]; obj != nil {
-					fmt.Printf("StarExpr: Found obj in Uses for %s\n", selectorExpr.Sel.Name)
 					ref := &Reference{
 						Name:   selectorExpr.Sel.Name,
 						File:   relPath,
@@ -946,10 +1118,6 @@ func (a *PackageAnalyzer) analyzeFile(file *ast.File, relPath string, info *type
 					// Try to create target symbol information from the type checker
 					if targetSymbol := a.createSymbolFromObjectWithBase(obj, "", a.fset.Position(obj.Pos()), basePath, moduleInfo); targetSymbol != nil {
 						ref.Target = targetSymbol
-						fmt.Printf("StarExpr: Found reference with target: %s -> %s:%d (%s)\n", 
-							selectorExpr.Sel.Name, targetSymbol.File, targetSymbol.Line, targetSymbol.Package)
-					} else {
-						fmt.Printf("StarExpr: Found reference without target: %s at %s:%d\n", selectorExpr.Sel.Name, relPath, pos.Line)
 					}
 					
 					fileInfo.References = append(fileInfo.References, ref)
@@ -968,10 +1136,6 @@ func (a *PackageAnalyzer) analyzeFile(file *ast.File, relPath string, info *type
 							// Try to create target symbol information from the type
 							if targetSymbol := a.createSymbolFromObjectWithBase(obj, "", a.fset.Position(obj.Pos()), basePath, moduleInfo); targetSymbol != nil {
 								ref.Target = targetSymbol
-								fmt.Printf("StarExpr: Found type reference with target: %s -> %s:%d (%s)\n", 
-									selectorExpr.Sel.Name, targetSymbol.File, targetSymbol.Line, targetSymbol.Package)
-							} else {
-								fmt.Printf("StarExpr: Found type reference without target: %s at %s:%d\n", selectorExpr.Sel.Name, relPath, pos.Line)
 							}
 							
 							fileInfo.References = append(fileInfo.References, ref)
@@ -1025,14 +1189,6 @@ func (a *PackageAnalyzer) analyzeFile(file *ast.File, relPath string, info *type
 										},
 									}
 									
-									if isExternal {
-										fmt.Printf("StarExpr: Found cross-repository reference: *%s.%s -> %s@%s (external)\n", 
-											packageName, selectorExpr.Sel.Name, resolvedPath, version)
-									} else {
-										fmt.Printf("StarExpr: Found same-repository reference: *%s.%s -> %s (internal)\n", 
-											packageName, selectorExpr.Sel.Name, importPath)
-									}
-									
 									fileInfo.References = append(fileInfo.References, ref)
 									break
 								}
@@ -1040,11 +1196,9 @@ func (a *PackageAnalyzer) analyzeFile(file *ast.File, relPath string, info *type
 						}
 					}
 				} else {
-					fmt.Printf("StarExpr: Neither Uses nor Types found for %s, falling back\n", selectorExpr.Sel.Name)
 					// Fallback: Create lazy external reference for *package.Symbol patterns
 					if ident, ok := selectorExpr.X.(*ast.Ident); ok {
 						packageName := ident.Name
-						fmt.Printf("StarExpr: Fallback processing pointer selector *%s.%s at %s:%d\n", packageName, selectorExpr.Sel.Name, relPath, pos.Line)
 						
 						// Check if this package name corresponds to an import
 						for _, importInfo := range fileInfo.Imports {
@@ -1089,14 +1243,6 @@ func (a *PackageAnalyzer) analyzeFile(file *ast.File, relPath string, info *type
 									},
 								}
 								
-								if isExternal {
-									fmt.Printf("StarExpr: Found cross-repository reference: *%s.%s -> %s@%s (external)\n", 
-										packageName, selectorExpr.Sel.Name, resolvedPath, version)
-								} else {
-									fmt.Printf("StarExpr: Found same-repository reference: *%s.%s -> %s (internal)\n", 
-										packageName, selectorExpr.Sel.Name, importPath)
-								}
-								
 								fileInfo.References = append(fileInfo.References, ref)
 								break
 							}
@@ -1111,7 +1257,6 @@ func (a *PackageAnalyzer) analyzeFile(file *ast.File, relPath string, info *type
 
 	return fileInfo, nil
 }
-
 func (a *PackageAnalyzer) createSymbolFromObjectWithBase(obj types.Object, file string, pos token.Position, basePath string, moduleInfo *ModuleInfo) *Symbol {
 	if obj == nil {
 		return nil
@@ -1165,6 +1310,9 @@ func (a *PackageAnalyzer) createSymbolFromObjectWithBase(obj types.Object, file
 		symbol.Signature = o.Type().String()
 	case *types.TypeName:
 		symbol.Type = "type"
+		if isTypeParam(o) {
+			symbol.Type = "typeparam"
+		}
 		symbol.Signature = o.Type().String()
 	case *types.Var:
 		if o.IsField() {
@@ -1179,6 +1327,7 @@ func (a *PackageAnalyzer) createSymbolFromObjectWithBase(obj types.Object, file
 	default:
 		symbol.Type = "unknown"
 	}
+	symbol.Deprecated = a.deprecationNoticeOf(obj)
 
 	return symbol
 }
@@ -1226,6 +1375,9 @@ func (a *PackageAnalyzer) createSymbolFromObject(obj types.Object, file string,
 		symbol.Signature = o.Type().String()
 	case *types.TypeName:
 		symbol.Type = "type"
+		if isTypeParam(o) {
+			symbol.Type = "typeparam"
+		}
 		symbol.Signature = o.Type().String()
 	case *types.Var:
 		if o.IsField() {
@@ -1240,6 +1392,7 @@ func (a *PackageAnalyzer) createSymbolFromObject(obj types.Object, file string,
 	default:
 		symbol.Type = "unknown"
 	}
+	symbol.Deprecated = a.deprecationNoticeOf(obj)
 
 	return symbol
 }
@@ -1250,38 +1403,30 @@ func (a *PackageAnalyzer) createSymbolFromObject(obj types.Object, file string,
 
 // resolveCrossPackageReferences function removed - no longer needed since Files field changed to []string
 
-// AnalyzeSingleFile analyzes a single file and returns detailed file information
-func (a *PackageAnalyzer) AnalyzeSingleFile(repoPath, filePath string) (*FileInfo, error) {
+// AnalyzeSingleFile analyzes a single file and returns detailed file information.
+// Loading packages stops when ctx is done or the load timeout expires.
+func (a *PackageAnalyzer) AnalyzeSingleFile(ctx context.Context, repoPath, filePath string) (*FileInfo, error) {
+	// Files of nested modules are analyzed in the context of their own go.mod
+	moduleDir, moduleInfo := a.moduleContext(repoPath, filepath.Dir(filePath))
+
 	// Use packages analyzer if available
-	if a.packagesAnalyzer != nil {
-		fmt.Printf("Using golang.org/x/tools/go/packages for file analysis\n")
+	if a.usesPackages() {
+		a.logger.DebugContext(ctx, "Using golang.org/x/tools/go/packages for file analysis")
 		
-		// Parse module information for context
-		moduleInfo, err := a.ParseModuleInfo(repoPath)
-		if err != nil {
-			fmt.Printf("Warning: failed to parse module info for packages analyzer: %v\n", err)
-			moduleInfo = &ModuleInfo{
-				ModulePath:   "",
-				Dependencies: make(map[string]string),
-				Replaces:     make(map[string]string),
+		// The analyzer of the module resolves external references with its go.mod
+		packagesAnalyzer := a.packagesAnalyzerFor(repoPath, moduleDir)
+		key := CacheKey{Type: CacheKeyTypeFile, Dir: packagesAnalyzer.config.Dir, PackagePath: filepath.Dir(filePath), FilePath: filePath}
+		analysis, err := a.cachedAnalysis(ctx, packagesAnalyzer, key, func(ctx context.Context) (*CachedAnalysis, error) {
+			response, err := packagesAnalyzer.AnalyzeSingleFileWithQuality(ctx, filePath)
+			if err != nil {
+				return nil, err
 			}
+			return newCachedAnalysis(filePath, response), nil
+		})
+		if err != nil {
+			return nil, err
 		}
-		
-		// Set module context for proper external reference resolution
-		a.packagesAnalyzer.SetModuleContext(moduleInfo)
-		
-		return a.packagesAnalyzer.AnalyzeSingleFileWithPackages(filePath)
-	}
-
-	// Parse module information
-	moduleInfo, err := a.ParseModuleInfo(repoPath)
-	if err != nil {
-		fmt.Printf("Warning: failed to parse module info: %v\n", err)
-		moduleInfo = &ModuleInfo{
-			ModulePath:   "",
-			Dependencies: make(map[string]string),
-			Replaces:     make(map[string]string),
-		}
+		return analysis.FileInfo, nil
 	}
 
 	// We'll find the target file from the package parsing below
@@ -1322,12 +1467,15 @@ func (a *PackageAnalyzer) AnalyzeSingleFile(repoPath, filePath string) (*FileInf
 		return nil, fmt.Errorf("no package found in %s", absolutePackagePath)
 	}
 
-	// Prepare for type checking
+	// Prepare for type checking. Errors are reported as diagnostics of the file.
+	var typeErrors []types.Error
 	config := &types.Config{
 		Importer: importer.Default(),
 		Error: func(err error) {
-			// Ignore errors for now
-			fmt.Printf("Type checker error: %v\n", err)
+			a.logger.DebugContext(ctx, "Type checker error", "error", err)
+			if typeErr, ok := err.(types.Error); ok {
+				typeErrors = append(typeErrors, typeErr)
+			}
 		},
 	}
 
@@ -1352,120 +1500,73 @@ func (a *PackageAnalyzer) AnalyzeSingleFile(repoPath, filePath string) (*FileInf
 		Defs:  make(map[*ast.Ident]types.Object),
 		Uses:  make(map[*ast.Ident]types.Object),
 		Types: make(map[ast.Expr]types.TypeAndValue),
+		Scopes:    make(map[ast.Node]*types.Scope),
+		Implicits: make(map[ast.Node]types.Object),
 	}
 
 	typesPackage, err := config.Check(targetFile.Name.Name, a.fset, files, info)
 	if err != nil {
-		fmt.Printf("Type checking failed (continuing anyway): %v\n", err)
+		a.logger.DebugContext(ctx, "Type checking failed, continuing anyway", "error", err)
 	}
 
 	// Convert relative path
 	relPath := filepath.ToSlash(filePath)
 	
 	// Analyze the specific file using the AST file from the package parsing
-	fileInfo, err := a.analyzeFile(targetFile, relPath, info, typesPackage, repoPath, moduleInfo)
+	fileInfo, err := a.analyzeFile(ctx, targetFile, relPath, info, typesPackage, repoPath, moduleInfo)
 	if err != nil {
 		return nil, err
 	}
 
-	// Add scope-aware information if needed
-	scopes, err := a.extractScopes(targetFile, a.fset, info)
-	if err != nil {
-		fmt.Printf("Warning: failed to extract scopes: %v\n", err)
-	} else {
-		fileInfo.Scopes = scopes
-	}
-
-	definitions, err := a.extractDefinitions(targetFile, a.fset, info)
-	if err != nil {
-		fmt.Printf("Warning: failed to extract definitions: %v\n", err)
-	} else {
-		fileInfo.Definitions = definitions
-	}
-
-	return fileInfo, nil
-}
-
-// extractScopes extracts scope information from an AST file
-func (a *PackageAnalyzer) extractScopes(file *ast.File, fset *token.FileSet, info *types.Info) ([]*ScopeInfo, error) {
-	var scopes []*ScopeInfo
-	
-	// Track parent-child relationships for hierarchical scope IDs
-	currentFunctionScope := ""
-	scopeCounters := make(map[string]int) // For numbering scopes within parents
-
-	// Walk AST nodes to identify scopes and their hierarchy
-	ast.Inspect(file, func(n ast.Node) bool {
-		switch node := n.(type) {
-		case *ast.FuncDecl:
-			if node.Name != nil {
-				// Function scope
-				start := fset.Position(node.Pos())
-				end := fset.Position(node.End())
-				
-				scopeID := "/" + node.Name.Name
-				currentFunctionScope = scopeID
-				
-				scopes = append(scopes, &ScopeInfo{
-					ID:   scopeID,
-					Type: "function",
-					Name: node.Name.Name,
-					Range: Range{
-						Start: Position{Line: start.Line, Column: start.Column},
-						End:   Position{Line: end.Line, Column: end.Column},
-					},
-				})
-			}
-
-		case *ast.IfStmt:
-			// If statement creates a block scope
-			if node.Body != nil {
-				start := fset.Position(node.Body.Pos())
-				end := fset.Position(node.Body.End())
-				
-				// Build hierarchical scope ID
-				parentScope := currentFunctionScope
-				if parentScope == "" {
-					parentScope = "/"
-				}
-				
-				// Number the if blocks within the parent scope
-				scopeCounters[parentScope]++
-				blockNum := scopeCounters[parentScope]
-				
-				scopeID := parentScope + "/if_" + fmt.Sprintf("%d", blockNum)
-				if parentScope == "/" {
-					scopeID = "/if_" + fmt.Sprintf("%d", blockNum)
-				}
-				
-				scopes = append(scopes, &ScopeInfo{
-					ID:   scopeID,
-					Type: "block",
-					Range: Range{
-						Start: Position{Line: start.Line, Column: start.Column},
-						End:   Position{Line: end.Line, Column: end.Column},
-					},
-				})
-			}
+	// Add scope-aware information: the scope tree, the definitions placed in it and
+	// the links from references to the definitions
+	tree := buildScopeTree(targetFile, a.fset, info)
+	fileInfo.Scopes = tree.Scopes()
+	definitions, byObject := a.collectDefinitions(targetFile, a.fset, info, tree)
+	fileInfo.Definitions = definitions
+	fileInfo.Outline = extractOutline(targetFile, a.fset)
+	fileInfo.Diagnostics = typeErrorDiagnostics(typeErrors, a.fset.Position(targetFile.Pos()).Filename, importSpecPaths(files))
+	sortDiagnostics(fileInfo.Diagnostics)
+	fileInfo.SemanticTokens = semanticTokens(targetFile, a.fset, info, func(obj types.Object) (bool, bool) {
+		path := objectPackagePath(obj)
+		if path == "" {
+			return true, true // Universe scope
 		}
-		return true
+		stdlib := a.IsStandardLibraryImportWithContext(path, moduleInfo)
+		inModule := typesPackage != nil && path == typesPackage.Path() ||
+			moduleInfo.ModulePath != "" && (path == moduleInfo.ModulePath || strings.HasPrefix(path, moduleInfo.ModulePath+"/"))
+		return stdlib, !inModule
+	}, func(obj types.Object) bool {
+		return a.deprecationNoticeOf(obj) != ""
+	})
+	a.linkLocalReferences(fileInfo, targetFile, a.fset, info, byObject)
+	linkShadowing(targetFile, a.fset, info, byObject, func(filename string) string {
+		if rel, err := filepath.Rel(repoPath, filename); err == nil {
+			return filepath.ToSlash(rel)
+		}
+		return filename
 	})
 
-	return scopes, nil
+	return fileInfo, nil
 }
 
-// isInterestingBlock determines if a block statement represents a significant scope
-func (a *PackageAnalyzer) isInterestingBlock(block *ast.BlockStmt) bool {
-	// For now, return false for all blocks - we only want specific control flow blocks
-	// In a more complete implementation, we'd check if this block is part of
-	// an if statement, for loop, etc. by examining parent nodes
-	return false
+// extractScopes extracts the lexical scopes of an AST file, see scopeTree
+func (a *PackageAnalyzer) extractScopes(file *ast.File, fset *token.FileSet, info *types.Info) ([]*ScopeInfo, error) {
+	return buildScopeTree(file, fset, info).Scopes(), nil
 }
 
 // extractDefinitions extracts local symbol definitions from an AST file
 func (a *PackageAnalyzer) extractDefinitions(file *ast.File, fset *token.FileSet, info *types.Info) ([]*Definition, error) {
+	definitions, _ := a.collectDefinitions(file, fset, info, buildScopeTree(file, fset, info))
+	return definitions, nil
+}
+
+// collectDefinitions extracts the definitions of file, placed in the scopes of tree.
+// With type information, it also returns the definition of each object defined in
+// the file, so references to them can be linked.
+func (a *PackageAnalyzer) collectDefinitions(file *ast.File, fset *token.FileSet, info *types.Info, tree *scopeTree) ([]*Definition, map[types.Object]*Definition) {
 	var definitions []*Definition
-	defCounter := 1
+	byObject := make(map[types.Object]*Definition)
 	currentFunctionScope := ""
 
 	// If we have type info, use it; otherwise extract from AST directly
@@ -1473,41 +1574,48 @@ func (a *PackageAnalyzer) extractDefinitions(file *ast.File, fset *token.FileSet
 		// Walk the AST to find definitions using type information
 		ast.Inspect(file, func(n ast.Node) bool {
 			switch node := n.(type) {
-			case *ast.FuncDecl:
-				if node.Name != nil {
-					currentFunctionScope = "/" + node.Name.Name
+			case *ast.TypeSwitchStmt:
+				// The variable of "switch v := x.(type)" is declared once per clause
+				ident, typ, objects := typeSwitchVar(node, info)
+				if ident == nil {
+					break
+				}
+				signature := ""
+				if typ != nil {
+					signature = typ.String()
+				}
+				pos := fset.Position(ident.Pos())
+				def := &Definition{
+					ID:        definitionID(ident.Name, pos),
+					Name:      ident.Name,
+					Type:      "var",
+					Line:      pos.Line,
+					Column:    pos.Column,
+					ScopeID:   tree.innermost(ident.Pos()),
+					Signature: signature,
+				}
+				definitions = append(definitions, def)
+				for _, obj := range objects {
+					byObject[obj] = def
 				}
 			case *ast.Ident:
 				// Check if this identifier defines a symbol
 				if obj := info.Defs[node]; obj != nil && obj.Type() != nil {
 					pos := fset.Position(node.Pos())
 					
-					// Determine scope ID based on symbol type and context
-					var scopeID string
-					if _, isFunc := obj.(*types.Func); isFunc {
-						// Functions are always defined in global scope
-						scopeID = "/"
-					} else {
-						// Variables and other symbols use current context
-						scopeID = currentFunctionScope
-						if scopeID == "" {
-							scopeID = "/" // Global scope
-						}
-					}
-					
 					// Create definition
 					def := &Definition{
-						ID:        fmt.Sprintf("def_%d", defCounter),
+						ID:        definitionID(node.Name, pos),
 						Name:      node.Name,
 						Type:      a.getObjectType(obj),
 						Line:      pos.Line,
 						Column:    pos.Column,
-						ScopeID:   scopeID,
+						ScopeID:   tree.ScopeID(obj, node.Pos()),
 						Signature: obj.Type().String(),
 					}
 					
 					definitions = append(definitions, def)
-					defCounter++
+					byObject[obj] = def
 				}
 			}
 			return true
@@ -1523,7 +1631,7 @@ func (a *PackageAnalyzer) extractDefinitions(file *ast.File, fset *token.FileSet
 					// Function declarations themselves are defined in the global scope
 					pos := fset.Position(node.Name.Pos())
 					def := &Definition{
-						ID:        fmt.Sprintf("def_%d", defCounter),
+						ID:        definitionID(node.Name.Name, pos),
 						Name:      node.Name.Name,
 						Type:      "func",
 						Line:      pos.Line,
@@ -1533,7 +1641,6 @@ func (a *PackageAnalyzer) extractDefinitions(file *ast.File, fset *token.FileSet
 					}
 					
 					definitions = append(definitions, def)
-					defCounter++
 				}
 			case *ast.GenDecl:
 				// Handle var, const, type declarations
@@ -1550,7 +1657,7 @@ func (a *PackageAnalyzer) extractDefinitions(file *ast.File, fset *token.FileSet
 							}
 							
 							def := &Definition{
-								ID:        fmt.Sprintf("def_%d", defCounter),
+								ID:        definitionID(ident.Name, pos),
 								Name:      ident.Name,
 								Type:      defType,
 								Line:      pos.Line,
@@ -1560,14 +1667,13 @@ func (a *PackageAnalyzer) extractDefinitions(file *ast.File, fset *token.FileSet
 							}
 							
 							definitions = append(definitions, def)
-							defCounter++
 						}
 					case *ast.TypeSpec:
 						// type declaration
 						pos := fset.Position(s.Name.Pos())
 						
 						def := &Definition{
-							ID:        fmt.Sprintf("def_%d", defCounter),
+							ID:        definitionID(s.Name.Name, pos),
 							Name:      s.Name.Name,
 							Type:      "type",
 							Line:      pos.Line,
@@ -1577,7 +1683,6 @@ func (a *PackageAnalyzer) extractDefinitions(file *ast.File, fset *token.FileSet
 						}
 						
 						definitions = append(definitions, def)
-						defCounter++
 					}
 				}
 			case *ast.AssignStmt:
@@ -1594,7 +1699,7 @@ func (a *PackageAnalyzer) extractDefinitions(file *ast.File, fset *token.FileSet
 							}
 							
 							def := &Definition{
-								ID:        fmt.Sprintf("def_%d", defCounter),
+								ID:        definitionID(ident.Name, pos),
 								Name:      ident.Name,
 								Type:      "var",
 								Line:      pos.Line,
@@ -1604,7 +1709,6 @@ func (a *PackageAnalyzer) extractDefinitions(file *ast.File, fset *token.FileSet
 							}
 							
 							definitions = append(definitions, def)
-							defCounter++
 						}
 					}
 				}
@@ -1613,11 +1717,37 @@ func (a *PackageAnalyzer) extractDefinitions(file *ast.File, fset *token.FileSet
 		})
 	}
 
-	return definitions, nil
+	return definitions, byObject
+}
+
+// linkLocalReferences marks the references of fileInfo to objects defined in the
+// file as local, with the ID of their definition
+func (a *PackageAnalyzer) linkLocalReferences(fileInfo *FileInfo, file *ast.File, fset *token.FileSet, info *types.Info, byObject map[types.Object]*Definition) {
+	type location struct{ line, column int }
+	definitionAt := make(map[location]*Definition)
+	ast.Inspect(file, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok {
+			if def := byObject[info.Uses[ident]]; def != nil {
+				pos := fset.Position(ident.Pos())
+				definitionAt[location{pos.Line, pos.Column}] = def
+			}
+		}
+		return true
+	})
+
+	for _, ref := range fileInfo.References {
+		if def := definitionAt[location{ref.Line, ref.Column}]; def != nil {
+			ref.Type = "local"
+			ref.DefinitionID = def.ID
+		}
+	}
 }
 
 // getObjectType returns the type string for a types.Object
 func (a *PackageAnalyzer) getObjectType(obj types.Object) string {
+	if isTypeParam(obj) {
+		return "typeparam"
+	}
 	switch obj.(type) {
 	case *types.Func:
 		return "func"
@@ -1,6 +1,7 @@
 package analyzer
 
 import (
+	"context"
 	"fmt"
 	"go/ast"
 	"go/importer"
@@ -119,7 +120,7 @@ func main() {
 }`,
 			expectedDefinitions: []Definition{
 				{
-					ID:        "def_1",
+					ID:        "def_globalVar_3_5",
 					Name:      "globalVar",
 					Type:      "var",
 					Line:      3,
@@ -128,7 +129,7 @@ func main() {
 					Signature: "int",
 				},
 				{
-					ID:        "def_2",
+					ID:        "def_main_5_6",
 					Name:      "main",
 					Type:      "func",
 					Line:      5,
@@ -137,7 +138,7 @@ func main() {
 					Signature: "func",
 				},
 				{
-					ID:        "def_3",
+					ID:        "def_localVar_6_2",
 					Name:      "localVar",
 					Type:      "var",
 					Line:      6,
@@ -160,7 +161,7 @@ func anotherFunc() {
 }`,
 			expectedDefinitions: []Definition{
 				{
-					ID:        "def_1",
+					ID:        "def_quoteLuaVal_3_6",
 					Name:      "quoteLuaVal",
 					Type:      "func",
 					Line:      3,
@@ -169,7 +170,7 @@ func anotherFunc() {
 					Signature: "func",
 				},
 				{
-					ID:        "def_2", 
+					ID:        "def_x_3_18", 
 					Name:      "x",
 					Type:      "var", 
 					Line:      3,
@@ -178,7 +179,7 @@ func anotherFunc() {
 					Signature: "int",
 				},
 				{
-					ID:        "def_3", 
+					ID:        "def_anotherFunc_7_6", 
 					Name:      "anotherFunc",
 					Type:      "func",
 					Line:      7,
@@ -187,7 +188,7 @@ func anotherFunc() {
 					Signature: "func",
 				},
 				{
-					ID:        "def_4",
+					ID:        "def_localVar_8_2",
 					Name:      "localVar",
 					Type:      "var", 
 					Line:      8,
@@ -212,7 +213,7 @@ type MyStruct struct {
 }`,
 			expectedDefinitions: []Definition{
 				{
-					ID:        "def_1",
+					ID:        "def_MyStruct_8_6",
 					Name:      "MyStruct",
 					Type:      "type",
 					Line:      8,
@@ -221,7 +222,7 @@ type MyStruct struct {
 					Signature: "type",
 				},
 				{
-					ID:        "def_2",
+					ID:        "def_buf_9_2",
 					Name:      "buf",
 					Type:      "var",
 					Line:      9,
@@ -230,7 +231,7 @@ type MyStruct struct {
 					Signature: "int",
 				},
 				{
-					ID:        "def_3",
+					ID:        "def_client_10_2",
 					Name:      "client",
 					Type:      "var",
 					Line:      10,
@@ -279,6 +280,9 @@ type MyStruct struct {
 
 			for i, expected := range tt.expectedDefinitions {
 				actual := definitions[i]
+				if actual.ID != expected.ID {
+					t.Errorf("Definition %d: expected ID %q, got %q", i, expected.ID, actual.ID)
+				}
 				if actual.Name != expected.Name {
 					t.Errorf("Definition %d: expected Name %q, got %q", i, expected.Name, actual.Name)
 				}
@@ -356,7 +360,7 @@ func NewStruct() *MyStruct {
 			}
 
 			// Analyze the file (this will extract references)
-			fileInfo, err := analyzer.AnalyzeSingleFile(tmpDir, "test.go")
+			fileInfo, err := analyzer.AnalyzeSingleFile(context.Background(), tmpDir, "test.go")
 			if err != nil {
 				t.Fatalf("AnalyzeSingleFile failed: %v", err)
 			}
@@ -429,7 +433,7 @@ func main() {
 			}
 
 			// Analyze the file
-			fileInfo, err := analyzer.AnalyzeSingleFile(tmpDir, "test.go")
+			fileInfo, err := analyzer.AnalyzeSingleFile(context.Background(), tmpDir, "test.go")
 			if err != nil {
 				t.Fatalf("AnalyzeSingleFile failed: %v", err)
 			}
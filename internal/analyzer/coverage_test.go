@@ -1,6 +1,7 @@
 package analyzer
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/packages"
 )
 
 // TestComprehensiveCoverage provides comprehensive test coverage for all components
@@ -167,7 +169,7 @@ func testAnalysisCacheCoverage(t *testing.T) {
 	assert.Equal(t, "rev2", cached.Revision)
 	
 	// Test recalculation logic
-	shouldRecalc, availableDeps, err := cache.ShouldRecalculate(packageKey, "/tmp")
+	shouldRecalc, availableDeps, err := cache.ShouldRecalculate(context.Background(), packageKey, "/tmp")
 	assert.NoError(t, err)
 	assert.False(t, shouldRecalc) // Complete analysis shouldn't recalculate
 	assert.Len(t, availableDeps, 0)
@@ -305,27 +307,27 @@ func testRevisionAnalyzerCoverage(t *testing.T, tempDir string) {
 	require.NotNil(t, analyzer)
 	
 	// Test package analysis
-	packageResp, err := analyzer.AnalyzePackage("", "")
+	packageResp, err := analyzer.AnalyzePackage(context.Background(), "", "")
 	require.NoError(t, err)
 	assert.NotNil(t, packageResp.PackageInfo)
 	assert.NotEmpty(t, packageResp.Revision)
 	assert.False(t, packageResp.Complete) // Has missing deps
 	
 	// Test same revision request
-	sameRevResp, err := analyzer.AnalyzePackage("", packageResp.Revision)
+	sameRevResp, err := analyzer.AnalyzePackage(context.Background(), "", packageResp.Revision)
 	require.NoError(t, err)
 	assert.True(t, sameRevResp.NoChange)
 	assert.Equal(t, packageResp.Revision, sameRevResp.Revision)
 	
 	// Test file analysis
-	fileResp, err := analyzer.AnalyzeFile("", "main.go", "")
+	fileResp, err := analyzer.AnalyzeFile(context.Background(), "", "main.go", "")
 	require.NoError(t, err)
 	assert.NotNil(t, fileResp.FileInfo)
 	assert.NotEmpty(t, fileResp.Revision)
 	assert.False(t, fileResp.Complete)
 	
 	// Test file same revision
-	sameFileResp, err := analyzer.AnalyzeFile("", "main.go", fileResp.Revision)
+	sameFileResp, err := analyzer.AnalyzeFile(context.Background(), "", "main.go", fileResp.Revision)
 	require.NoError(t, err)
 	assert.True(t, sameFileResp.NoChange)
 	
@@ -395,14 +397,10 @@ func testQualityAssessmentCoverage(t *testing.T) {
 	assert.Equal(t, AnalysisModeFailed, failedQuality.AnalysisMode)
 	assert.False(t, failedQuality.EnhancementAvailable)
 	
-	// Test import error extraction
-	errorMsg := "could not import github.com/test/package (invalid package name: \"\")"
-	importPath := extractImportPathFromError(errorMsg)
-	assert.Equal(t, "github.com/test/package", importPath)
-	
-	// Test empty error
-	emptyImportPath := extractImportPathFromError("some other error")
-	assert.Equal(t, "", emptyImportPath)
+	// Test error kinds
+	assert.Equal(t, DiagnosticList, errorKind(packages.ListError))
+	assert.Equal(t, DiagnosticType, errorKind(packages.TypeError))
+	assert.Equal(t, DiagnosticUnknown, errorKind(packages.UnknownError))
 }
 
 func testErrorHandlingCoverage(t *testing.T) {
@@ -410,13 +408,13 @@ func testErrorHandlingCoverage(t *testing.T) {
 	
 	// Test dependency checker errors
 	checker := &SimpleDependencyChecker{}
-	available, err := checker.AreDependenciesAvailable("/nonexistent/path", []string{"dep1"})
+	available, err := checker.AreDependenciesAvailable(context.Background(), "/nonexistent/path", []string{"dep1"})
 	assert.NoError(t, err) // Should not error, but return empty
 	assert.Len(t, available, 0)
 	
 	// Test cache with invalid paths
 	cache := NewAnalysisCache(checker)
-	shouldRecalc, deps, err := cache.ShouldRecalculate(
+	shouldRecalc, deps, err := cache.ShouldRecalculate(context.Background(),
 		CacheKey{Type: CacheKeyTypePackage, PackagePath: "nonexistent"},
 		"/nonexistent/path",
 	)
@@ -2,12 +2,18 @@ package analyzer
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os/exec"
+	"sort"
 	"sync"
 	"time"
 )
 
+// ErrJobNotFound is returned when cancelling a dependency loading job that does not exist
+var ErrJobNotFound = errors.New("no loading job found")
+
 // DependencyLoader handles asynchronous loading of missing dependencies
 type DependencyLoader struct {
 	// activeJobs tracks currently running dependency loading jobs
@@ -19,6 +25,8 @@ type DependencyLoader struct {
 	
 	// environment variables for go commands
 	env []string
+
+	logger *slog.Logger
 }
 
 // LoadingJob represents a background dependency loading operation
@@ -37,6 +45,7 @@ type LoadingJob struct {
 	ctx        context.Context
 	cancelFunc context.CancelFunc
 	updates    chan DependencyProgress
+	done       chan struct{} // Closed when the job finishes
 }
 
 // NewDependencyLoader creates a new dependency loader
@@ -45,16 +54,22 @@ func NewDependencyLoader(workDir string, env []string) *DependencyLoader {
 		activeJobs: make(map[string]*LoadingJob),
 		workDir:    workDir,
 		env:        env,
+		logger:     slog.Default(),
 	}
 }
 
+// SetLogger sets the logger receiving the loader's log records
+func (dl *DependencyLoader) SetLogger(logger *slog.Logger) {
+	dl.logger = logger
+}
+
 // StartDependencyLoading initiates background loading of missing dependencies
 func (dl *DependencyLoader) StartDependencyLoading(enhancementToken string, missingDeps []string) (*LoadingJob, error) {
 	dl.jobsMutex.Lock()
 	defer dl.jobsMutex.Unlock()
 	
-	// Check if already loading this token
-	if existingJob, exists := dl.activeJobs[enhancementToken]; exists {
+	// Check if already loading this token; finished jobs can be started again
+	if existingJob, exists := dl.activeJobs[enhancementToken]; exists && existingJob.CompletedTime == nil {
 		return existingJob, nil
 	}
 	
@@ -76,6 +91,7 @@ func (dl *DependencyLoader) StartDependencyLoading(enhancementToken string, miss
 		ctx:        ctx,
 		cancelFunc: cancel,
 		updates:    make(chan DependencyProgress, len(missingDeps)),
+		done:       make(chan struct{}),
 	}
 	
 	dl.activeJobs[enhancementToken] = job
@@ -86,6 +102,12 @@ func (dl *DependencyLoader) StartDependencyLoading(enhancementToken string, miss
 	return job, nil
 }
 
+// Done returns a channel closed when the job finishes, whether it succeeded, failed
+// or was canceled
+func (job *LoadingJob) Done() <-chan struct{} {
+	return job.done
+}
+
 // GetLoadingStatus returns the current status of a dependency loading job
 func (dl *DependencyLoader) GetLoadingStatus(enhancementToken string) (*DependencyLoadingStatus, error) {
 	dl.jobsMutex.RLock()
@@ -123,7 +145,7 @@ func (dl *DependencyLoader) CancelLoading(enhancementToken string) error {
 	
 	job, exists := dl.activeJobs[enhancementToken]
 	if !exists {
-		return fmt.Errorf("no loading job found for token: %s", enhancementToken)
+		return fmt.Errorf("%w: %s", ErrJobNotFound, enhancementToken)
 	}
 	
 	job.cancelFunc()
@@ -133,50 +155,59 @@ func (dl *DependencyLoader) CancelLoading(enhancementToken string) error {
 	return nil
 }
 
-// runDependencyLoading executes the actual dependency loading in background
+// runDependencyLoading executes the actual dependency loading in background. The job
+// is updated with the mutex held, as it is read by status and listing calls.
 func (dl *DependencyLoader) runDependencyLoading(job *LoadingJob) {
-	defer func() {
-		close(job.updates)
-		dl.jobsMutex.Lock()
-		delete(dl.activeJobs, job.ID)
-		dl.jobsMutex.Unlock()
-	}()
+	// Finished jobs are kept so their outcome can be queried until they are evicted
+	defer close(job.done)
+	defer close(job.updates)
 	
-	fmt.Printf("Starting dependency loading for job %s: %v\n", job.ID, job.Dependencies)
+	dl.logger.Info("Starting dependency loading", "job", job.ID, "dependencies", job.Dependencies)
 	
 	for _, dep := range job.Dependencies {
 		select {
 		case <-job.ctx.Done():
 			// Job was cancelled
+			dl.jobsMutex.Lock()
 			job.Status = LoadingStatusFailed
 			now := time.Now()
 			job.CompletedTime = &now
+			dl.jobsMutex.Unlock()
 			return
 		default:
 			// Load this dependency
-			err := dl.loadSingleDependency(dep)
+			err := dl.loadSingleDependency(job.ctx, dep)
+			
+			dl.jobsMutex.Lock()
 			if err != nil {
 				job.Failed = append(job.Failed, dep)
 				job.Errors = append(job.Errors, fmt.Sprintf("%s: %v", dep, err))
 				job.Progress.Failed++
-				fmt.Printf("Failed to load dependency %s: %v\n", dep, err)
+				dl.logger.Warn("Failed to load dependency", "job", job.ID, "dependency", dep, "error", err)
 			} else {
 				job.Loaded = append(job.Loaded, dep)
 				job.Progress.Completed++
-				fmt.Printf("Successfully loaded dependency: %s\n", dep)
+				dl.logger.Debug("Loaded dependency", "job", job.ID, "dependency", dep)
 			}
+			progress := job.Progress
+			dl.jobsMutex.Unlock()
 			
 			// Send progress update
 			select {
-			case job.updates <- job.Progress:
+			case job.updates <- progress:
 			default:
 				// Channel full, skip update
 			}
 		}
 	}
 	
+	dl.jobsMutex.Lock()
+	defer dl.jobsMutex.Unlock()
+	
 	// Determine final status
-	if len(job.Failed) == 0 {
+	if job.ctx.Err() == context.Canceled {
+		job.Status = LoadingStatusFailed
+	} else if len(job.Failed) == 0 {
 		job.Status = LoadingStatusComplete
 	} else if len(job.Loaded) == 0 {
 		job.Status = LoadingStatusFailed
@@ -187,26 +218,16 @@ func (dl *DependencyLoader) runDependencyLoading(job *LoadingJob) {
 	now := time.Now()
 	job.CompletedTime = &now
 	
-	fmt.Printf("Dependency loading completed for job %s: loaded=%d, failed=%d\n", 
-		job.ID, len(job.Loaded), len(job.Failed))
+	dl.logger.Info("Dependency loading completed", "job", job.ID, "loaded", len(job.Loaded), "failed", len(job.Failed))
 }
 
-// loadSingleDependency downloads a single dependency using go mod download
-func (dl *DependencyLoader) loadSingleDependency(dependency string) error {
-	// Execute go mod download for this specific dependency
-	cmd := exec.Command("go", "mod", "download", dependency)
-	cmd.Dir = dl.workDir
-	
-	// Set environment
-	if dl.env != nil {
-		cmd.Env = dl.env
-	}
-	
-	// Run the command with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+// loadSingleDependency downloads a single dependency using go mod download, stopping
+// when ctx is done
+func (dl *DependencyLoader) loadSingleDependency(ctx context.Context, dependency string) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
 	defer cancel()
 	
-	cmd = exec.CommandContext(ctx, "go", "mod", "download", dependency)
+	cmd := exec.CommandContext(ctx, "go", "mod", "download", dependency)
 	cmd.Dir = dl.workDir
 	if dl.env != nil {
 		cmd.Env = dl.env
@@ -246,6 +267,45 @@ func (dl *DependencyLoader) CleanupCompletedJobs(maxAge time.Duration) {
 	}
 }
 
+// EvictJobs removes finished jobs completed more than ttl ago, then the oldest finished
+// jobs until at most maxJobs finished jobs remain. Running jobs are never evicted.
+// A zero ttl or maxJobs disables that limit. It returns the number of jobs removed.
+func (dl *DependencyLoader) EvictJobs(ttl time.Duration, maxJobs int) int {
+	dl.jobsMutex.Lock()
+	defer dl.jobsMutex.Unlock()
+	
+	removed := 0
+	now := time.Now()
+	finished := make([]*LoadingJob, 0)
+	
+	for token, job := range dl.activeJobs {
+		if job.CompletedTime == nil {
+			continue
+		}
+		if ttl > 0 && now.Sub(*job.CompletedTime) > ttl {
+			delete(dl.activeJobs, token)
+			removed++
+			continue
+		}
+		finished = append(finished, job)
+	}
+	
+	if maxJobs <= 0 || len(finished) <= maxJobs {
+		return removed
+	}
+	
+	// Oldest completions first
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].CompletedTime.Before(*finished[j].CompletedTime)
+	})
+	for _, job := range finished[:len(finished)-maxJobs] {
+		delete(dl.activeJobs, job.ID)
+		removed++
+	}
+	
+	return removed
+}
+
 // ListActiveJobs returns information about all active loading jobs
 func (dl *DependencyLoader) ListActiveJobs() []*LoadingJob {
 	dl.jobsMutex.RLock()
@@ -277,9 +337,9 @@ func (pa *PackagesAnalyzer) SetDependencyLoader(loader *DependencyLoader) {
 }
 
 // TriggerEnhancedAnalysis starts dependency loading and returns an enhanced analysis response
-func (pa *PackagesAnalyzer) TriggerEnhancedAnalysis(packagePath string) (*EnhancedAnalysisResponse, error) {
+func (pa *PackagesAnalyzer) TriggerEnhancedAnalysis(ctx context.Context, packagePath string) (*EnhancedAnalysisResponse, error) {
 	// First, do initial analysis
-	response, err := pa.AnalyzePackageWithQuality(packagePath)
+	response, err := pa.AnalyzePackageWithQuality(ctx, packagePath)
 	if err != nil {
 		return response, err
 	}
@@ -292,15 +352,26 @@ func (pa *PackagesAnalyzer) TriggerEnhancedAnalysis(packagePath string) (*Enhanc
 		)
 		if err != nil {
 			// Log error but don't fail the response
-			fmt.Printf("Failed to start dependency loading: %v\n", err)
+			pa.logger.WarnContext(ctx, "Failed to start dependency loading", "package", packagePath, "error", err)
 		} else {
 			// Add dependency status to response
 			response.DependencyStatus = &DependencyLoadingStatus{
 				Status:   job.Status,
 				Progress: job.Progress,
 			}
+			go pa.invalidateWhenLoaded(job)
 		}
 	}
 	
 	return response, nil
-}
\ No newline at end of file
+}
+// invalidateWhenLoaded drops the module load once job finishes, if it downloaded any
+// dependency, so the next request loads the module with them
+func (pa *PackagesAnalyzer) invalidateWhenLoaded(job *LoadingJob) {
+	<-job.Done()
+	status, err := pa.dependencyLoader.GetLoadingStatus(job.ID)
+	if err == nil && len(status.LoadedDependencies) > 0 {
+		pa.logger.Info("Reloading module with downloaded dependencies", "dir", pa.config.Dir, "job", job.ID)
+		pa.InvalidateModuleLoad()
+	}
+}
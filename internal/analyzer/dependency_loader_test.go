@@ -1,13 +1,17 @@
 package analyzer
 
 import (
+	"context"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"gonav/internal/testutil"
 )
 
 func TestDependencyLoader_StartLoading(t *testing.T) {
@@ -146,7 +150,7 @@ func main() {
 	packagesAnalyzer.SetDependencyLoader(dependencyLoader)
 
 	// Trigger enhanced analysis
-	response, err := packagesAnalyzer.TriggerEnhancedAnalysis("")
+	response, err := packagesAnalyzer.TriggerEnhancedAnalysis(context.Background(), "")
 	require.NoError(t, err)
 	require.NotNil(t, response)
 
@@ -241,4 +245,69 @@ func TestDependencyLoader_JobCleanup(t *testing.T) {
 	// Should have no active jobs after cleanup
 	activeJobs = loader.ListActiveJobs()
 	assert.Len(t, activeJobs, 0)
-}
\ No newline at end of file
+}
+func TestDependencyLoader_EvictJobs(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "evict-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	loader := NewDependencyLoader(tempDir, nil)
+
+	// Jobs fail quickly without a go.mod
+	for _, token := range []string{"job-1", "job-2", "job-3"} {
+		_, err := loader.StartDependencyLoading(token, []string{"github.com/nonexistent/package"})
+		require.NoError(t, err)
+	}
+	require.Eventually(t, func() bool {
+		for _, job := range loader.ListActiveJobs() {
+			if job.CompletedTime == nil {
+				return false
+			}
+		}
+		return true
+	}, 10*time.Second, 10*time.Millisecond)
+
+	// Finished jobs stay queryable until evicted
+	status, err := loader.GetLoadingStatus("job-1")
+	require.NoError(t, err)
+	assert.NotEqual(t, LoadingStatusIdle, status.Status)
+
+	assert.Equal(t, 1, loader.EvictJobs(0, 2))
+	assert.Len(t, loader.ListActiveJobs(), 2)
+
+	assert.Equal(t, 2, loader.EvictJobs(time.Nanosecond, 0))
+	assert.Len(t, loader.ListActiveJobs(), 0)
+}
+
+func TestPackagesAnalyzer_ReloadsWithLoadedDependencies(t *testing.T) {
+	proxyDir := t.TempDir()
+	testutil.WriteProxyModule(t, proxyDir, "example.com/lib", "v1.0.0")
+	repoDir := testutil.TempFiles(t, map[string]string{
+		"go.mod":  "module example.com/app\n\ngo 1.21\n\nrequire example.com/lib v1.0.0\n",
+		"main.go": "package main\n\nimport \"example.com/lib\"\n\nfunc main() { lib.Hello() }\n",
+	})
+
+	// Packages are loaded offline, the dependency is downloaded from the proxy
+	goEnv := append(os.Environ(), "GOMODCACHE="+t.TempDir(), "GOSUMDB=off", "GOFLAGS=-mod=mod")
+	packagesAnalyzer := NewPackagesAnalyzer(repoDir, slices.Concat(goEnv, []string{"GOPROXY=off"}))
+	dependencyLoader := NewDependencyLoader(repoDir, slices.Concat(goEnv, []string{"GOPROXY=file://" + filepath.ToSlash(proxyDir)}))
+	packagesAnalyzer.SetDependencyLoader(dependencyLoader)
+
+	response, err := packagesAnalyzer.TriggerEnhancedAnalysis(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example.com/lib"}, response.Quality.MissingDependencies)
+	incomplete, err := packagesAnalyzer.LoadModule(context.Background())
+	require.NoError(t, err)
+
+	// The module load missing the dependency is dropped once the job downloaded it
+	jobs := dependencyLoader.ListActiveJobs()
+	require.Len(t, jobs, 1)
+	require.Eventually(t, func() bool {
+		reloaded, err := packagesAnalyzer.LoadModule(context.Background())
+		return err == nil && reloaded != incomplete
+	}, 30*time.Second, 10*time.Millisecond)
+
+	response, err = packagesAnalyzer.AnalyzePackageWithQuality(context.Background(), "")
+	require.NoError(t, err)
+	assert.True(t, response.Quality.IsComplete)
+}
@@ -3,9 +3,12 @@ package analyzer
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"os/exec"
 	"sync"
 	"time"
+
+	"gonav/internal/logging"
 )
 
 // DependencyQueueConfig configures the dependency download queue
@@ -21,6 +24,14 @@ type DependencyQueueConfig struct {
 	
 	// RetryAttempts is the number of times to retry failed downloads
 	RetryAttempts int
+
+	// Logger receives the log records of the queue and of the analyzers using it,
+	// slog.Default() if nil
+	Logger *slog.Logger
+
+	// Env is the environment of the go commands downloading dependencies, the
+	// environment of the current process if nil
+	Env []string
 }
 
 // DefaultDependencyQueueConfig returns sensible default configuration
@@ -57,6 +68,7 @@ type DependencyDownloadResult struct {
 // DependencyQueue manages concurrent downloading of missing dependencies
 type DependencyQueue struct {
 	config    DependencyQueueConfig
+	logger    *slog.Logger
 	requests  chan DependencyDownloadRequest
 	workers   []chan struct{} // Stop channels for workers
 	active    map[string]bool // Track active downloads to prevent duplicates
@@ -88,6 +100,7 @@ func NewDependencyQueue(config DependencyQueueConfig) *DependencyQueue {
 	
 	dq := &DependencyQueue{
 		config:     config,
+		logger:     logging.OrDefault(config.Logger),
 		requests:   make(chan DependencyDownloadRequest, config.QueueSize),
 		workers:    make([]chan struct{}, config.MaxConcurrentDownloads),
 		active:     make(map[string]bool),
@@ -170,21 +183,28 @@ func (dq *DependencyQueue) processDownloadRequest(workerID int, req DependencyDo
 	dq.stats.ActiveDownloads++
 	dq.statsMux.Unlock()
 	
+	failed := false
 	defer func() {
 		// Clean up active tracking
 		dq.activeMux.Lock()
 		delete(dq.active, cacheKey)
 		dq.activeMux.Unlock()
 		
-		// Update stats
+		// Update stats, with the running average of the processing time
 		dq.statsMux.Lock()
 		dq.stats.ActiveDownloads--
 		dq.stats.CompletedRequests++
+		if failed {
+			dq.stats.FailedRequests++
+		}
+		elapsed := time.Since(startTime)
+		dq.stats.AverageTime += (elapsed - dq.stats.AverageTime) / time.Duration(dq.stats.CompletedRequests)
 		dq.statsMux.Unlock()
 	}()
 	
-	fmt.Printf("Worker %d: Starting download for %s (%d dependencies)\n", 
-		workerID, cacheKey, len(req.Dependencies))
+	// Records carry the ID of the request that triggered the download
+	logger := dq.logger.With("worker", workerID, logging.RequestIDKey, req.RequestID)
+	logger.Info("Starting dependency download", "key", cacheKey, "dependencies", len(req.Dependencies))
 	
 	result := DependencyDownloadResult{
 		RequestID:   req.RequestID,
@@ -200,14 +220,15 @@ func (dq *DependencyQueue) processDownloadRequest(workerID int, req DependencyDo
 		if err != nil {
 			result.Failed = append(result.Failed, dep)
 			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", dep, err))
-			fmt.Printf("Worker %d: Failed to download %s: %v\n", workerID, dep, err)
+			logger.Warn("Failed to download dependency", "dependency", dep, "error", err)
 		} else {
 			result.Successful = append(result.Successful, dep)
-			fmt.Printf("Worker %d: Successfully downloaded %s\n", workerID, dep)
+			logger.Debug("Downloaded dependency", "dependency", dep)
 		}
 	}
 	
 	result.TotalDownloadTime = time.Since(startTime)
+	failed = len(result.Failed) > 0
 	
 	// Send result if channel is provided
 	if req.ResultChan != nil {
@@ -218,8 +239,8 @@ func (dq *DependencyQueue) processDownloadRequest(workerID int, req DependencyDo
 		}
 	}
 	
-	fmt.Printf("Worker %d: Completed download for %s (success: %d, failed: %d, time: %v)\n",
-		workerID, cacheKey, len(result.Successful), len(result.Failed), result.TotalDownloadTime)
+	logger.Info("Completed dependency download", "key", cacheKey, "successful", len(result.Successful),
+		"failed", len(result.Failed), "duration", result.TotalDownloadTime)
 }
 
 // downloadSingleDependency downloads a single dependency using go mod download
@@ -229,6 +250,7 @@ func (dq *DependencyQueue) downloadSingleDependency(workDir, dependency string)
 	
 	cmd := exec.CommandContext(ctx, "go", "mod", "download", dependency)
 	cmd.Dir = workDir
+	cmd.Env = dq.config.Env
 	
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -250,7 +272,7 @@ func (dq *DependencyQueue) GetStats() DependencyQueueStats {
 
 // Shutdown gracefully shuts down the dependency queue
 func (dq *DependencyQueue) Shutdown(timeout time.Duration) error {
-	fmt.Println("Shutting down dependency queue...")
+	dq.logger.Info("Shutting down dependency queue")
 	
 	// Stop accepting new requests
 	dq.cancelFunc()
@@ -269,10 +291,10 @@ func (dq *DependencyQueue) Shutdown(timeout time.Duration) error {
 	
 	select {
 	case <-done:
-		fmt.Println("Dependency queue shutdown completed")
+		dq.logger.Info("Dependency queue shutdown completed")
 		return nil
 	case <-time.After(timeout):
-		fmt.Println("Dependency queue shutdown timed out")
+		dq.logger.Warn("Dependency queue shutdown timed out", "timeout", timeout)
 		return fmt.Errorf("shutdown timed out after %v", timeout)
 	}
 }
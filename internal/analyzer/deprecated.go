@@ -0,0 +1,380 @@
+package analyzer
+
+import (
+	"context"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// DeprecatedUse is a use of a deprecated declaration, as listed by DeprecatedUses
+type DeprecatedUse struct {
+	File   string  `json:"file"` // Relative to the repository root
+	Line   int     `json:"line"`
+	Column int     `json:"column"`
+	Name   string  `json:"name"`
+	Target *Symbol `json:"target"` // The deprecated declaration, with its notice
+}
+
+// deprecationNotice returns the text of the "Deprecated:" paragraph of a doc comment,
+// or "" if the comment has none. Following the Go convention, the paragraph must start
+// with "Deprecated: ".
+func deprecationNotice(doc *ast.CommentGroup) string {
+	if doc == nil {
+		return ""
+	}
+	for _, paragraph := range strings.Split(doc.Text(), "\n\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		if notice, ok := strings.CutPrefix(paragraph, "Deprecated: "); ok {
+			return strings.Join(strings.Fields(notice), " ")
+		}
+	}
+	return ""
+}
+
+// fileDeprecations calls mark with the key and notice of every deprecated declaration
+// of file. Keys are the names of package-level declarations, "T.Method" for methods
+// and interface methods, and "T.Field" for fields, "T.Inner.Field" in nested structs.
+// Specs of a deprecated declaration group are deprecated too.
+func fileDeprecations(file *ast.File, mark func(key, notice string)) {
+	var markFields func(prefix string, fields *ast.FieldList)
+	markFields = func(prefix string, fields *ast.FieldList) {
+		if fields == nil {
+			return
+		}
+		for _, field := range fields.List {
+			notice := deprecationNotice(field.Doc)
+			for _, name := range field.Names {
+				if notice != "" {
+					mark(prefix+name.Name, notice)
+				}
+				if st, ok := field.Type.(*ast.StructType); ok {
+					markFields(prefix+name.Name+".", st.Fields)
+				}
+			}
+		}
+	}
+
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			key := d.Name.Name
+			if recv := receiverTypeName(d); recv != "" {
+				key = recv + "." + key
+			}
+			if notice := deprecationNotice(d.Doc); notice != "" {
+				mark(key, notice)
+			}
+		case *ast.GenDecl:
+			groupNotice := deprecationNotice(d.Doc)
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					notice := deprecationNotice(s.Doc)
+					if notice == "" {
+						notice = groupNotice
+					}
+					if notice != "" {
+						mark(s.Name.Name, notice)
+					}
+					switch t := s.Type.(type) {
+					case *ast.StructType:
+						markFields(s.Name.Name+".", t.Fields)
+					case *ast.InterfaceType:
+						markFields(s.Name.Name+".", t.Methods)
+					}
+				case *ast.ValueSpec:
+					notice := deprecationNotice(s.Doc)
+					if notice == "" {
+						notice = groupNotice
+					}
+					for _, name := range s.Names {
+						if notice != "" {
+							mark(name.Name, notice)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// deprecationKey returns the key of the declaration of obj in fileDeprecations, or ""
+// for local declarations. Fields are keyed after their parent in fields.
+func deprecationKey(obj types.Object, fields *fieldParentIndex) string {
+	obj = genericOrigin(obj)
+	switch o := obj.(type) {
+	case *types.Func:
+		sig, ok := o.Type().(*types.Signature)
+		if !ok || sig.Recv() == nil {
+			return o.Name()
+		}
+		recv := sig.Recv().Type()
+		if ptr, ok := recv.(*types.Pointer); ok {
+			recv = ptr.Elem()
+		}
+		if named, ok := recv.(*types.Named); ok {
+			return named.Origin().Obj().Name() + "." + o.Name()
+		}
+		return ""
+	case *types.Var:
+		if o.IsField() {
+			if parent := fields.Parent(o); parent != "" {
+				return stripTypeParams(parent) + "." + o.Name()
+			}
+			return ""
+		}
+	case *types.PkgName, *types.Label:
+		return ""
+	}
+	if !isPackageLevel(obj) {
+		return ""
+	}
+	return obj.Name()
+}
+
+// stripTypeParams removes the type parameters of the type name of a field parent, as
+// in "List[T].Inner"
+func stripTypeParams(name string) string {
+	if i := strings.Index(name, "["); i != -1 {
+		if j := strings.Index(name[i:], "]"); j != -1 {
+			return name[:i] + name[i+j+1:]
+		}
+	}
+	return name
+}
+
+// deprecationIndex caches the deprecation notices of package directories, parsed
+// from source on first use. Declarations of dependencies and of the standard library
+// are found in their module cache or GOROOT directory.
+type deprecationIndex struct {
+	mutex  sync.Mutex
+	dirs   map[string]*deprecatedDir // Package directory -> its notices
+	goroot map[string]string         // Import path -> GOROOT directory, "" if not in GOROOT
+}
+
+// deprecatedDir holds the notices of a package directory, parsed once
+type deprecatedDir struct {
+	once    sync.Once
+	notices map[string]string // Declaration key -> notice
+}
+
+func newDeprecationIndex() *deprecationIndex {
+	return &deprecationIndex{
+		dirs:   make(map[string]*deprecatedDir),
+		goroot: make(map[string]string),
+	}
+}
+
+// Notice returns the deprecation notice of obj, or "" if it is not deprecated.
+// Standard library packages are found in GOROOT, other packages in the directory of
+// filename, the file declaring obj. fields, which may be nil, locates struct fields.
+func (d *deprecationIndex) Notice(obj types.Object, filename string, fields *fieldParentIndex) string {
+	if d == nil || obj == nil || obj.Pkg() == nil {
+		return ""
+	}
+	key := deprecationKey(obj, fields)
+	if key == "" {
+		return ""
+	}
+
+	dir := d.gorootDir(obj.Pkg().Path())
+	if dir == "" && filename != "" && filepath.IsAbs(filename) {
+		dir = filepath.Dir(filename)
+	}
+	if dir == "" {
+		return ""
+	}
+	return d.dir(dir)[key]
+}
+
+// Release drops the notices of the package directories within the repository at
+// repoPath, for example when it is evicted from the cache
+func (d *deprecationIndex) Release(repoPath string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for dir := range d.dirs {
+		if isWithinRepository(dir, repoPath) {
+			delete(d.dirs, dir)
+		}
+	}
+}
+
+// gorootDir returns the directory of the standard library package at importPath, or "".
+// GOROOT is looked up directly: go/build would run the go command for other paths.
+func (d *deprecationIndex) gorootDir(importPath string) string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	dir, ok := d.goroot[importPath]
+	if !ok {
+		// The first element of standard library import paths has no dot
+		first, _, _ := strings.Cut(importPath, "/")
+		if !strings.Contains(first, ".") {
+			candidate := filepath.Join(build.Default.GOROOT, "src", filepath.FromSlash(importPath))
+			if info, err := os.Stat(candidate); err == nil && info.IsDir() {
+				dir = candidate
+			}
+		}
+		d.goroot[importPath] = dir
+	}
+	return dir
+}
+
+// dir returns the notices of the package in dir, parsing it on first use. Parsing
+// happens outside of the index lock, once per directory.
+func (d *deprecationIndex) dir(dir string) map[string]string {
+	d.mutex.Lock()
+	entry, ok := d.dirs[dir]
+	if !ok {
+		entry = &deprecatedDir{}
+		d.dirs[dir] = entry
+	}
+	d.mutex.Unlock()
+
+	entry.once.Do(func() {
+		entry.notices = parseDeprecations(dir)
+	})
+	return entry.notices
+}
+
+// parseDeprecations returns the notices of the declarations of the package in dir,
+// test files excluded
+func parseDeprecations(dir string) map[string]string {
+	notices := make(map[string]string)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return notices
+	}
+	fset := token.NewFileSet()
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		// Declarations parsed before a syntax error are kept
+		file, _ := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments|parser.SkipObjectResolution)
+		if file == nil {
+			continue
+		}
+		fileDeprecations(file, func(key, notice string) {
+			notices[key] = notice
+		})
+	}
+	return notices
+}
+
+// deprecationNoticeOf returns the deprecation notice of obj. Objects of imported
+// packages other than the standard library cannot be located, as the type checker
+// imports them from export data.
+func (a *PackageAnalyzer) deprecationNoticeOf(obj types.Object) string {
+	if obj == nil || obj.Pkg() == nil {
+		return ""
+	}
+	return a.deprecations.Notice(obj, a.fset.Position(obj.Pos()).Filename, nil)
+}
+
+// deprecationNoticeOf returns the deprecation notice of obj, declared in a package
+// loaded along with pkg
+func (pa *PackagesAnalyzer) deprecationNoticeOf(obj types.Object, pkg *packages.Package) string {
+	if obj == nil || obj.Pkg() == nil {
+		return ""
+	}
+	return pa.deprecations.Notice(obj, pkg.Fset.Position(genericOrigin(obj).Pos()).Filename, pa.fieldParents)
+}
+
+// DeprecatedUses lists the uses of deprecated declarations in the module, only of the
+// ones declared in module modulePath or its packages if it is not empty. The module is
+// loaded if needed.
+func (pa *PackagesAnalyzer) DeprecatedUses(ctx context.Context, modulePath string) ([]*DeprecatedUse, error) {
+	moduleLoad, err := pa.LoadModule(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	uses := make([]*DeprecatedUse, 0)
+	for _, pkg := range moduleLoad.Packages {
+		if pkg.TypesInfo == nil {
+			continue
+		}
+		for i, file := range pkg.Syntax {
+			if i >= len(pkg.CompiledGoFiles) || !pa.inRepository(pkg.CompiledGoFiles[i]) {
+				continue
+			}
+			relPath, err := filepath.Rel(pa.rootDir, pkg.CompiledGoFiles[i])
+			if err != nil {
+				continue
+			}
+
+			ast.Inspect(file, func(n ast.Node) bool {
+				ident, ok := n.(*ast.Ident)
+				if !ok {
+					return true
+				}
+				obj := pkg.TypesInfo.Uses[ident]
+				if obj == nil || obj.Pkg() == nil || !inModule(obj.Pkg().Path(), modulePath) {
+					return true
+				}
+				if pa.deprecationNoticeOf(obj, pkg) == "" {
+					return true
+				}
+				pos := pkg.Fset.Position(ident.Pos())
+				uses = append(uses, &DeprecatedUse{
+					File:   filepath.ToSlash(relPath),
+					Line:   pos.Line,
+					Column: pos.Column,
+					Name:   ident.Name,
+					Target: pa.convertObjectToSymbol(obj, pkg),
+				})
+				return true
+			})
+		}
+	}
+	return uses, nil
+}
+
+// inModule reports whether the package at importPath belongs to module modulePath,
+// always if modulePath is empty
+func inModule(importPath, modulePath string) bool {
+	return modulePath == "" || importPath == modulePath || strings.HasPrefix(importPath, modulePath+"/")
+}
+
+// DeprecatedUses lists the uses of deprecated declarations in every module of the
+// repository at repoPath, only of the ones declared in module modulePath if it is not
+// empty, sorted by file and position
+func (a *PackageAnalyzer) DeprecatedUses(ctx context.Context, repoPath, modulePath string) ([]*DeprecatedUse, error) {
+	analyzers, err := a.repositoryAnalyzers(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	uses := make([]*DeprecatedUse, 0)
+	for _, packagesAnalyzer := range analyzers {
+		moduleUses, err := packagesAnalyzer.DeprecatedUses(ctx, modulePath)
+		if err != nil {
+			return nil, err
+		}
+		uses = append(uses, moduleUses...)
+	}
+
+	sort.SliceStable(uses, func(i, j int) bool {
+		if uses[i].File != uses[j].File {
+			return uses[i].File < uses[j].File
+		}
+		if uses[i].Line != uses[j].Line {
+			return uses[i].Line < uses[j].Line
+		}
+		return uses[i].Column < uses[j].Column
+	})
+	return uses, nil
+}
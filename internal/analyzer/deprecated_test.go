@@ -0,0 +1,201 @@
+package analyzer
+
+import (
+	"context"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gonav/internal/testutil"
+)
+
+// deprecatedModule is a module using deprecated declarations of its own packages
+// and of the standard library
+var deprecatedModule = map[string]string{
+	"go.mod": "module example.com/app\n\ngo 1.21\n",
+	"legacy/legacy.go": `package legacy
+
+// Dial connects to addr.
+//
+// Deprecated: use Connect, which
+// supports contexts.
+func Dial(addr string) {}
+
+func Connect(addr string) { Dial(addr) }
+
+// Config configures a client.
+type Config struct {
+	// Deprecated: use Timeout.
+	Wait    int
+	Timeout int
+}
+
+// Deprecated: the configuration is reset by Connect.
+func (c *Config) Reset() {}
+
+// Deprecated: unused since v2.
+const (
+	ModeA = iota
+	ModeB
+)
+`,
+	"main.go": `package main
+
+import (
+	"strings"
+
+	"example.com/app/legacy"
+)
+
+func main() {
+	legacy.Dial(strings.Title("addr"))
+	legacy.Connect("addr")
+	c := legacy.Config{Wait: 1}
+	c.Reset()
+	_ = legacy.ModeB + c.Timeout
+}
+`,
+}
+
+func TestDeprecationNotice(t *testing.T) {
+	src := `package p
+
+// A is old.
+//
+// Deprecated: use B.
+func A() {}
+
+// Deprecated:use B.
+func B() {}
+
+type T struct {
+	// Deprecated: use Inner.New.
+	Inner struct {
+		// Deprecated: gone.
+		Old int
+	}
+}
+
+// Deprecated: use Run.
+func (t T) Start() {}
+
+type I interface {
+	// Deprecated: use Close.
+	Stop()
+}
+`
+	file, err := parser.ParseFile(token.NewFileSet(), "p.go", src, parser.ParseComments)
+	require.NoError(t, err)
+
+	notices := make(map[string]string)
+	fileDeprecations(file, func(key, notice string) {
+		notices[key] = notice
+	})
+	assert.Equal(t, map[string]string{
+		"A":           "use B.",
+		"T.Inner":     "use Inner.New.",
+		"T.Inner.Old": "gone.",
+		"T.Start":     "use Run.",
+		"I.Stop":      "use Close.",
+	}, notices, "the paragraph must start with \"Deprecated: \"")
+}
+
+func TestDeprecatedSymbols(t *testing.T) {
+	tempDir := testutil.TempFiles(t, deprecatedModule)
+
+	fileInfo, err := NewPackagesAnalyzer(tempDir, nil).AnalyzeSingleFileWithPackages(context.Background(), "main.go")
+	require.NoError(t, err)
+
+	notices := make(map[string]string)
+	for _, ref := range fileInfo.References {
+		if ref.Target != nil {
+			notices[ref.Name] = ref.Target.Deprecated
+		}
+	}
+	assert.Equal(t, "use Connect, which supports contexts.", notices["Dial"])
+	assert.Contains(t, notices["Title"], "golang.org/x/text/cases", "standard library targets")
+	assert.Equal(t, "use Timeout.", notices["Wait"])
+	assert.Equal(t, "the configuration is reset by Connect.", notices["Reset"])
+	assert.Equal(t, "unused since v2.", notices["ModeB"], "specs of a deprecated group")
+	assert.Empty(t, notices["Connect"])
+	assert.Empty(t, notices["Timeout"])
+
+	// The legacy analyzer locates declarations of the package and of the standard library
+	legacyInfo, err := New().AnalyzeSingleFile(context.Background(), tempDir, "legacy/legacy.go")
+	require.NoError(t, err)
+	legacyNotices := make(map[string]string)
+	for _, ref := range legacyInfo.References {
+		if ref.Target != nil {
+			legacyNotices[ref.Name] = ref.Target.Deprecated
+		}
+	}
+	assert.Equal(t, "use Connect, which supports contexts.", legacyNotices["Dial"])
+
+	legacyInfo, err = New().AnalyzeSingleFile(context.Background(), tempDir, "main.go")
+	require.NoError(t, err)
+	for _, ref := range legacyInfo.References {
+		if ref.Name == "Title" && ref.Target != nil {
+			assert.Contains(t, ref.Target.Deprecated, "golang.org/x/text/cases")
+		}
+	}
+}
+
+func TestDeprecatedUses(t *testing.T) {
+	tempDir := testutil.TempFiles(t, deprecatedModule)
+	a := New()
+	a.SetRepositoryContext(tempDir, nil)
+
+	uses, err := a.DeprecatedUses(context.Background(), tempDir, "")
+	require.NoError(t, err)
+
+	var found []string
+	for _, use := range uses {
+		require.NotNil(t, use.Target)
+		assert.NotEmpty(t, use.Target.Deprecated)
+		found = append(found, use.File+":"+use.Name)
+	}
+	assert.Equal(t, []string{
+		"legacy/legacy.go:Dial", "main.go:Dial", "main.go:Title", "main.go:Wait", "main.go:Reset", "main.go:ModeB",
+	}, found, "sorted by file and position")
+	assert.Equal(t, 10, uses[1].Line)
+	assert.Equal(t, 9, uses[1].Column)
+
+	// Restricted to a module
+	uses, err = a.DeprecatedUses(context.Background(), tempDir, "strings")
+	require.NoError(t, err)
+	require.Len(t, uses, 1)
+	assert.Equal(t, "Title", uses[0].Name)
+
+	uses, err = a.DeprecatedUses(context.Background(), tempDir, "example.com/other")
+	require.NoError(t, err)
+	assert.Empty(t, uses)
+}
+
+func TestDeprecationIndex(t *testing.T) {
+	tempDir := testutil.TempFiles(t, deprecatedModule)
+	a := New()
+	a.SetRepositoryContext(tempDir, nil)
+
+	_, err := a.DeprecatedUses(context.Background(), tempDir, "")
+	require.NoError(t, err)
+
+	index := a.deprecations
+	legacyDir := filepath.Join(tempDir, "legacy")
+	assert.Contains(t, index.dir(legacyDir), "Dial")
+
+	// Only standard library paths are looked up in GOROOT
+	assert.NotEmpty(t, index.gorootDir("strings"))
+	assert.Empty(t, index.gorootDir("example.com/app/legacy"))
+
+	// Releasing the repository drops the notices of its directories
+	a.ReleaseRepository(tempDir)
+	index.mutex.Lock()
+	_, cached := index.dirs[legacyDir]
+	index.mutex.Unlock()
+	assert.False(t, cached)
+	assert.Contains(t, index.dir(legacyDir), "Dial", "parsed again on next use")
+}
@@ -0,0 +1,179 @@
+package analyzer
+
+import (
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Kinds of diagnostics, after the kinds of packages errors
+const (
+	DiagnosticList    = "list"  // Reported by the go command, as for imports it cannot resolve
+	DiagnosticParse   = "parse" // Syntax errors
+	DiagnosticType    = "type"  // Type checking errors
+	DiagnosticUnknown = "unknown"
+)
+
+// Diagnostic is an error reported for a file while loading or type checking its package.
+// Symbols and references are missing or incomplete around it.
+type Diagnostic struct {
+	Line       int    `json:"line"`
+	Column     int    `json:"column"`
+	Message    string `json:"message"`
+	Kind       string `json:"kind"`                 // One of the Diagnostic constants
+	ImportPath string `json:"importPath,omitempty"` // For imports that could not be resolved
+}
+
+// ParseDiagnostics parses the Go source src and returns its syntax errors, for files
+// that cannot be analyzed otherwise
+func ParseDiagnostics(filename string, src []byte) []*Diagnostic {
+	_, err := parser.ParseFile(token.NewFileSet(), filename, src, parser.SkipObjectResolution)
+
+	var list scanner.ErrorList
+	if !errors.As(err, &list) {
+		return nil
+	}
+	diagnostics := make([]*Diagnostic, 0, len(list))
+	for _, e := range list {
+		diagnostics = append(diagnostics, &Diagnostic{
+			Line:    e.Pos.Line,
+			Column:  e.Pos.Column,
+			Message: e.Msg,
+			Kind:    DiagnosticParse,
+		})
+	}
+	return diagnostics
+}
+
+// errorKind returns the diagnostic kind of a packages error kind
+func errorKind(kind packages.ErrorKind) string {
+	switch kind {
+	case packages.ListError:
+		return DiagnosticList
+	case packages.ParseError:
+		return DiagnosticParse
+	case packages.TypeError:
+		return DiagnosticType
+	}
+	return DiagnosticUnknown
+}
+
+// importSpecPaths returns the import paths of the import specs of files, by the
+// position of their path literal, where the type checker reports failed imports
+func importSpecPaths(files []*ast.File) map[token.Pos]string {
+	paths := make(map[token.Pos]string)
+	for _, file := range files {
+		for _, spec := range file.Imports {
+			if path, err := strconv.Unquote(spec.Path.Value); err == nil {
+				paths[spec.Path.Pos()] = path
+			}
+		}
+	}
+	return paths
+}
+
+// missingImports returns the sorted import paths of the direct imports of pkg that
+// could not be loaded, for which the go command found no package
+func missingImports(pkg *packages.Package) []string {
+	missing := make([]string, 0)
+	for path, imported := range pkg.Imports {
+		if imported == nil || imported.Name == "" {
+			missing = append(missing, path)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// typeErrorDiagnostics returns the diagnostics of the type errors reported in the file
+// filename. Failed imports are identified by the import spec they are reported at.
+func typeErrorDiagnostics(typeErrors []types.Error, filename string, imports map[token.Pos]string) []*Diagnostic {
+	diagnostics := make([]*Diagnostic, 0)
+	target := canonicalPath(filename)
+	for _, e := range typeErrors {
+		pos := e.Fset.Position(e.Pos)
+		if pos.Filename == "" || canonicalPath(pos.Filename) != target {
+			continue
+		}
+		diagnostics = append(diagnostics, &Diagnostic{
+			Line:       pos.Line,
+			Column:     pos.Column,
+			Message:    e.Msg,
+			Kind:       DiagnosticType,
+			ImportPath: imports[e.Pos],
+		})
+	}
+	return diagnostics
+}
+
+// fileDiagnostics returns the diagnostics of the errors reported for pkg in the file
+// filename, sorted by position
+func fileDiagnostics(pkg *packages.Package, filename string) []*Diagnostic {
+	diagnostics := typeErrorDiagnostics(pkg.TypeErrors, filename, importSpecPaths(pkg.Syntax))
+
+	// Other errors only record their position as text
+	target := canonicalPath(filename)
+	for _, e := range pkg.Errors {
+		if e.Kind == packages.TypeError {
+			continue
+		}
+		file, line, column := splitErrorPosition(e.Pos)
+		if file == "" || !filepath.IsAbs(file) || canonicalPath(file) != target {
+			continue
+		}
+		diagnostics = append(diagnostics, &Diagnostic{
+			Line:    line,
+			Column:  column,
+			Message: e.Msg,
+			Kind:    errorKind(e.Kind),
+		})
+	}
+
+	sortDiagnostics(diagnostics)
+	return diagnostics
+}
+
+// sortDiagnostics sorts diagnostics by position
+func sortDiagnostics(diagnostics []*Diagnostic) {
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		if diagnostics[i].Line != diagnostics[j].Line {
+			return diagnostics[i].Line < diagnostics[j].Line
+		}
+		return diagnostics[i].Column < diagnostics[j].Column
+	})
+}
+
+// splitErrorPosition splits the position of a packages error, "file:line:column" or
+// "file:line", into its parts. The file is "" for errors without a position.
+func splitErrorPosition(pos string) (file string, line, column int) {
+	file = pos
+	var numbers []int
+	for len(numbers) < 2 {
+		i := strings.LastIndex(file, ":")
+		if i == -1 {
+			break
+		}
+		n, err := strconv.Atoi(file[i+1:])
+		if err != nil {
+			break
+		}
+		numbers = append(numbers, n)
+		file = file[:i]
+	}
+	switch len(numbers) {
+	case 0:
+		return "", 0, 0
+	case 1:
+		return file, numbers[0], 0
+	}
+	return file, numbers[1], numbers[0]
+}
@@ -0,0 +1,130 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gonav/internal/testutil"
+)
+
+// diagnosticsModule is a module with a missing import, type errors and a package
+// with a syntax error
+var diagnosticsModule = map[string]string{
+	"go.mod": "module example.com/diag\n\ngo 1.21\n",
+	"main.go": `package main
+
+import (
+	"fmt"
+	gone "example.com/diag/missing"
+)
+
+func main() {
+	var count int = "many"
+	fmt.Println(count, gone.Value)
+}
+`,
+	"other.go": `package main
+
+func helper() { undefinedHelper() }
+`,
+	"broken/broken.go": `package broken
+
+func Ok() {}
+
+func Broken( {
+`,
+}
+
+func TestFileDiagnostics(t *testing.T) {
+	tempDir := testutil.TempFiles(t, diagnosticsModule)
+
+	fileInfo, err := NewPackagesAnalyzer(tempDir, nil).AnalyzeSingleFileWithPackages(context.Background(), "main.go")
+	require.NoError(t, err)
+
+	// Errors of other.go are not reported for main.go
+	require.Len(t, fileInfo.Diagnostics, 2)
+	imported := fileInfo.Diagnostics[0]
+	assert.Equal(t, 5, imported.Line)
+	assert.Equal(t, 7, imported.Column)
+	assert.Equal(t, DiagnosticType, imported.Kind)
+	assert.Equal(t, "example.com/diag/missing", imported.ImportPath, "found from the import spec, with an alias")
+
+	assigned := fileInfo.Diagnostics[1]
+	assert.Equal(t, 9, assigned.Line)
+	assert.Equal(t, 18, assigned.Column)
+	assert.Equal(t, DiagnosticType, assigned.Kind)
+	assert.Contains(t, assigned.Message, "cannot use")
+	assert.Empty(t, assigned.ImportPath)
+
+	fileInfo, err = NewPackagesAnalyzer(tempDir, nil).AnalyzeSingleFileWithPackages(context.Background(), "broken/broken.go")
+	require.NoError(t, err)
+	require.NotEmpty(t, fileInfo.Diagnostics)
+	assert.Equal(t, DiagnosticParse, fileInfo.Diagnostics[0].Kind)
+	assert.Equal(t, 5, fileInfo.Diagnostics[0].Line)
+}
+
+func TestAnalysisQualityImportErrors(t *testing.T) {
+	tempDir := testutil.TempFiles(t, diagnosticsModule)
+
+	pkg, err := NewPackagesAnalyzer(tempDir, nil).loadPackage(context.Background(), "")
+	require.NoError(t, err)
+
+	quality := AssessAnalysisQuality(pkg)
+	assert.False(t, quality.IsComplete)
+	assert.Equal(t, []string{"example.com/diag/missing"}, quality.MissingDependencies, "taken from the imports")
+	assert.Equal(t, AnalysisModePartial, quality.AnalysisMode)
+
+	importPaths := make(map[string]string)
+	for _, importErr := range quality.ImportErrors {
+		assert.Equal(t, DiagnosticType, importErr.Kind)
+		importPaths[importErr.Position] = importErr.ImportPath
+	}
+	assert.Len(t, importPaths, 3)
+	assert.Equal(t, "example.com/diag/missing", importPaths[filepath.Join(tempDir, "main.go")+":5:7"])
+	assert.Empty(t, importPaths[filepath.Join(tempDir, "other.go")+":3:17"])
+}
+
+func TestLegacyFileDiagnostics(t *testing.T) {
+	tmpDir := t.TempDir()
+	source := `package main
+
+import "nothere/pkg"
+
+func main() {
+	pkg.Run(undefinedValue)
+}
+`
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte(source), 0644))
+
+	fileInfo, err := New().AnalyzeSingleFile(context.Background(), tmpDir, "main.go")
+	require.NoError(t, err)
+	require.Len(t, fileInfo.Diagnostics, 2)
+	assert.Equal(t, 3, fileInfo.Diagnostics[0].Line)
+	assert.Equal(t, "nothere/pkg", fileInfo.Diagnostics[0].ImportPath)
+	assert.Equal(t, 6, fileInfo.Diagnostics[1].Line)
+	assert.Contains(t, fileInfo.Diagnostics[1].Message, "undefinedValue")
+}
+
+func TestParseDiagnostics(t *testing.T) {
+	diagnostics := ParseDiagnostics("broken.go", []byte("package p\n\nfunc ok() {}\n\nfunc broken( {\n"))
+	require.NotEmpty(t, diagnostics)
+	assert.Equal(t, 5, diagnostics[0].Line)
+	assert.Equal(t, DiagnosticParse, diagnostics[0].Kind)
+
+	assert.Nil(t, ParseDiagnostics("ok.go", []byte("package p\n")))
+
+	file, line, column := splitErrorPosition("/repo/main.go:12:3")
+	assert.Equal(t, "/repo/main.go", file)
+	assert.Equal(t, 12, line)
+	assert.Equal(t, 3, column)
+	file, line, _ = splitErrorPosition("C:/repo/main.go:12")
+	assert.Equal(t, "C:/repo/main.go", file)
+	assert.Equal(t, 12, line)
+	file, _, _ = splitErrorPosition("-")
+	assert.Empty(t, file)
+}
@@ -0,0 +1,31 @@
+package analyzer
+
+import (
+	"golang.org/x/tools/go/packages"
+)
+
+// AnalysisError reports a package or file that could not be analyzed, together with
+// the errors the go command and the type checker reported while loading its package
+type AnalysisError struct {
+	Err          error
+	ImportErrors []ImportError
+}
+
+func (e *AnalysisError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *AnalysisError) Unwrap() error {
+	return e.Err
+}
+
+// newAnalysisError wraps err with the errors reported for the loaded packages pkgs
+func newAnalysisError(err error, pkgs ...*packages.Package) error {
+	importErrors := make([]ImportError, 0)
+	for _, pkg := range pkgs {
+		if pkg != nil {
+			importErrors = append(importErrors, AssessAnalysisQuality(pkg).ImportErrors...)
+		}
+	}
+	return &AnalysisError{Err: err, ImportErrors: importErrors}
+}
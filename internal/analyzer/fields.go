@@ -0,0 +1,112 @@
+package analyzer
+
+import (
+	"go/types"
+	"sync"
+)
+
+// fieldParentIndex caches the field parents of the packages of an analyzer, one
+// package graph at a time: a package loaded again replaces the previous one.
+type fieldParentIndex struct {
+	mutex    sync.Mutex
+	packages map[string]*packageFieldParents // Import path -> field parents
+}
+
+// packageFieldParents holds the result of fieldParents for a package
+type packageFieldParents struct {
+	pkg     *types.Package
+	parents map[*types.Var]string
+}
+
+func newFieldParentIndex() *fieldParentIndex {
+	return &fieldParentIndex{packages: make(map[string]*packageFieldParents)}
+}
+
+// Parent returns the name of the type declaring field, as "Server", or "Config.Limits"
+// for a field of a struct nested in a field of Config. It returns "" if field belongs
+// to a struct type not reachable from a package-level type. The parents of a package
+// are computed on first use; a nil index computes them on every call.
+func (ix *fieldParentIndex) Parent(field *types.Var) string {
+	field = field.Origin()
+	pkg := field.Pkg()
+	if pkg == nil {
+		return ""
+	}
+	if ix == nil {
+		return fieldParents(pkg)[field]
+	}
+
+	ix.mutex.Lock()
+	defer ix.mutex.Unlock()
+	entry, ok := ix.packages[pkg.Path()]
+	if !ok || entry.pkg != pkg {
+		entry = &packageFieldParents{pkg: pkg, parents: fieldParents(pkg)}
+		ix.packages[pkg.Path()] = entry
+	}
+	return entry.parents[field]
+}
+
+// fieldParents maps the fields of the package-level struct types of pkg, and of the
+// struct literal types of their fields, to the name of the type declaring them
+func fieldParents(pkg *types.Package) map[*types.Var]string {
+	parents := make(map[*types.Var]string)
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		typeName, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || typeName.IsAlias() {
+			continue
+		}
+		addFieldParents(parents, typeName.Type().Underlying(), typeNameOf(typeName))
+	}
+	return parents
+}
+
+// typeNameOf returns the name of a type declaration, with its type parameters
+func typeNameOf(typeName *types.TypeName) string {
+	if named, ok := typeName.Type().(*types.Named); ok {
+		return typeNameWithParams(named)
+	}
+	return typeName.Name()
+}
+
+// addFieldParents records name as the parent of the fields of typ, if it is a struct,
+// and the fields of the struct literal types of its fields. Types sharing a struct
+// keep the parent recorded first.
+func addFieldParents(parents map[*types.Var]string, typ types.Type, name string) {
+	st, ok := typ.(*types.Struct)
+	if !ok {
+		return
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		if _, exists := parents[f]; !exists {
+			parents[f] = name
+		}
+		addFieldParents(parents, f.Type(), name+"."+f.Name())
+	}
+}
+
+// promotionPath returns the embedded fields traversed by a selection to reach a
+// promoted field or method, outermost first, or nil if the member is not promoted
+func promotionPath(sel *types.Selection) []*types.Var {
+	index := sel.Index()
+	if len(index) < 2 {
+		return nil
+	}
+
+	path := make([]*types.Var, 0, len(index)-1)
+	typ := sel.Recv()
+	for _, i := range index[:len(index)-1] {
+		if ptr, ok := typ.Underlying().(*types.Pointer); ok {
+			typ = ptr.Elem()
+		}
+		st, ok := typ.Underlying().(*types.Struct)
+		if !ok || i >= st.NumFields() {
+			return path
+		}
+		embedded := st.Field(i)
+		path = append(path, embedded)
+		typ = embedded.Type()
+	}
+	return path
+}
@@ -0,0 +1,169 @@
+package analyzer
+
+import (
+	"context"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gonav/internal/testutil"
+)
+
+// fieldsModule is a module with struct types embedding each other
+var fieldsModule = map[string]string{
+	"go.mod": "module example.com/shapes\n\ngo 1.21\n",
+	"shapes.go": `package shapes
+
+type Point struct {
+	X, Y int
+}
+
+type Base struct {
+	Point
+	ID string
+}
+
+func (b *Base) Describe() string { return b.ID }
+
+type Shape struct {
+	*Base
+	Name  string
+	Style struct {
+		Color string
+	}
+}
+`,
+	"use.go": `package shapes
+
+func use(s Shape) (int, string, string) {
+	s.Describe()
+	return s.X, s.Name, s.Style.Color
+}
+`,
+}
+
+func TestFieldSymbols(t *testing.T) {
+	tempDir := testutil.TempFiles(t, fieldsModule)
+
+	packageInfo, err := NewPackagesAnalyzer(tempDir, nil).AnalyzePackageWithPackages(context.Background(), ".")
+	require.NoError(t, err)
+
+	parents := map[string]string{
+		"Point.X":    "Point",
+		"Point.Y":    "Point",
+		"Base.Point": "Base",
+		"Base.ID":    "Base",
+		"Shape.Base": "Shape",
+		"Shape.Name": "Shape",
+	}
+	for name, parent := range parents {
+		require.Contains(t, packageInfo.Symbols, name)
+		assert.Equal(t, "field", packageInfo.Symbols[name].Type, name)
+		assert.Equal(t, parent, packageInfo.Symbols[name].Parent, name)
+	}
+
+	fileInfo, err := NewPackagesAnalyzer(tempDir, nil).AnalyzeSingleFileWithPackages(context.Background(), "shapes.go")
+	require.NoError(t, err)
+	kinds := make(map[string]string)
+	for _, def := range fileInfo.Definitions {
+		kinds[def.Name] = def.Type
+	}
+	assert.Equal(t, "field", kinds["X"])
+	assert.Equal(t, "field", kinds["Color"])
+	assert.Equal(t, "type", kinds["Shape"])
+	require.Contains(t, fileInfo.Symbols, "Shape.Style.Color")
+	assert.Equal(t, "Shape.Style", fileInfo.Symbols["Shape.Style.Color"].Parent)
+}
+
+func TestPromotedSelectors(t *testing.T) {
+	tempDir := testutil.TempFiles(t, fieldsModule)
+
+	fileInfo, err := NewPackagesAnalyzer(tempDir, nil).AnalyzeSingleFileWithPackages(context.Background(), "use.go")
+	require.NoError(t, err)
+
+	refs := make(map[string]*Reference)
+	for _, ref := range fileInfo.References {
+		refs[ref.Name] = ref
+	}
+	promotion := func(name string) []string {
+		require.Contains(t, refs, name)
+		var path []string
+		for _, embedded := range refs[name].Promotion {
+			path = append(path, embedded.Name)
+		}
+		return path
+	}
+
+	assert.Equal(t, []string{"Shape.Base", "Base.Point"}, promotion("X"))
+	assert.Equal(t, "Point.X", refs["X"].Target.Name)
+	assert.Equal(t, 4, refs["X"].Target.Line)
+	assert.Equal(t, "shapes.go", refs["X"].Promotion[1].File)
+	assert.Equal(t, 8, refs["X"].Promotion[1].Line)
+
+	assert.Equal(t, []string{"Shape.Base"}, promotion("Describe"))
+	assert.Equal(t, "(*Base).Describe", refs["Describe"].Target.Name)
+
+	assert.Empty(t, promotion("Name"))
+	assert.Equal(t, "Shape.Name", refs["Name"].Target.Name)
+	assert.Empty(t, promotion("Color"))
+	assert.Equal(t, "Shape.Style.Color", refs["Color"].Target.Name)
+}
+
+func TestFieldParentIndex(t *testing.T) {
+	check := func() *types.Package {
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, "shapes.go", `package shapes
+
+type Point struct{ X, Y int }
+
+type Shape struct {
+	Style struct{ Color string }
+}
+
+type Alias = Point
+
+type Copy Point
+`, 0)
+		require.NoError(t, err)
+		pkg, err := new(types.Config).Check("example.com/shapes", fset, []*ast.File{file}, nil)
+		require.NoError(t, err)
+		return pkg
+	}
+	field := func(pkg *types.Package, path ...string) *types.Var {
+		typ := pkg.Scope().Lookup(path[0]).Type()
+		var v *types.Var
+		for _, name := range path[1:] {
+			st := typ.Underlying().(*types.Struct)
+			for i := 0; i < st.NumFields(); i++ {
+				if st.Field(i).Name() == name {
+					v = st.Field(i)
+				}
+			}
+			typ = v.Type()
+		}
+		return v
+	}
+
+	pkg := check()
+	parents := fieldParents(pkg)
+	assert.Len(t, parents, 4)
+	assert.Equal(t, "Copy", parents[field(pkg, "Point", "X")], "first type in scope order sharing the struct")
+	assert.Equal(t, "Shape", parents[field(pkg, "Shape", "Style")])
+	assert.Equal(t, "Shape.Style", parents[field(pkg, "Shape", "Style", "Color")])
+
+	index := newFieldParentIndex()
+	assert.Equal(t, "Shape.Style", index.Parent(field(pkg, "Shape", "Style", "Color")))
+	var nilIndex *fieldParentIndex
+	assert.Equal(t, "Shape", nilIndex.Parent(field(pkg, "Shape", "Style")))
+
+	// A package checked again replaces the previous one
+	reloaded := check()
+	assert.Equal(t, "Shape", index.Parent(field(reloaded, "Shape", "Style")))
+	assert.Len(t, index.packages, 1)
+	assert.Same(t, reloaded, index.packages["example.com/shapes"].pkg)
+}
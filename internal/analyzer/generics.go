@@ -0,0 +1,89 @@
+package analyzer
+
+import (
+	"go/types"
+	"strings"
+)
+
+// isTypeParam reports whether obj declares a type parameter
+func isTypeParam(obj types.Object) bool {
+	if typeName, ok := obj.(*types.TypeName); ok {
+		_, ok = typeName.Type().(*types.TypeParam)
+		return ok
+	}
+	return false
+}
+
+// genericOrigin returns the generic declaration obj is an instantiation of, for methods
+// and fields of instantiated types, or obj itself
+func genericOrigin(obj types.Object) types.Object {
+	switch o := obj.(type) {
+	case *types.Func:
+		return o.Origin()
+	case *types.Var:
+		return o.Origin()
+	}
+	return obj
+}
+
+// typeNameWithParams returns the name of named followed by the type parameters of its
+// generic declaration, as in "List[T]", or its plain name if it is not generic
+func typeNameWithParams(named *types.Named) string {
+	origin := named.Origin()
+	params := origin.TypeParams()
+	if params.Len() == 0 {
+		return origin.Obj().Name()
+	}
+	names := make([]string, params.Len())
+	for i := range names {
+		names[i] = params.At(i).Obj().Name()
+	}
+	return origin.Obj().Name() + "[" + strings.Join(names, ", ") + "]"
+}
+
+// instanceSignature returns the signature of the instantiation of the generic function
+// or type obj recorded in inst, such as "func p.Map[int, string](s []int, ...) []string".
+// It returns "" if the type arguments are the type parameters of the declaration
+// themselves, as in the receiver of a method.
+func instanceSignature(obj types.Object, inst types.Instance) string {
+	if inst.TypeArgs == nil || inst.TypeArgs.Len() == 0 || isOwnTypeParams(obj, inst.TypeArgs) {
+		return ""
+	}
+	switch obj.(type) {
+	case *types.TypeName:
+		return "type " + inst.Type.String()
+	case *types.Func:
+		args := make([]string, inst.TypeArgs.Len())
+		for i := range args {
+			args[i] = inst.TypeArgs.At(i).String()
+		}
+		name := obj.Name()
+		if obj.Pkg() != nil {
+			name = obj.Pkg().Path() + "." + name
+		}
+		return "func " + name + "[" + strings.Join(args, ", ") + "]" + strings.TrimPrefix(inst.Type.String(), "func")
+	}
+	return ""
+}
+
+// isOwnTypeParams reports whether args are type parameters named as the ones of the
+// generic declaration obj, in order
+func isOwnTypeParams(obj types.Object, args *types.TypeList) bool {
+	var params *types.TypeParamList
+	switch t := obj.Type().(type) {
+	case *types.Named:
+		params = t.TypeParams()
+	case *types.Signature:
+		params = t.TypeParams()
+	}
+	if params == nil || params.Len() != args.Len() {
+		return false
+	}
+	for i := 0; i < args.Len(); i++ {
+		arg, ok := args.At(i).(*types.TypeParam)
+		if !ok || arg.Obj().Name() != params.At(i).Obj().Name() {
+			return false
+		}
+	}
+	return true
+}
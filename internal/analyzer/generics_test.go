@@ -0,0 +1,118 @@
+package analyzer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gonav/internal/testutil"
+)
+
+// genericsModule is a module declaring generic types and functions in package list
+// and using them from package main
+var genericsModule = map[string]string{
+	"go.mod": "module example.com/gen\n\ngo 1.21\n",
+	"list/list.go": `package list
+
+type List[T any] struct {
+	items []T
+}
+
+func (l *List[T]) Push(v T) {
+	l.items = append(l.items, v)
+}
+
+func (l List[T]) Len() int { return len(l.items) }
+
+func Map[K, V any](s []K, f func(K) V) []V {
+	out := make([]V, 0, len(s))
+	for _, k := range s {
+		out = append(out, f(k))
+	}
+	return out
+}
+`,
+	"main.go": `package main
+
+import (
+	"strconv"
+
+	"example.com/gen/list"
+)
+
+func main() {
+	var l list.List[int]
+	l.Push(1)
+	_ = list.Map([]int{l.Len()}, strconv.Itoa)
+}
+`,
+}
+
+func TestGenericDeclarations(t *testing.T) {
+	tempDir := testutil.TempFiles(t, genericsModule)
+
+	fileInfo, err := NewPackagesAnalyzer(tempDir, nil).AnalyzeSingleFileWithPackages(context.Background(), "list/list.go")
+	require.NoError(t, err)
+
+	scopeIDs := make([]string, 0, len(fileInfo.Scopes))
+	for _, scope := range fileInfo.Scopes {
+		scopeIDs = append(scopeIDs, scope.ID)
+	}
+	assert.Equal(t, []string{"/List", "/List_Push", "/List_Len", "/Map", "/Map/range_1"}, scopeIDs)
+
+	typeParams := make(map[string]string) // Scope -> type parameters declared in it
+	for _, def := range fileInfo.Definitions {
+		if def.Type == "typeparam" {
+			typeParams[def.ScopeID] += def.Name
+		}
+	}
+	assert.Equal(t, map[string]string{"/List": "T", "/List_Push": "T", "/List_Len": "T", "/Map": "KV"}, typeParams)
+
+	require.Contains(t, fileInfo.Symbols, "(*List[T]).Push")
+	assert.Equal(t, "function", fileInfo.Symbols["(*List[T]).Push"].Type)
+	require.Contains(t, fileInfo.Symbols, "List[T].Len")
+	require.Contains(t, fileInfo.Symbols, "T")
+	assert.Equal(t, "typeparam", fileInfo.Symbols["T"].Type)
+
+	packageInfo, err := NewPackagesAnalyzer(tempDir, nil).AnalyzePackageWithPackages(context.Background(), "list")
+	require.NoError(t, err)
+	assert.Contains(t, packageInfo.Symbols, "(*List[T]).Push")
+	assert.Contains(t, packageInfo.Symbols, "List[T].Len")
+}
+
+func TestGenericInstantiations(t *testing.T) {
+	tempDir := testutil.TempFiles(t, genericsModule)
+
+	fileInfo, err := NewPackagesAnalyzer(tempDir, nil).AnalyzeSingleFileWithPackages(context.Background(), "main.go")
+	require.NoError(t, err)
+
+	targets := make(map[string]*Symbol)
+	for _, ref := range fileInfo.References {
+		if ref.Target != nil {
+			targets[ref.Name] = ref.Target
+		}
+	}
+
+	require.Contains(t, targets, "List")
+	assert.Equal(t, "type", targets["List"].Type)
+	assert.Equal(t, "list/list.go", targets["List"].File)
+	assert.Equal(t, 3, targets["List"].Line)
+	assert.Equal(t, "type example.com/gen/list.List[int]", targets["List"].InstanceSignature)
+
+	require.Contains(t, targets, "Push")
+	push := targets["Push"]
+	assert.Equal(t, "(*List[T]).Push", push.Name)
+	assert.Equal(t, "list/list.go", push.File)
+	assert.Equal(t, 7, push.Line)
+	assert.Equal(t, "func (*example.com/gen/list.List[T]).Push(v T)", push.Signature)
+	assert.Equal(t, "func (*example.com/gen/list.List[int]).Push(v int)", push.InstanceSignature)
+
+	require.Contains(t, targets, "Len")
+	assert.Equal(t, "List[T].Len", targets["Len"].Name)
+
+	require.Contains(t, targets, "Map")
+	assert.Equal(t, "func example.com/gen/list.Map[K, V any](s []K, f func(K) V) []V", targets["Map"].Signature)
+	assert.Equal(t, "func example.com/gen/list.Map[int, string](s []int, f func(int) string) []string", targets["Map"].InstanceSignature)
+}
@@ -0,0 +1,284 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// ErrTypeNotFound is returned when the type of a hierarchy request is not declared in
+// the package, or is not a struct or interface
+var ErrTypeNotFound = errors.New("no struct or interface type with this name")
+
+const (
+	// DefaultHierarchyDepth is the number of embedding levels expanded when no depth is given
+	DefaultHierarchyDepth = 5
+
+	// MaxHierarchyDepth is the largest number of embedding levels expanded
+	MaxHierarchyDepth = 20
+
+	// MaxHierarchyNodes bounds the entries of a hierarchy, as every path through the
+	// embedding graph is expanded and may grow exponentially with the depth
+	MaxHierarchyNodes = 2000
+)
+
+// TypeHierarchy is a struct or interface type with the types it embeds and the types
+// of the module embedding it. Embedded types only list what they embed, and embedding
+// types what embeds them, so each direction is followed from the requested type.
+type TypeHierarchy struct {
+	Type       *Symbol            `json:"type"`
+	Kind       string             `json:"kind"`                 // "struct", "interface", or "type" for other embedded types
+	Pointer    bool               `json:"pointer,omitempty"`    // Embedded as a pointer, as *Base
+	Field      string             `json:"field,omitempty"`      // Struct field linking this entry and its parent, embedding one in the other
+	Methods    []*HierarchyMethod `json:"methods"`              // Method set, promoted methods included
+	Embeds     []*TypeHierarchy   `json:"embeds,omitempty"`     // Types embedded in this one
+	EmbeddedBy []*TypeHierarchy   `json:"embeddedBy,omitempty"` // Types of the module embedding this one
+	Truncated  bool               `json:"truncated,omitempty"`  // Not fully expanded, at the depth or node limit or in a cycle
+}
+
+// HierarchyMethod is a method of the method set of a type in a hierarchy
+type HierarchyMethod struct {
+	Name   string   `json:"name"`
+	Method *Symbol  `json:"method"`        // Declaration, named after its receiver as "(*Base).Describe"
+	Via    []string `json:"via,omitempty"` // Embedded fields or interfaces the method is promoted through, outermost first
+}
+
+// embedding is a type embedding another
+type embedding struct {
+	typeName *types.TypeName
+	field    string // Embedded field, "" for interfaces
+	pointer  bool
+}
+
+// hierarchyBuilder builds the hierarchy of a type within a module load
+type hierarchyBuilder struct {
+	pa        *PackagesAnalyzer
+	pkg       *packages.Package               // Package of the requested type, for positions
+	embedders map[*types.TypeName][]embedding // Embedded type -> types of the module embedding it
+	maxDepth  int
+	nodes     int // Entries built so far, bounded by MaxHierarchyNodes
+}
+
+// TypeHierarchy returns the embedding hierarchy of the struct or interface typeName
+// declared in the package at packagePath, relative to the repository root, expanded
+// to depth levels in each direction, at most MaxHierarchyDepth, and to at most
+// MaxHierarchyNodes entries. The module is loaded if needed.
+func (pa *PackagesAnalyzer) TypeHierarchy(ctx context.Context, packagePath, typeName string, depth int) (*TypeHierarchy, error) {
+	moduleLoad, err := pa.LoadModule(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pkg, err := pa.loadPackage(ctx, pa.moduleRelativePath(packagePath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load package %s: %w", packagePath, err)
+	}
+	if pkg.Types == nil {
+		return nil, newAnalysisError(fmt.Errorf("package %s has no type information", packagePath), pkg)
+	}
+
+	obj, ok := pkg.Types.Scope().Lookup(typeName).(*types.TypeName)
+	if !ok || obj.IsAlias() || hierarchyKind(obj.Type()) == "type" {
+		return nil, fmt.Errorf("%w: %s in package %s", ErrTypeNotFound, typeName, packagePath)
+	}
+	if depth <= 0 {
+		depth = DefaultHierarchyDepth
+	}
+	depth = min(depth, MaxHierarchyDepth)
+
+	builder := &hierarchyBuilder{
+		pa:        pa,
+		pkg:       pkg,
+		embedders: indexEmbedders(moduleLoad.Packages),
+		maxDepth:  depth,
+	}
+	root := builder.node(obj)
+	var embedsTruncated, embeddedByTruncated bool
+	root.Embeds, embedsTruncated = builder.embeds(obj, 1, map[*types.TypeName]bool{obj: true})
+	root.EmbeddedBy, embeddedByTruncated = builder.embeddedBy(obj, 1, map[*types.TypeName]bool{obj: true})
+	root.Truncated = embedsTruncated || embeddedByTruncated
+	return root, nil
+}
+
+// indexEmbedders maps the types embedded by the package-level types of pkgs to the
+// types embedding them
+func indexEmbedders(pkgs []*packages.Package) map[*types.TypeName][]embedding {
+	embedders := make(map[*types.TypeName][]embedding)
+	for _, pkg := range pkgs {
+		if pkg.Types == nil {
+			continue
+		}
+		scope := pkg.Types.Scope()
+		for _, name := range scope.Names() {
+			typeName, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || typeName.IsAlias() {
+				continue
+			}
+			for _, e := range embeddedTypes(typeName) {
+				embedders[e.typeName] = append(embedders[e.typeName], embedding{typeName: typeName, field: e.field, pointer: e.pointer})
+			}
+		}
+	}
+	return embedders
+}
+
+// embeddedTypes returns the named types embedded in the struct or interface declared
+// by typeName, in declaration order
+func embeddedTypes(typeName *types.TypeName) []embedding {
+	var embedded []embedding
+	switch t := typeName.Type().Underlying().(type) {
+	case *types.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			field := t.Field(i)
+			if !field.Embedded() {
+				continue
+			}
+			typ, pointer := field.Type(), false
+			if ptr, ok := typ.(*types.Pointer); ok {
+				typ, pointer = ptr.Elem(), true
+			}
+			if named, ok := typ.(*types.Named); ok {
+				embedded = append(embedded, embedding{typeName: named.Origin().Obj(), field: field.Name(), pointer: pointer})
+			}
+		}
+	case *types.Interface:
+		for i := 0; i < t.NumEmbeddeds(); i++ {
+			if named, ok := t.EmbeddedType(i).(*types.Named); ok {
+				embedded = append(embedded, embedding{typeName: named.Origin().Obj()})
+			}
+		}
+	}
+	return embedded
+}
+
+// hierarchyKind returns the kind of a type in a hierarchy
+func hierarchyKind(typ types.Type) string {
+	switch typ.Underlying().(type) {
+	case *types.Struct:
+		return "struct"
+	case *types.Interface:
+		return "interface"
+	}
+	return "type"
+}
+
+// node returns the hierarchy entry of typeName, with its method set
+func (b *hierarchyBuilder) node(typeName *types.TypeName) *TypeHierarchy {
+	b.nodes++
+	node := &TypeHierarchy{
+		Type:    b.pa.convertObjectToSymbol(typeName, b.pkg),
+		Kind:    hierarchyKind(typeName.Type()),
+		Methods: make([]*HierarchyMethod, 0),
+	}
+
+	typ := typeName.Type()
+	methodSetType := typ
+	if node.Kind != "interface" {
+		// Methods with pointer receivers are in the method set of addressable values
+		methodSetType = types.NewPointer(typ)
+	}
+	methodSet := types.NewMethodSet(methodSetType)
+	for i := 0; i < methodSet.Len(); i++ {
+		sel := methodSet.At(i)
+		method := &HierarchyMethod{
+			Name:   sel.Obj().Name(),
+			Method: b.pa.convertObjectToSymbol(sel.Obj(), b.pkg),
+		}
+		for _, embedded := range promotionPath(sel) {
+			method.Via = append(method.Via, embedded.Name())
+		}
+		if iface, ok := typ.Underlying().(*types.Interface); ok {
+			method.Via = interfaceMethodVia(iface, sel.Obj())
+		}
+		node.Methods = append(node.Methods, method)
+	}
+	return node
+}
+
+// interfaceMethodVia returns the name of the embedded interface method is promoted
+// from, or nil if iface declares it
+func interfaceMethodVia(iface *types.Interface, method types.Object) []string {
+	for i := 0; i < iface.NumExplicitMethods(); i++ {
+		if iface.ExplicitMethod(i) == method {
+			return nil
+		}
+	}
+	for i := 0; i < iface.NumEmbeddeds(); i++ {
+		named, ok := iface.EmbeddedType(i).(*types.Named)
+		if !ok {
+			continue
+		}
+		if obj, _, _ := types.LookupFieldOrMethod(named, false, method.Pkg(), method.Name()); obj == method {
+			return []string{named.Obj().Name()}
+		}
+	}
+	return nil
+}
+
+// embeds returns the entries of the types embedded in typeName, at the given depth,
+// and whether some were left out once the node budget was spent. path holds the types
+// being expanded, to stop at cycles such as type Node struct{ *Node }.
+func (b *hierarchyBuilder) embeds(typeName *types.TypeName, depth int, path map[*types.TypeName]bool) ([]*TypeHierarchy, bool) {
+	var nodes []*TypeHierarchy
+	for _, e := range embeddedTypes(typeName) {
+		if b.nodes >= MaxHierarchyNodes {
+			return nodes, true
+		}
+		node := b.node(e.typeName)
+		node.Field = e.field
+		node.Pointer = e.pointer
+		if depth >= b.maxDepth || path[e.typeName] {
+			node.Truncated = len(embeddedTypes(e.typeName)) > 0
+		} else {
+			path[e.typeName] = true
+			node.Embeds, node.Truncated = b.embeds(e.typeName, depth+1, path)
+			delete(path, e.typeName)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, false
+}
+
+// embeddedBy returns the entries of the types of the module embedding typeName, at
+// the given depth, sorted by package and name, and whether some were left out once
+// the node budget was spent
+func (b *hierarchyBuilder) embeddedBy(typeName *types.TypeName, depth int, path map[*types.TypeName]bool) ([]*TypeHierarchy, bool) {
+	embedders := append([]embedding(nil), b.embedders[typeName]...)
+	sort.SliceStable(embedders, func(i, j int) bool {
+		pi, pj := embedders[i].typeName.Pkg().Path(), embedders[j].typeName.Pkg().Path()
+		if pi != pj {
+			return pi < pj
+		}
+		return embedders[i].typeName.Name() < embedders[j].typeName.Name()
+	})
+
+	var nodes []*TypeHierarchy
+	for _, e := range embedders {
+		if b.nodes >= MaxHierarchyNodes {
+			return nodes, true
+		}
+		node := b.node(e.typeName)
+		node.Field = e.field
+		node.Pointer = e.pointer
+		if depth >= b.maxDepth || path[e.typeName] {
+			node.Truncated = len(b.embedders[e.typeName]) > 0
+		} else {
+			path[e.typeName] = true
+			node.EmbeddedBy, node.Truncated = b.embeddedBy(e.typeName, depth+1, path)
+			delete(path, e.typeName)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, false
+}
+
+// TypeHierarchy returns the embedding hierarchy of the struct or interface typeName
+// declared in the package at packagePath, in the module of the repository at repoPath
+// containing the package
+func (a *PackageAnalyzer) TypeHierarchy(ctx context.Context, repoPath, packagePath, typeName string, depth int) (*TypeHierarchy, error) {
+	moduleDir := FindModuleDir(repoPath, packagePath)
+	return a.packagesAnalyzerFor(repoPath, moduleDir).TypeHierarchy(ctx, packagePath, typeName, depth)
+}
@@ -0,0 +1,171 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gonav/internal/testutil"
+)
+
+// hierarchyFiles are added to fieldsModule to declare interfaces and embedding cycles
+var hierarchyFiles = map[string]string{
+	"io/io.go": `package io
+
+type Reader interface {
+	Read(p []byte) (int, error)
+}
+
+type Writer interface {
+	Write(p []byte) (int, error)
+}
+
+type ReadWriter interface {
+	Reader
+	Writer
+	Close() error
+}
+
+type Node struct {
+	*Node
+	Value int
+}
+`,
+	"labeled.go": `package shapes
+
+type Labeled struct {
+	Shape
+}
+`,
+}
+
+// hierarchyNames returns the names of the types of nodes
+func hierarchyNames(nodes []*TypeHierarchy) []string {
+	var names []string
+	for _, node := range nodes {
+		names = append(names, node.Type.Name)
+	}
+	return names
+}
+
+func TestTypeHierarchy(t *testing.T) {
+	tempDir := testutil.TempFiles(t, fieldsModule, hierarchyFiles)
+	pa := NewPackagesAnalyzer(tempDir, nil)
+
+	base, err := pa.TypeHierarchy(context.Background(), "", "Base", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "struct", base.Kind)
+	assert.Equal(t, "shapes.go", base.Type.File)
+
+	// Base embeds Point, and is embedded by Shape, itself embedded by Labeled
+	assert.Equal(t, []string{"Point"}, hierarchyNames(base.Embeds))
+	assert.Equal(t, "Point", base.Embeds[0].Field)
+	require.Equal(t, []string{"Shape"}, hierarchyNames(base.EmbeddedBy))
+	shape := base.EmbeddedBy[0]
+	assert.Equal(t, "Base", shape.Field)
+	assert.True(t, shape.Pointer)
+	assert.Equal(t, []string{"Labeled"}, hierarchyNames(shape.EmbeddedBy))
+	assert.Empty(t, shape.Embeds, "embedding types only list what embeds them")
+
+	// Promoted methods record the embedded fields they come through
+	require.Len(t, shape.Methods, 1)
+	assert.Equal(t, "Describe", shape.Methods[0].Name)
+	assert.Equal(t, "(*Base).Describe", shape.Methods[0].Method.Name)
+	assert.Equal(t, []string{"Base"}, shape.Methods[0].Via)
+	labeled := shape.EmbeddedBy[0]
+	require.Len(t, labeled.Methods, 1)
+	assert.Equal(t, []string{"Shape", "Base"}, labeled.Methods[0].Via)
+	require.Len(t, base.Methods, 1)
+	assert.Empty(t, base.Methods[0].Via)
+
+	// The depth limits expansion
+	base, err = pa.TypeHierarchy(context.Background(), "", "Base", 1)
+	require.NoError(t, err)
+	require.Len(t, base.EmbeddedBy, 1)
+	assert.Empty(t, base.EmbeddedBy[0].EmbeddedBy)
+	assert.True(t, base.EmbeddedBy[0].Truncated)
+
+	_, err = pa.TypeHierarchy(context.Background(), "", "Missing", 0)
+	assert.True(t, errors.Is(err, ErrTypeNotFound))
+}
+
+func TestInterfaceHierarchy(t *testing.T) {
+	tempDir := testutil.TempFiles(t, fieldsModule, hierarchyFiles)
+	pa := NewPackagesAnalyzer(tempDir, nil)
+
+	rw, err := pa.TypeHierarchy(context.Background(), "io", "ReadWriter", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "interface", rw.Kind)
+	assert.Equal(t, []string{"Reader", "Writer"}, hierarchyNames(rw.Embeds))
+
+	via := make(map[string][]string)
+	for _, method := range rw.Methods {
+		via[method.Name] = method.Via
+	}
+	assert.Equal(t, map[string][]string{"Close": nil, "Read": {"Reader"}, "Write": {"Writer"}}, via)
+
+	reader, err := pa.TypeHierarchy(context.Background(), "io", "Reader", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ReadWriter"}, hierarchyNames(reader.EmbeddedBy))
+
+	// Cycles are not expanded
+	node, err := pa.TypeHierarchy(context.Background(), "io", "Node", 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Node"}, hierarchyNames(node.Embeds))
+	assert.True(t, node.Embeds[0].Truncated)
+	assert.True(t, node.Embeds[0].Pointer)
+	require.Equal(t, []string{"Node"}, hierarchyNames(node.EmbeddedBy))
+	assert.True(t, node.EmbeddedBy[0].Truncated)
+}
+
+// countHierarchy returns the number of entries below node and the depth of its
+// deepest Embeds entry
+func countHierarchy(node *TypeHierarchy) (int, int) {
+	count, depth := 0, 0
+	for _, child := range append(append([]*TypeHierarchy{}, node.Embeds...), node.EmbeddedBy...) {
+		childCount, childDepth := countHierarchy(child)
+		count += 1 + childCount
+		depth = max(depth, 1+childDepth)
+	}
+	return count, depth
+}
+
+func TestHierarchyLimits(t *testing.T) {
+	// Each level embeds both types of the next one, doubling the paths at every level,
+	// and a chain longer than the maximum depth
+	var dag, chain strings.Builder
+	dag.WriteString("package dag\n")
+	chain.WriteString("package chain\n")
+	for i := 0; i < 14; i++ {
+		fmt.Fprintf(&dag, "\ntype A%d struct{ A%d; B%d }\n\ntype B%d struct{ A%d; B%d }\n", i, i+1, i+1, i, i+1, i+1)
+	}
+	dag.WriteString("\ntype A14 struct{}\n\ntype B14 struct{}\n")
+	for i := 0; i < MaxHierarchyDepth+5; i++ {
+		fmt.Fprintf(&chain, "\ntype C%d struct{ C%d }\n", i, i+1)
+	}
+	fmt.Fprintf(&chain, "\ntype C%d struct{}\n", MaxHierarchyDepth+5)
+
+	tempDir := testutil.TempFiles(t, fieldsModule, map[string]string{
+		"dag/dag.go":     dag.String(),
+		"chain/chain.go": chain.String(),
+	})
+	pa := NewPackagesAnalyzer(tempDir, nil)
+
+	// The node budget bounds exponential hierarchies
+	root, err := pa.TypeHierarchy(context.Background(), "dag", "A0", MaxHierarchyDepth)
+	require.NoError(t, err)
+	assert.True(t, root.Truncated)
+	count, _ := countHierarchy(root)
+	assert.LessOrEqual(t, count, MaxHierarchyNodes)
+
+	// Depths above the maximum are clamped
+	root, err = pa.TypeHierarchy(context.Background(), "chain", "C0", 1000000)
+	require.NoError(t, err)
+	_, depth := countHierarchy(root)
+	assert.Equal(t, MaxHierarchyDepth, depth)
+}
@@ -1,6 +1,7 @@
 package analyzer
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
@@ -103,7 +104,7 @@ go 1.21
 	require.NotNil(t, packagesAnalyzer)
 
 	// Analyze the package
-	packageInfo, err := packagesAnalyzer.AnalyzePackageWithPackages("")
+	packageInfo, err := packagesAnalyzer.AnalyzePackageWithPackages(context.Background(), "")
 	require.NoError(t, err)
 	require.NotNil(t, packageInfo)
 
@@ -244,7 +245,7 @@ go 1.21
 	packagesAnalyzer := NewPackagesAnalyzer(tempDir, nil)
 
 	// Analyze single file for references
-	fileInfo, err := packagesAnalyzer.AnalyzeSingleFileWithPackages("main.go")
+	fileInfo, err := packagesAnalyzer.AnalyzeSingleFileWithPackages(context.Background(), "main.go")
 	require.NoError(t, err)
 	require.NotNil(t, fileInfo)
 
@@ -253,7 +254,8 @@ go 1.21
 
 	foundMethodRefs := make(map[string]*Reference)
 	for _, ref := range fileInfo.References {
-		if ref.Target != nil && strings.Contains(ref.Target.Name, ".") {
+		// Fields are qualified by their type as well
+		if ref.Target != nil && ref.Target.Type != "field" && strings.Contains(ref.Target.Name, ".") {
 			foundMethodRefs[ref.Target.Name] = ref
 			t.Logf("Found method reference: %s -> %s", ref.Name, ref.Target.Name)
 		}
@@ -353,7 +355,7 @@ go 1.21
 	packagesAnalyzer := NewPackagesAnalyzer(tempDir, nil)
 
 	// Analyze the package
-	packageInfo, err := packagesAnalyzer.AnalyzePackageWithPackages("")
+	packageInfo, err := packagesAnalyzer.AnalyzePackageWithPackages(context.Background(), "")
 	require.NoError(t, err)
 	require.NotNil(t, packageInfo)
 
@@ -0,0 +1,27 @@
+package analyzer
+
+import (
+	"time"
+
+	"gonav/internal/metrics"
+)
+
+var (
+	packagesLoadDuration = metrics.Default.NewHistogramVec("gonav_packages_load_duration_seconds",
+		"Duration of packages.Load calls, by result (success or error).", metrics.DefaultBuckets, "result")
+
+	moduleLoadResults = metrics.Default.NewCounterVec("gonav_module_load_results_total",
+		"Module load lookups, by result (hit when the loaded packages were reused, miss when the module was loaded).", "result")
+
+	analysisCacheResults = metrics.Default.NewCounterVec("gonav_analysis_cache_results_total",
+		"Analysis cache lookups, by result (hit, miss, newer or no_change).", "result")
+)
+
+// observeLoad records the duration of a packages.Load call started at start
+func observeLoad(start time.Time, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	packagesLoadDuration.With(result).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,53 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModuleLoad_ResultMetrics(t *testing.T) {
+	repoDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(repoDir, "go.mod"), []byte("module example.com/app\n\ngo 1.21\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(repoDir, "app.go"), []byte("package app\n"), 0644))
+
+	count := func(result string) float64 {
+		return moduleLoadResults.With(result).Value()
+	}
+	hits, misses := count("hit"), count("miss")
+
+	packagesAnalyzer := NewPackagesAnalyzer(repoDir, nil)
+	_, err := packagesAnalyzer.LoadModule(context.Background())
+	require.NoError(t, err)
+	_, err = packagesAnalyzer.LoadModule(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, 1.0, count("miss")-misses)
+	assert.Equal(t, 1.0, count("hit")-hits)
+}
+
+func TestAnalysisCache_ResultMetrics(t *testing.T) {
+	count := func(result CacheResult) float64 {
+		return analysisCacheResults.With(string(result)).Value()
+	}
+	before := map[CacheResult]float64{}
+	for _, result := range []CacheResult{CacheResultMiss, CacheResultHit, CacheResultNoChange, CacheResultNewer} {
+		before[result] = count(result)
+	}
+
+	cache := NewAnalysisCache(&SimpleDependencyChecker{})
+	key := CacheKey{Type: CacheKeyTypePackage, PackagePath: "app"}
+	cache.Get(key, "")
+	cache.Set(key, &CachedAnalysis{Revision: "r2"})
+	cache.Get(key, "")
+	cache.Get(key, "r2")
+	cache.Get(key, "r1")
+
+	for _, result := range []CacheResult{CacheResultMiss, CacheResultHit, CacheResultNoChange, CacheResultNewer} {
+		assert.Equal(t, 1.0, count(result)-before[result], result)
+	}
+}
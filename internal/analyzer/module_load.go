@@ -0,0 +1,178 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"golang.org/x/tools/go/packages"
+
+	"gonav/internal/env"
+)
+
+// ModuleLoad holds every package of a module, loaded and type-checked at once.
+// Package and file requests are served from it instead of loading again.
+type ModuleLoad struct {
+	Packages []*packages.Package
+	LoadedAt time.Time
+	Duration time.Duration
+
+	byDir  map[string]*packages.Package // Canonical package directory -> package
+	byFile map[string]*packages.Package // Canonical file path -> package
+}
+
+// newModuleLoad indexes loaded packages by directory and file
+func newModuleLoad(pkgs []*packages.Package) *ModuleLoad {
+	load := &ModuleLoad{
+		Packages: pkgs,
+		LoadedAt: time.Now(),
+		byDir:    make(map[string]*packages.Package),
+		byFile:   make(map[string]*packages.Package),
+	}
+
+	for _, pkg := range pkgs {
+		for _, file := range append(append([]string{}, pkg.GoFiles...), pkg.CompiledGoFiles...) {
+			path := canonicalPath(file)
+			if _, exists := load.byFile[path]; !exists {
+				load.byFile[path] = pkg
+			}
+		}
+		if len(pkg.GoFiles) > 0 {
+			dir := canonicalPath(filepath.Dir(pkg.GoFiles[0]))
+			if _, exists := load.byDir[dir]; !exists {
+				load.byDir[dir] = pkg
+			}
+		}
+	}
+
+	return load
+}
+
+// PackageForDir returns the package in the directory dir, or nil
+func (ml *ModuleLoad) PackageForDir(dir string) *packages.Package {
+	return ml.byDir[canonicalPath(dir)]
+}
+
+// PackageForFile returns the package containing the file at path, or nil
+func (ml *ModuleLoad) PackageForFile(path string) *packages.Package {
+	return ml.byFile[canonicalPath(path)]
+}
+
+// LoadModule loads and type-checks every package of the module on first use and
+// returns the same packages graph afterwards, until InvalidateModuleLoad is called.
+// Concurrent calls share one load, which runs without holding the load mutex.
+// Loading stops when ctx is done or the load timeout expires.
+func (pa *PackagesAnalyzer) LoadModule(ctx context.Context) (*ModuleLoad, error) {
+	pa.loadMutex.Lock()
+	moduleLoad, generation := pa.moduleLoad, pa.loadGeneration
+	pa.loadMutex.Unlock()
+
+	if moduleLoad != nil {
+		moduleLoadResults.With("hit").Inc()
+		return moduleLoad, nil
+	}
+	moduleLoadResults.With("miss").Inc()
+
+	moduleLoad, err, _ := pa.loads.Do(ctx, strconv.Itoa(generation), func(ctx context.Context) (*ModuleLoad, error) {
+		if pa.vendorErr != nil {
+			pa.logger.WarnContext(ctx, "Failed to parse vendor/modules.txt, loading from the module cache",
+				"dir", pa.config.Dir, "error", pa.vendorErr)
+		}
+
+		start := time.Now()
+		pkgs, err := pa.load(ctx, "./...")
+		if err != nil {
+			return nil, err
+		}
+
+		moduleLoad := newModuleLoad(pkgs)
+		moduleLoad.Duration = time.Since(start)
+		pa.logger.InfoContext(ctx, "Loaded module", "dir", pa.config.Dir, "packages", len(pkgs), "duration", moduleLoad.Duration)
+
+		// A load started before the module load was dropped may miss dependencies
+		// downloaded since, so it serves its callers but is not kept
+		pa.loadMutex.Lock()
+		if pa.loadGeneration == generation {
+			pa.moduleLoad = moduleLoad
+		}
+		pa.loadMutex.Unlock()
+		return moduleLoad, nil
+	})
+	return moduleLoad, err
+}
+
+// InvalidateModuleLoad drops the loaded packages graph, so the next request loads
+// the module again. Used when dependencies become available after the first load.
+func (pa *PackagesAnalyzer) InvalidateModuleLoad() {
+	pa.dropModuleLoad()
+}
+
+// dropModuleLoad drops the loaded packages graph, and the result of any load in
+// flight, returning false if none was loaded
+func (pa *PackagesAnalyzer) dropModuleLoad() bool {
+	pa.loadMutex.Lock()
+	defer pa.loadMutex.Unlock()
+	loaded := pa.moduleLoad != nil
+	pa.moduleLoad = nil
+	pa.loadGeneration++
+	return loaded
+}
+
+// loadPackage returns the package in moduleRelDir, a directory relative to the module
+// being analyzed, from the module load. Directories outside the module's "./..."
+// pattern, such as the vendor tree, are loaded on their own.
+func (pa *PackagesAnalyzer) loadPackage(ctx context.Context, moduleRelDir string) (*packages.Package, error) {
+	dir := filepath.Join(pa.config.Dir, filepath.FromSlash(moduleRelDir))
+
+	moduleLoad, err := pa.LoadModule(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if pkg := moduleLoad.PackageForDir(dir); pkg != nil {
+		return pkg, nil
+	}
+
+	pattern := "./" + moduleRelDir
+	pkgs, err := pa.load(ctx, pattern)
+	if err != nil {
+		return nil, err
+	}
+	if pkg := newModuleLoad(pkgs).PackageForDir(dir); pkg != nil {
+		return pkg, nil
+	}
+	return nil, newAnalysisError(fmt.Errorf("no packages found for pattern %s", pattern), pkgs...)
+}
+
+// load runs packages.Load for pattern, bounded by ctx and the load timeout
+func (pa *PackagesAnalyzer) load(ctx context.Context, pattern string) ([]*packages.Package, error) {
+	ctx, cancel := env.WithTimeout(ctx, pa.loadTimeout)
+	defer cancel()
+
+	// The configuration is shared by concurrent requests, each with its own context
+	config := *pa.config
+	config.Context = ctx
+
+	operation := fmt.Sprintf("loading packages %s in %s", pattern, pa.config.Dir)
+	start := time.Now()
+	pkgs, err := packages.Load(&config, pattern)
+	observeLoad(start, err)
+	if err != nil {
+		return nil, env.ContextError(ctx, operation, pa.loadTimeout, fmt.Errorf("failed to load packages %s: %w", pattern, err))
+	}
+	// Packages loaded from an interrupted go list are incomplete
+	if ctx.Err() != nil {
+		return nil, env.ContextError(ctx, operation, pa.loadTimeout, ctx.Err())
+	}
+	return pkgs, nil
+}
+
+// canonicalPath resolves symlinks so paths reported by the go command can be
+// compared with paths under the repository directory
+func canonicalPath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return filepath.Clean(path)
+}
@@ -0,0 +1,227 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gonav/internal/testutil"
+)
+
+func TestModuleLoad(t *testing.T) {
+	repoDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.21\n",
+		"main.go": `package main
+
+import "example.com/app/util"
+
+func main() {
+	util.Add(1, 2)
+}
+`,
+		"util/util.go": `package util
+
+// Add adds
+func Add(a, b int) int { return a + b }
+`,
+		"util/xutil.go": "package util\n\nfunc Sub(a, b int) int { return a - b }\n",
+	}
+	testutil.WriteFiles(t, repoDir, files)
+
+	analyzer := New()
+	analyzer.SetRepositoryContext(repoDir, nil)
+	packagesAnalyzer := analyzer.packagesAnalyzerFor(repoDir, "")
+
+	// The root package is the package at the module root, not the first one loaded
+	packageInfo, err := analyzer.AnalyzePackage(context.Background(), repoDir, "")
+	require.NoError(t, err)
+	assert.Equal(t, "main", packageInfo.Name)
+	assert.Equal(t, "example.com/app", packageInfo.Path)
+
+	moduleLoad, err := packagesAnalyzer.LoadModule(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, moduleLoad.Packages, 2)
+	assert.NotNil(t, moduleLoad.PackageForFile(filepath.Join(repoDir, "util", "xutil.go")))
+
+	// Further package and file requests are served from the same load
+	packageInfo, err = analyzer.AnalyzePackage(context.Background(), repoDir, "util")
+	require.NoError(t, err)
+	assert.Equal(t, "util", packageInfo.Name)
+	assert.Contains(t, packageInfo.Symbols, "Sub")
+
+	fileInfo, err := analyzer.AnalyzeSingleFile(context.Background(), repoDir, "util/util.go")
+	require.NoError(t, err)
+	assert.Contains(t, fileInfo.Source, "func Add")
+
+	fileInfo, err = analyzer.AnalyzeSingleFile(context.Background(), repoDir, "main.go")
+	require.NoError(t, err)
+	assert.Contains(t, fileInfo.Source, "func main")
+
+	reloaded, err := packagesAnalyzer.LoadModule(context.Background())
+	require.NoError(t, err)
+	assert.Same(t, moduleLoad, reloaded)
+
+	// Configuring the same repository again keeps the load
+	analyzer.SetRepositoryContext(repoDir, nil)
+	assert.Same(t, packagesAnalyzer, analyzer.packagesAnalyzerFor(repoDir, ""))
+
+	// Invalidating forces a new load
+	packagesAnalyzer.InvalidateModuleLoad()
+	reloaded, err = packagesAnalyzer.LoadModule(context.Background())
+	require.NoError(t, err)
+	assert.NotSame(t, moduleLoad, reloaded)
+
+	// Releasing the repository drops its analyzers
+	analyzer.ReleaseRepository(repoDir)
+	assert.NotSame(t, packagesAnalyzer, analyzer.packagesAnalyzerFor(repoDir, ""))
+}
+
+func TestModuleLoad_MultipleRepositories(t *testing.T) {
+	writeRepo := func(modulePath, source string) string {
+		repoDir := t.TempDir()
+		testutil.WriteFiles(t, repoDir, map[string]string{
+			"go.mod": "module " + modulePath + "\n\ngo 1.21\n",
+			"lib.go": source,
+		})
+		return repoDir
+	}
+	firstRepo := writeRepo("example.com/first", "package first\n\nfunc First() {}\n")
+	secondRepo := writeRepo("example.com/second", "package second\n\nfunc Second() {}\n")
+
+	analyzer := New()
+	analyzer.SetRepositoryContext(firstRepo, nil)
+	analyzer.SetRepositoryContext(secondRepo, nil)
+
+	// Each repository is analyzed from its own module, whichever was configured last
+	packageInfo, err := analyzer.AnalyzePackage(context.Background(), firstRepo, "")
+	require.NoError(t, err)
+	assert.Equal(t, "first", packageInfo.Name)
+
+	packageInfo, err = analyzer.AnalyzePackage(context.Background(), secondRepo, "")
+	require.NoError(t, err)
+	assert.Equal(t, "second", packageInfo.Name)
+	assert.Equal(t, 2, analyzer.LoadedModules())
+
+	// Clearing the analysis cache of a repository keeps the other loaded
+	assert.Equal(t, 1, analyzer.DropModuleLoads(firstRepo))
+	assert.Equal(t, 1, analyzer.LoadedModules())
+	assert.Equal(t, 0, analyzer.DropModuleLoads(firstRepo))
+	assert.Equal(t, 1, analyzer.DropModuleLoads(""))
+	assert.Equal(t, 0, analyzer.LoadedModules())
+
+	// Cleared modules are loaded again on demand
+	packageInfo, err = analyzer.AnalyzePackage(context.Background(), firstRepo, "")
+	require.NoError(t, err)
+	assert.Equal(t, "first", packageInfo.Name)
+	assert.Equal(t, 1, analyzer.LoadedModules())
+}
+
+func TestModuleLoad_ConcurrentAnalyses(t *testing.T) {
+	repoDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(repoDir, "go.mod"), []byte("module example.com/app\n\ngo 1.21\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(repoDir, "app.go"), []byte("package app\n\nfunc Run() {}\n"), 0644))
+
+	analyzer := New()
+	analyzer.SetRepositoryContext(repoDir, nil)
+	packagesAnalyzer := analyzer.packagesAnalyzerFor(repoDir, "")
+
+	// Hold the module load so every request arrives while the first is in flight
+	packagesAnalyzer.loadMutex.Lock()
+	const requests = 3
+	var wg sync.WaitGroup
+	packages := make([]*PackageInfo, requests)
+	files := make([]*FileInfo, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			packageInfo, err := analyzer.AnalyzePackage(context.Background(), repoDir, "")
+			assert.NoError(t, err)
+			packages[i] = packageInfo
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			fileInfo, err := analyzer.AnalyzeSingleFile(context.Background(), repoDir, "app.go")
+			assert.NoError(t, err)
+			files[i] = fileInfo
+		}(i)
+	}
+	// Configuring the repository again does not race with the requests
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		analyzer.SetRepositoryContext(repoDir, nil)
+	}()
+	require.Eventually(t, func() bool {
+		return analyzer.analyses.InFlight() == 2
+	}, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	packagesAnalyzer.loadMutex.Unlock()
+	wg.Wait()
+
+	// Concurrent requests for the same key share one result
+	require.NotNil(t, packages[0])
+	require.NotNil(t, files[0])
+	for i := 1; i < requests; i++ {
+		assert.Same(t, packages[0], packages[i])
+		assert.Same(t, files[0], files[i])
+	}
+	assert.Contains(t, packages[0].Symbols, "Run")
+}
+
+func TestModuleLoad_Timeout(t *testing.T) {
+	repoDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(repoDir, "go.mod"), []byte("module example.com/slow\n\ngo 1.21\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(repoDir, "lib.go"), []byte("package slow\n\nfunc Slow() {}\n"), 0644))
+
+	analyzer := New()
+	analyzer.SetRepositoryContext(repoDir, nil)
+
+	// Canceled requests stop loading
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := analyzer.AnalyzePackage(ctx, repoDir, "")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// Loads exceeding the timeout fail with a clear error
+	analyzer.SetLoadTimeout(time.Nanosecond)
+	_, err = analyzer.AnalyzeSingleFile(context.Background(), repoDir, "lib.go")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "timed out after 1ns")
+
+	// Failed loads are not kept
+	analyzer.SetLoadTimeout(0)
+	packageInfo, err := analyzer.AnalyzePackage(context.Background(), repoDir, "")
+	require.NoError(t, err)
+	assert.Equal(t, "slow", packageInfo.Name)
+}
+
+func TestModuleLoad_AnalysisError(t *testing.T) {
+	repoDir := t.TempDir()
+	files := map[string]string{
+		"go.mod":            "module example.com/broken\n\ngo 1.21\n",
+		"broken/broken.go":  "package broken\n\nimport \"example.com/missing\"\n\nvar _ = missing.Value\n",
+		"broken/ignored.go": "//go:build ignore\n\npackage broken\n",
+	}
+	testutil.WriteFiles(t, repoDir, files)
+
+	analyzer := New()
+	analyzer.SetRepositoryContext(repoDir, append(os.Environ(), "GOPROXY=off", "GOFLAGS=-mod=mod"))
+
+	// Files that cannot be analyzed report the errors of their package
+	_, err := analyzer.AnalyzeSingleFile(context.Background(), repoDir, "broken/ignored.go")
+	require.Error(t, err)
+
+	var analysisErr *AnalysisError
+	require.ErrorAs(t, err, &analysisErr)
+	require.NotEmpty(t, analysisErr.ImportErrors)
+	assert.Contains(t, analysisErr.ImportErrors[0].Error, "example.com/missing")
+}
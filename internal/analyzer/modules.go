@@ -0,0 +1,224 @@
+package analyzer
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gonav/internal/repo"
+)
+
+// ModuleRoot describes a Go module found in a repository. Repositories can contain
+// nested modules, such as tools/go.mod or sdk/v2/go.mod, next to the root module.
+type ModuleRoot struct {
+	Path string      `json:"path"` // Module path from go.mod
+	Dir  string      `json:"dir"`  // Relative path from repo root, "" for the root module
+	Info *ModuleInfo `json:"-"`
+}
+
+// DiscoverModules finds every module of the repository, the root module first. The
+// go.mod files are found by repo.FindModules, so the server and the analyzer agree on
+// which directories hold modules.
+func (a *PackageAnalyzer) DiscoverModules(repoPath string) ([]*ModuleRoot, error) {
+	entries, err := repo.FindModules(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	modules := make([]*ModuleRoot, 0, len(entries))
+	for _, entry := range entries {
+		moduleInfo, err := a.ParseModuleInfo(filepath.Join(repoPath, filepath.FromSlash(entry.Dir)))
+		if err != nil {
+			a.logger.Warn("Skipping module", "dir", entry.Dir, "error", err)
+			continue
+		}
+
+		modules = append(modules, &ModuleRoot{
+			Path: moduleInfo.ModulePath,
+			Dir:  entry.Dir,
+			Info: moduleInfo,
+		})
+	}
+	return modules, nil
+}
+
+// FindModuleDir returns the directory, relative to the repository root, of the module
+// containing relDir: the closest enclosing directory with a go.mod file, ignoring those
+// skipped by repo.FindModules. It returns "" for the root module, including when the
+// repository has no go.mod at all.
+func FindModuleDir(repoPath, relDir string) string {
+	dir := filepath.ToSlash(filepath.Clean(relDir))
+	for dir != "." && dir != "" && dir != "/" && !strings.HasPrefix(dir, "..") {
+		if slices.ContainsFunc(strings.Split(dir, "/"), repo.SkipModuleDir) {
+			dir = filepath.ToSlash(filepath.Dir(dir))
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(repoPath, filepath.FromSlash(dir), "go.mod")); err == nil {
+			return dir
+		}
+		dir = filepath.ToSlash(filepath.Dir(dir))
+	}
+	return ""
+}
+
+// moduleContext returns the module directory and module information for a path
+// relative to the repository root
+func (a *PackageAnalyzer) moduleContext(repoPath, relDir string) (string, *ModuleInfo) {
+	moduleDir := FindModuleDir(repoPath, relDir)
+	return moduleDir, a.moduleInfo(filepath.Join(repoPath, filepath.FromSlash(moduleDir)))
+}
+
+// moduleInfo parses the go.mod file in dir, returning an empty module context if it
+// cannot be parsed
+func (a *PackageAnalyzer) moduleInfo(dir string) *ModuleInfo {
+	moduleInfo, err := a.ParseModuleInfo(dir)
+	if err != nil {
+		a.logger.Warn("Failed to parse module info", "dir", dir, "error", err)
+		moduleInfo = &ModuleInfo{
+			ModulePath:   "",
+			Dependencies: make(map[string]string),
+			Replaces:     make(map[string]string),
+		}
+	}
+	return moduleInfo
+}
+
+// packagesAnalyzerFor returns the packages analyzer loading packages in the context of
+// the module at moduleDir, creating it on first use
+func (a *PackageAnalyzer) packagesAnalyzerFor(repoPath, moduleDir string) *PackagesAnalyzer {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.analyzerForDir(repoPath, filepath.Join(repoPath, filepath.FromSlash(moduleDir)))
+}
+
+// repositoryAnalyzers returns the packages analyzers of every module of the repository
+// at repoPath. Repositories without go.mod are analyzed as a single module.
+func (a *PackageAnalyzer) repositoryAnalyzers(repoPath string) ([]*PackagesAnalyzer, error) {
+	modules, err := a.DiscoverModules(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(modules) == 0 {
+		modules = append(modules, &ModuleRoot{Dir: ""})
+	}
+
+	analyzers := make([]*PackagesAnalyzer, 0, len(modules))
+	for _, module := range modules {
+		analyzers = append(analyzers, a.packagesAnalyzerFor(repoPath, module.Dir))
+	}
+	return analyzers, nil
+}
+
+// analyzerForDir returns the analyzer of the module at dir within the repository at
+// repoPath, with the module context of its go.mod set on creation, so analyzers shared
+// by concurrent requests are never modified. The caller must hold the mutex.
+func (a *PackageAnalyzer) analyzerForDir(repoPath, dir string) *PackagesAnalyzer {
+	dir = filepath.Clean(dir)
+	if pa, exists := a.analyzers[dir]; exists {
+		return pa
+	}
+
+	pa := NewPackagesAnalyzer(dir, a.env)
+	pa.rootDir = filepath.Clean(repoPath)
+	pa.moduleInfo = a.moduleInfo(dir)
+	pa.loadTimeout = a.loadTimeout
+	pa.logger = a.logger
+	pa.deprecations = a.deprecations
+	if a.dependencyLoader != nil {
+		pa.dependencyLoader = a.dependencyLoader
+	} else if a.packagesAnalyzer != nil {
+		pa.dependencyLoader = a.packagesAnalyzer.dependencyLoader
+	}
+	a.analyzers[dir] = pa
+	return pa
+}
+
+// ReleaseRepository drops the loaded modules, cached analyses and deprecation notices
+// of a repository, for example when it is evicted from the cache
+func (a *PackageAnalyzer) ReleaseRepository(repoPath string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for dir := range a.analyzers {
+		if isWithinRepository(dir, repoPath) {
+			delete(a.analyzers, dir)
+		}
+	}
+	if a.analysisCache != nil {
+		a.analysisCache.Clear(repoPath)
+	}
+	a.deprecations.Release(repoPath)
+}
+
+// DropModuleLoads drops the loaded modules of the repository at repoPath, or of
+// every repository if repoPath is empty, so they are loaded and type-checked again
+// on next use. Analyses cached from them are dropped too. It returns the number of
+// modules dropped.
+func (a *PackageAnalyzer) DropModuleLoads(repoPath string) int {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.analysisCache != nil {
+		a.analysisCache.Clear(repoPath)
+	}
+
+	cleared := 0
+	for dir, pa := range a.analyzers {
+		if repoPath != "" && !isWithinRepository(dir, repoPath) {
+			continue
+		}
+		if pa.dropModuleLoad() {
+			cleared++
+		}
+	}
+	return cleared
+}
+
+// LoadedModules returns the number of modules whose packages are kept in memory
+func (a *PackageAnalyzer) LoadedModules() int {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	loaded := 0
+	for _, pa := range a.analyzers {
+		pa.loadMutex.Lock()
+		if pa.moduleLoad != nil {
+			loaded++
+		}
+		pa.loadMutex.Unlock()
+	}
+	return loaded
+}
+
+// isWithinRepository reports whether dir is the repository at repoPath or below it
+func isWithinRepository(dir, repoPath string) bool {
+	repoPath = filepath.Clean(repoPath)
+	return dir == repoPath || strings.HasPrefix(dir, repoPath+string(filepath.Separator))
+}
+
+// ImportPath returns the import path of the package at relDir, relative to the
+// repository root, within this module
+func (m *ModuleRoot) ImportPath(relDir string) string {
+	if relDir == m.Dir {
+		return m.Path
+	}
+	subPath := relDir
+	if m.Dir != "" {
+		subPath = strings.TrimPrefix(relDir, m.Dir+"/")
+	}
+	return m.Path + "/" + subPath
+}
+
+// moduleForDir returns the innermost module containing relDir, or nil
+func moduleForDir(modules []*ModuleRoot, relDir string) *ModuleRoot {
+	var found *ModuleRoot
+	for _, module := range modules {
+		if module.Dir == "" || relDir == module.Dir || strings.HasPrefix(relDir, module.Dir+"/") {
+			if found == nil || len(module.Dir) > len(found.Dir) {
+				found = module
+			}
+		}
+	}
+	return found
+}
@@ -0,0 +1,138 @@
+package analyzer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gonav/internal/testutil"
+)
+
+// multiModuleRepo is a repository with a root module, a nested sdk module required
+// through a replace directive, an independent nested tools module, and a test fixture
+// that is not a module of the repository
+var multiModuleRepo = map[string]string{
+	"go.mod": `module example.com/root
+
+go 1.21
+
+require example.com/root/sdk v0.0.0
+
+replace example.com/root/sdk => ./sdk
+`,
+	"main.go": `package main
+
+import "example.com/root/sdk/client"
+
+func main() {
+	client.Hello()
+}
+`,
+	"sdk/go.mod": "module example.com/root/sdk\n\ngo 1.21\n",
+	"sdk/client/client.go": `package client
+
+// Hello greets
+func Hello() string { return "hello" }
+`,
+	"tools/go.mod": "module example.com/root/tools\n\ngo 1.21\n",
+	"tools/gen/gen.go": `package gen
+
+func Generate() int { return helper() }
+
+func helper() int { return 1 }
+`,
+	"testdata/fixture/go.mod":     "module example.com/fixture\n\ngo 1.21\n",
+	"testdata/fixture/fixture.go": "package fixture\n",
+}
+
+func TestDiscoverModules(t *testing.T) {
+	repoDir := testutil.TempFiles(t, multiModuleRepo)
+	analyzer := New()
+
+	modules, err := analyzer.DiscoverModules(repoDir)
+	require.NoError(t, err)
+	require.Len(t, modules, 3)
+	assert.Equal(t, "example.com/root", modules[0].Path)
+	assert.Equal(t, "", modules[0].Dir)
+	assert.Equal(t, "example.com/root/sdk", modules[1].Path)
+	assert.Equal(t, "sdk", modules[1].Dir)
+	assert.Equal(t, "example.com/root/tools", modules[2].Path)
+	assert.Equal(t, "tools", modules[2].Dir)
+
+	assert.Equal(t, "tools", FindModuleDir(repoDir, "tools/gen"))
+	assert.Equal(t, "sdk", FindModuleDir(repoDir, "sdk"))
+	assert.Equal(t, "", FindModuleDir(repoDir, ""))
+	assert.Equal(t, "", FindModuleDir(repoDir, "."))
+	assert.Equal(t, "", FindModuleDir(repoDir, "testdata/fixture")) // Skipped like by repo.FindModules
+
+	// Module analyzers carry the context of their own go.mod from creation
+	analyzer.SetRepositoryContext(repoDir, nil)
+	assert.Equal(t, "example.com/root/tools", analyzer.packagesAnalyzerFor(repoDir, "tools").moduleInfo.ModulePath)
+	assert.Equal(t, "example.com/root", analyzer.packagesAnalyzerFor(repoDir, "").moduleInfo.ModulePath)
+
+	// Packages are grouped under the module that contains them
+	packages, err := analyzer.DiscoverPackages(repoDir)
+	require.NoError(t, err)
+	require.Contains(t, packages, "tools/gen")
+	assert.Equal(t, "example.com/root/tools", packages["tools/gen"].Module)
+	assert.Equal(t, "example.com/root/tools/gen", packages["tools/gen"].ImportPath)
+	require.Contains(t, packages, "sdk/client")
+	assert.Equal(t, "example.com/root/sdk", packages["sdk/client"].Module)
+	require.Contains(t, packages, "")
+	assert.Equal(t, "example.com/root", packages[""].ImportPath)
+}
+
+func TestAnalyzeNestedModule(t *testing.T) {
+	repoDir := testutil.TempFiles(t, multiModuleRepo)
+	analyzer := New()
+	analyzer.SetRepositoryContext(repoDir, nil)
+
+	// Packages of a nested module are loaded from that module
+	packageInfo, err := analyzer.AnalyzePackage(context.Background(), repoDir, "tools/gen")
+	require.NoError(t, err)
+	assert.Equal(t, "gen", packageInfo.Name)
+	assert.Equal(t, "example.com/root/tools/gen", packageInfo.Path)
+	require.Len(t, packageInfo.Files, 1)
+	assert.Equal(t, "tools/gen/gen.go", packageInfo.Files[0].Path)
+	assert.Contains(t, packageInfo.Symbols, "Generate")
+
+	fileInfo, err := analyzer.AnalyzeSingleFile(context.Background(), repoDir, "tools/gen/gen.go")
+	require.NoError(t, err)
+	var helperRef *Reference
+	for _, ref := range fileInfo.References {
+		if ref.Name == "helper" {
+			helperRef = ref
+		}
+	}
+	require.NotNil(t, helperRef)
+	require.NotNil(t, helperRef.Target)
+	assert.Equal(t, "tools/gen/gen.go", helperRef.Target.File)
+}
+
+func TestAnalyzeSiblingModuleReference(t *testing.T) {
+	repoDir := testutil.TempFiles(t, multiModuleRepo)
+	analyzer := New()
+	analyzer.SetRepositoryContext(repoDir, nil)
+
+	fileInfo, err := analyzer.AnalyzeSingleFile(context.Background(), repoDir, "main.go")
+	require.NoError(t, err)
+
+	var helloRef *Reference
+	for _, ref := range fileInfo.References {
+		if ref.Name == "Hello" {
+			helloRef = ref
+		}
+	}
+	require.NotNil(t, helloRef)
+	require.NotNil(t, helloRef.Target)
+
+	// The replace directive points into the repository, so the reference navigates
+	// to the sibling module's file instead of a separately downloaded module
+	assert.Equal(t, "sdk/client/client.go", helloRef.Target.File)
+	assert.Equal(t, 4, helloRef.Target.Line)
+	assert.Empty(t, helloRef.Target.Version)
+	assert.Equal(t, "example.com/root/sdk/client", helloRef.Target.ImportPath)
+	assert.False(t, helloRef.Target.IsStdLib)
+}
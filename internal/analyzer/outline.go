@@ -0,0 +1,246 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+)
+
+// Kinds of outline entries
+const (
+	OutlineFunction  = "function"
+	OutlineMethod    = "method"
+	OutlineStruct    = "struct"
+	OutlineInterface = "interface"
+	OutlineType      = "type" // Other type declarations and aliases
+	OutlineField     = "field"
+	OutlineConstant  = "constant"
+	OutlineVariable  = "variable"
+	OutlineClosure   = "closure"
+)
+
+// OutlineSymbol is an entry of the outline of a file, shaped as an LSP DocumentSymbol
+type OutlineSymbol struct {
+	Name           string           `json:"name"`
+	Detail         string           `json:"detail,omitempty"` // Signature or type, as written in the source
+	Kind           string           `json:"kind"`             // One of the Outline constants
+	Range          Range            `json:"range"`            // Whole declaration, from its keyword to its end
+	SelectionRange Range            `json:"selectionRange"`   // Name of the declaration
+	Children       []*OutlineSymbol `json:"children,omitempty"`
+}
+
+// ParseOutline parses the Go source src and returns its outline, for files that
+// cannot be analyzed otherwise. Declarations parsed before a syntax error are still
+// outlined, and nil is returned if none could be.
+func ParseOutline(filename string, src []byte) []*OutlineSymbol {
+	fset := token.NewFileSet()
+	file, _ := parser.ParseFile(fset, filename, src, parser.SkipObjectResolution)
+	if file == nil {
+		return nil
+	}
+	return extractOutline(file, fset)
+}
+
+// extractOutline returns the outline of file: its top-level declarations in source
+// order, with the fields and methods of types and the function literals of function
+// bodies and initializers as children. Methods are nested in their receiver type when
+// it is declared in the file, and listed as "(*T).Method" at the top level otherwise.
+func extractOutline(file *ast.File, fset *token.FileSet) []*OutlineSymbol {
+	o := &outliner{fset: fset, types: make(map[string]*OutlineSymbol)}
+
+	outline := make([]*OutlineSymbol, 0)
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.GenDecl:
+			outline = append(outline, o.genDecl(d)...)
+		case *ast.FuncDecl:
+			if d.Recv == nil {
+				outline = append(outline, o.function(d, d.Name.Name, OutlineFunction))
+			}
+		}
+	}
+
+	// Methods are placed once every type of the file is known
+	for _, decl := range file.Decls {
+		d, ok := decl.(*ast.FuncDecl)
+		if !ok || d.Recv == nil {
+			continue
+		}
+		if parent := o.types[receiverTypeName(d)]; parent != nil {
+			parent.Children = append(parent.Children, o.function(d, d.Name.Name, OutlineMethod))
+		} else {
+			outline = append(outline, o.function(d, methodDisplayName(d), OutlineMethod))
+		}
+	}
+	return outline
+}
+
+type outliner struct {
+	fset  *token.FileSet
+	types map[string]*OutlineSymbol // Type entries by name, to attach methods
+}
+
+// entry returns an outline entry spanning pos to end, named by ident
+func (o *outliner) entry(name string, ident *ast.Ident, kind, detail string, pos, end token.Pos) *OutlineSymbol {
+	return &OutlineSymbol{
+		Name:           name,
+		Detail:         detail,
+		Kind:           kind,
+		Range:          o.rangeOf(pos, end),
+		SelectionRange: o.rangeOf(ident.Pos(), ident.End()),
+	}
+}
+
+func (o *outliner) rangeOf(pos, end token.Pos) Range {
+	start, stop := o.fset.Position(pos), o.fset.Position(end)
+	return Range{
+		Start: Position{Line: start.Line, Column: start.Column},
+		End:   Position{Line: stop.Line, Column: stop.Column},
+	}
+}
+
+// function returns the entry of a function or method declaration
+func (o *outliner) function(d *ast.FuncDecl, name, kind string) *OutlineSymbol {
+	symbol := o.entry(name, d.Name, kind, types.ExprString(d.Type), d.Pos(), d.End())
+	if d.Body != nil {
+		symbol.Children = o.closures(d.Body)
+	}
+	return symbol
+}
+
+// genDecl returns the entries of the specs of an import, const, var or type
+// declaration. A declaration with a single unparenthesized spec spans the keyword.
+func (o *outliner) genDecl(d *ast.GenDecl) []*OutlineSymbol {
+	var symbols []*OutlineSymbol
+	for _, spec := range d.Specs {
+		pos, end := spec.Pos(), spec.End()
+		if !d.Lparen.IsValid() {
+			pos, end = d.Pos(), d.End()
+		}
+
+		switch s := spec.(type) {
+		case *ast.TypeSpec:
+			symbols = append(symbols, o.typeSpec(s, pos, end))
+		case *ast.ValueSpec:
+			kind := OutlineVariable
+			if d.Tok == token.CONST {
+				kind = OutlineConstant
+			}
+			detail := ""
+			if s.Type != nil {
+				detail = types.ExprString(s.Type)
+			}
+			for i, name := range s.Names {
+				if name.Name == "_" {
+					continue
+				}
+				symbol := o.entry(name.Name, name, kind, detail, pos, end)
+				if i < len(s.Values) {
+					symbol.Children = o.closures(s.Values[i])
+				}
+				symbols = append(symbols, symbol)
+			}
+		}
+	}
+	return symbols
+}
+
+// typeSpec returns the entry of a type declaration, with its fields or interface
+// methods as children
+func (o *outliner) typeSpec(s *ast.TypeSpec, pos, end token.Pos) *OutlineSymbol {
+	kind := OutlineType
+	var members *ast.FieldList
+	switch t := s.Type.(type) {
+	case *ast.StructType:
+		kind, members = OutlineStruct, t.Fields
+	case *ast.InterfaceType:
+		kind, members = OutlineInterface, t.Methods
+	}
+
+	detail := ""
+	if kind == OutlineType {
+		detail = types.ExprString(s.Type)
+	}
+	symbol := o.entry(s.Name.Name, s.Name, kind, detail, pos, end)
+	o.types[s.Name.Name] = symbol
+
+	if members == nil {
+		return symbol
+	}
+	for _, field := range members.List {
+		memberKind := OutlineField
+		if _, ok := field.Type.(*ast.FuncType); ok && kind == OutlineInterface {
+			memberKind = OutlineMethod
+		}
+		detail := types.ExprString(field.Type)
+		if len(field.Names) == 0 {
+			// Embedded field or interface, named after its type
+			ident := embeddedIdent(field.Type)
+			if ident == nil {
+				continue
+			}
+			symbol.Children = append(symbol.Children, o.entry(ident.Name, ident, memberKind, detail, field.Pos(), field.End()))
+			continue
+		}
+		for _, name := range field.Names {
+			symbol.Children = append(symbol.Children, o.entry(name.Name, name, memberKind, detail, field.Pos(), field.End()))
+		}
+	}
+	return symbol
+}
+
+// closures returns the entries of the function literals in node, nested literals as
+// children of their enclosing literal
+func (o *outliner) closures(node ast.Node) []*OutlineSymbol {
+	var symbols []*OutlineSymbol
+	ast.Inspect(node, func(n ast.Node) bool {
+		lit, ok := n.(*ast.FuncLit)
+		if !ok {
+			return true
+		}
+		symbols = append(symbols, &OutlineSymbol{
+			Name:           "func",
+			Detail:         types.ExprString(lit.Type),
+			Kind:           OutlineClosure,
+			Range:          o.rangeOf(lit.Pos(), lit.End()),
+			SelectionRange: o.rangeOf(lit.Type.Func, lit.Type.Func+token.Pos(len("func"))),
+			Children:       o.closures(lit.Body),
+		})
+		return false
+	})
+	return symbols
+}
+
+// embeddedIdent returns the type name of an embedded field, as T in *pkg.T[int]
+func embeddedIdent(expr ast.Expr) *ast.Ident {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.SelectorExpr:
+			return e.Sel
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e
+		default:
+			return nil
+		}
+	}
+}
+
+// methodDisplayName returns the name of a method qualified by its receiver, as
+// "(*T).Method" or "T.Method"
+func methodDisplayName(d *ast.FuncDecl) string {
+	recv := receiverTypeName(d)
+	if recv == "" {
+		return d.Name.Name
+	}
+	if _, ok := d.Recv.List[0].Type.(*ast.StarExpr); ok {
+		return "(*" + recv + ")." + d.Name.Name
+	}
+	return recv + "." + d.Name.Name
+}
@@ -0,0 +1,103 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const outlineSource = `package main
+
+import "fmt"
+
+// Limit is documented
+const Limit = 3
+
+var (
+	handler = func() {}
+	count   int
+)
+
+type Server struct {
+	fmt.Stringer
+	Addr string
+}
+
+type Handler interface {
+	Serve() error
+}
+
+type ID = string
+
+func (s *Server) Start() error {
+	run := func() {
+		defer func() {}()
+	}
+	run()
+	return nil
+}
+
+func main() {}
+
+func (o Other) Skip() {}
+`
+
+// outlineNames returns the names and kinds of entries, as "name:kind"
+func outlineNames(symbols []*OutlineSymbol) []string {
+	var names []string
+	for _, symbol := range symbols {
+		names = append(names, symbol.Name+":"+symbol.Kind)
+	}
+	return names
+}
+
+func TestOutline(t *testing.T) {
+	outline := ParseOutline("main.go", []byte(outlineSource))
+
+	// Methods of types declared in other files come last, qualified by their receiver
+	require.Equal(t, []string{
+		"Limit:constant", "handler:variable", "count:variable", "Server:struct", "Handler:interface",
+		"ID:type", "main:function", "Other.Skip:method",
+	}, outlineNames(outline))
+
+	limit := outline[0]
+	assert.Equal(t, Range{Start: Position{Line: 6, Column: 1}, End: Position{Line: 6, Column: 16}}, limit.Range, "the doc comment is excluded")
+	assert.Equal(t, Range{Start: Position{Line: 6, Column: 7}, End: Position{Line: 6, Column: 12}}, limit.SelectionRange)
+
+	handler := outline[1]
+	assert.Equal(t, Range{Start: Position{Line: 9, Column: 2}, End: Position{Line: 9, Column: 21}}, handler.Range, "grouped specs span themselves")
+	assert.Equal(t, []string{"func:closure"}, outlineNames(handler.Children))
+	assert.Equal(t, "int", outline[2].Detail)
+
+	server := outline[3]
+	assert.Equal(t, Range{Start: Position{Line: 13, Column: 1}, End: Position{Line: 16, Column: 2}}, server.Range)
+	assert.Equal(t, []string{"Stringer:field", "Addr:field", "Start:method"}, outlineNames(server.Children))
+	start := server.Children[2]
+	assert.Equal(t, "func() error", start.Detail)
+	assert.Equal(t, Range{Start: Position{Line: 24, Column: 1}, End: Position{Line: 30, Column: 2}}, start.Range)
+	assert.Equal(t, Range{Start: Position{Line: 24, Column: 18}, End: Position{Line: 24, Column: 23}}, start.SelectionRange)
+	require.Equal(t, []string{"func:closure"}, outlineNames(start.Children))
+	assert.Equal(t, []string{"func:closure"}, outlineNames(start.Children[0].Children), "nested function literals")
+
+	assert.Equal(t, []string{"Serve:method"}, outlineNames(outline[4].Children))
+	assert.Equal(t, "string", outline[5].Detail)
+}
+
+func TestOutlineInFileInfo(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "test.go"), []byte(scopeTreeSource), 0644))
+
+	fileInfo, err := New().AnalyzeSingleFile(context.Background(), tmpDir, "test.go")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"T:struct", "init:function", "init:function"}, outlineNames(fileInfo.Outline))
+	assert.Equal(t, []string{"Run:method"}, outlineNames(fileInfo.Outline[0].Children))
+
+	// Declarations before a syntax error are still outlined
+	outline := ParseOutline("broken.go", []byte("package p\n\nfunc ok() {}\n\nfunc broken( {\n"))
+	require.NotEmpty(t, outline)
+	assert.Equal(t, "ok:function", outlineNames(outline)[0])
+}
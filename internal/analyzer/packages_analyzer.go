@@ -1,26 +1,58 @@
 package analyzer
 
 import (
+	"context"
 	"fmt"
 	"go/ast"
 	"go/types"
 	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"golang.org/x/tools/go/packages"
+
+	"gonav/internal/flight"
 )
 
 // PackagesAnalyzer uses golang.org/x/tools/go/packages for robust package analysis
 type PackagesAnalyzer struct {
 	config           *packages.Config
+	rootDir          string            // Repository root; file paths are relative to it
 	moduleInfo       *ModuleInfo       // Module context for resolving external references
+	vendor           *VendorInfo       // Vendored modules, nil if the module is not vendored
+	vendorErr        error             // Why vendor/modules.txt could not be parsed, reported on load
 	dependencyLoader *DependencyLoader // Optional dependency loader for progressive enhancement
+	deprecations     *deprecationIndex // Deprecation notices of declarations, parsed from source
+	fieldParents     *fieldParentIndex // Types declaring the struct fields of loaded packages
+
+	loadMutex      sync.Mutex
+	moduleLoad     *ModuleLoad               // Packages of the whole module, loaded on first use
+	loadGeneration int                       // Incremented when the module load is dropped
+	loads          flight.Group[*ModuleLoad] // Module loads in flight, by generation
+	loadTimeout    time.Duration             // Maximum duration of packages.Load, 0 for no limit
+
+	logger *slog.Logger
 }
 
 // NewPackagesAnalyzer creates a new packages-based analyzer
 func NewPackagesAnalyzer(repoPath string, env []string) *PackagesAnalyzer {
+	// Vendored modules are analyzed from the vendor tree, without the module cache
+	var vendor *VendorInfo
+	var vendorErr error
+	if HasVendorModules(repoPath) {
+		info, err := ParseVendorModules(repoPath)
+		if err != nil {
+			vendorErr = err
+		} else {
+			vendor = info
+			env = vendorEnv(env)
+		}
+	}
+
 	return &PackagesAnalyzer{
 		config: &packages.Config{
 			Mode: packages.NeedName |
@@ -35,84 +67,101 @@ func NewPackagesAnalyzer(repoPath string, env []string) *PackagesAnalyzer {
 			Env:   env,
 			Tests: false, // We'll handle test files separately if needed
 		},
-		moduleInfo: nil, // Will be set when analyzing
+		rootDir:      repoPath,
+		moduleInfo:   nil, // Will be set when analyzing
+		vendor:       vendor,
+		vendorErr:    vendorErr,
+		deprecations: newDeprecationIndex(),
+		fieldParents: newFieldParentIndex(),
+		logger:       slog.Default(),
 	}
 }
 
+// SetLogger sets the logger receiving the analyzer's log records
+func (pa *PackagesAnalyzer) SetLogger(logger *slog.Logger) {
+	pa.logger = logger
+}
+
+// SetLoadTimeout bounds the duration of every packages.Load, 0 for no limit
+func (pa *PackagesAnalyzer) SetLoadTimeout(timeout time.Duration) {
+	pa.loadTimeout = timeout
+}
+
 // SetModuleContext sets the module context for resolving external references
 func (pa *PackagesAnalyzer) SetModuleContext(moduleInfo *ModuleInfo) {
 	pa.moduleInfo = moduleInfo
 }
 
 // AnalyzePackageWithPackages analyzes a package using golang.org/x/tools/go/packages
-func (pa *PackagesAnalyzer) AnalyzePackageWithPackages(packagePath string) (*PackageInfo, error) {
-	// Load the specific package
-	pattern := "./" + packagePath
-	if packagePath == "" {
-		pattern = "./..."
-	}
-
-	pkgs, err := packages.Load(pa.config, pattern)
+func (pa *PackagesAnalyzer) AnalyzePackageWithPackages(ctx context.Context, packagePath string) (*PackageInfo, error) {
+	// Packages are served from the module load, relative to the module being analyzed
+	pkg, err := pa.loadPackage(ctx, pa.moduleRelativePath(packagePath))
 	if err != nil {
 		return nil, fmt.Errorf("failed to load package %s: %w", packagePath, err)
 	}
-
-	if len(pkgs) == 0 {
-		return nil, fmt.Errorf("no packages found for pattern %s", pattern)
-	}
-
-	// For now, analyze the first package found
-	pkg := pkgs[0]
 	
 	// Check for errors in package loading
 	if len(pkg.Errors) > 0 {
 		// Log errors but continue with partial analysis
 		for _, err := range pkg.Errors {
-			fmt.Printf("Package loading warning: %v\n", err)
+			pa.logger.DebugContext(ctx, "Package loading error", "package", pkg.PkgPath, "error", err)
 		}
 	}
 
-	return pa.convertPackageToPackageInfo(pkg)
+	packageInfo, err := pa.convertPackageToPackageInfo(pkg)
+	if err != nil {
+		return nil, newAnalysisError(err, pkg)
+	}
+	return packageInfo, nil
 }
 
 // AnalyzeSingleFileWithPackages analyzes a single file using packages
-func (pa *PackagesAnalyzer) AnalyzeSingleFileWithPackages(filePath string) (*FileInfo, error) {
-	// First, determine which package this file belongs to
-	dir := filepath.Dir(filePath)
-	relativeDir, err := filepath.Rel(pa.config.Dir, dir)
+func (pa *PackagesAnalyzer) AnalyzeSingleFileWithPackages(ctx context.Context, filePath string) (*FileInfo, error) {
+	targetPkg, err := pa.loadPackageForFile(ctx, filePath)
 	if err != nil {
-		relativeDir = "."
+		return nil, err
 	}
-	
-	pattern := "./" + relativeDir
-	if relativeDir == "." {
-		pattern = "./..."
+
+	fileInfo, err := pa.convertPackageToFileInfo(targetPkg, filePath)
+	if err != nil {
+		return nil, newAnalysisError(err, targetPkg)
+	}
+	return fileInfo, nil
+}
+
+// loadPackageForFile returns the package containing filePath, relative to the repository root
+func (pa *PackagesAnalyzer) loadPackageForFile(ctx context.Context, filePath string) (*packages.Package, error) {
+	dir := filepath.ToSlash(filepath.Dir(filePath))
+	if dir == "." {
+		dir = ""
 	}
 
-	pkgs, err := packages.Load(pa.config, pattern)
+	pkg, err := pa.loadPackage(ctx, pa.moduleRelativePath(dir))
 	if err != nil {
 		return nil, fmt.Errorf("failed to load package for file %s: %w", filePath, err)
 	}
 
-	// Find the package containing our file
-	var targetPkg *packages.Package
-	for _, pkg := range pkgs {
-		for _, file := range pkg.CompiledGoFiles {
-			if strings.HasSuffix(file, filePath) {
-				targetPkg = pkg
-				break
-			}
-		}
-		if targetPkg != nil {
-			break
+	absPath := canonicalPath(filepath.Join(pa.rootDir, filepath.FromSlash(filePath)))
+	for _, file := range pkg.CompiledGoFiles {
+		if canonicalPath(file) == absPath {
+			return pkg, nil
 		}
 	}
+	return nil, newAnalysisError(fmt.Errorf("could not find package containing file %s", filePath), pkg)
+}
 
-	if targetPkg == nil {
-		return nil, fmt.Errorf("could not find package containing file %s", filePath)
+// moduleRelativePath converts a path relative to the repository root into a path
+// relative to the directory of the module being analyzed
+func (pa *PackagesAnalyzer) moduleRelativePath(repoRelPath string) string {
+	moduleDir, err := filepath.Rel(pa.rootDir, pa.config.Dir)
+	if err != nil || moduleDir == "." {
+		return repoRelPath
 	}
-
-	return pa.convertPackageToFileInfo(targetPkg, filePath)
+	moduleDir = filepath.ToSlash(moduleDir)
+	if repoRelPath == moduleDir {
+		return ""
+	}
+	return strings.TrimPrefix(repoRelPath, moduleDir+"/")
 }
 
 // convertPackageToPackageInfo converts a packages.Package to our PackageInfo format
@@ -126,7 +175,7 @@ func (pa *PackagesAnalyzer) convertPackageToPackageInfo(pkg *packages.Package) (
 
 	// Add files
 	for _, file := range pkg.CompiledGoFiles {
-		rel, err := filepath.Rel(pa.config.Dir, file)
+		rel, err := filepath.Rel(pa.rootDir, file)
 		if err != nil {
 			rel = file
 		}
@@ -153,8 +202,9 @@ func (pa *PackagesAnalyzer) convertPackageToFileInfo(pkg *packages.Package, targ
 	var targetFile *ast.File
 	var targetFileContent string
 	
+	absPath := canonicalPath(filepath.Join(pa.rootDir, filepath.FromSlash(targetFilePath)))
 	for i, file := range pkg.CompiledGoFiles {
-		if strings.HasSuffix(file, targetFilePath) {
+		if canonicalPath(file) == absPath {
 			if i < len(pkg.Syntax) {
 				targetFile = pkg.Syntax[i]
 			}
@@ -178,11 +228,22 @@ func (pa *PackagesAnalyzer) convertPackageToFileInfo(pkg *packages.Package, targ
 		Symbols:     make(map[string]*Symbol),
 		Scopes:      make([]*ScopeInfo, 0),
 		Definitions: make([]*Definition, 0),
+		Outline:     extractOutline(targetFile, pkg.Fset),
+		Diagnostics: fileDiagnostics(pkg, absPath),
 	}
 
 	// Extract symbols and references for this specific file
 	if pkg.TypesInfo != nil {
 		pa.extractFileSymbolsAndReferences(targetFile, pkg, fileInfo)
+		fileInfo.SemanticTokens = semanticTokens(targetFile, pkg.Fset, pkg.TypesInfo, func(obj types.Object) (bool, bool) {
+			path := objectPackagePath(obj)
+			if path == "" {
+				return true, true // Universe scope
+			}
+			return pa.isStandardLibraryImport(path), pa.referenceType(obj, pkg) == "external"
+		}, func(obj types.Object) bool {
+			return pa.deprecationNoticeOf(obj, pkg) != ""
+		})
 	}
 
 	return fileInfo, nil
@@ -205,8 +266,17 @@ func (pa *PackagesAnalyzer) extractSymbolsFromPackage(pkg *packages.Package) []S
 			symbols = append(symbols, *symbol)
 		}
 		
-		// If this is a type definition, also extract its methods with qualified names
+		// If this is a type definition, also extract its fields and methods with qualified names
 		if typeName, ok := obj.(*types.TypeName); ok {
+			if st, ok := typeName.Type().Underlying().(*types.Struct); ok && !typeName.IsAlias() {
+				parent := typeNameOf(typeName)
+				for i := 0; i < st.NumFields(); i++ {
+					if fieldSymbol := pa.convertObjectToSymbolWithParent(st.Field(i), pkg, parent); fieldSymbol != nil {
+						symbols = append(symbols, *fieldSymbol)
+					}
+				}
+			}
+
 			namedType, ok := typeName.Type().(*types.Named)
 			if ok {
 				// Extract methods from this named type using MethodSet for completeness
@@ -218,8 +288,9 @@ func (pa *PackagesAnalyzer) extractSymbolsFromPackage(pkg *packages.Package) []S
 					if methodObj, ok := method.(*types.Func); ok {
 						methodSymbol := pa.convertObjectToSymbol(methodObj, pkg)
 						if methodSymbol != nil {
-							// Create qualified method name: "TypeName.MethodName" for value receiver
-							qualifiedName := fmt.Sprintf("%s.%s", typeName.Name(), methodObj.Name())
+							// Create qualified method name: "TypeName.MethodName" for value receiver,
+							// with the type parameters of generic types, as in "List[T].Len"
+							qualifiedName := fmt.Sprintf("%s.%s", typeNameWithParams(namedType), methodObj.Name())
 							methodSymbol.Name = qualifiedName
 							symbols = append(symbols, *methodSymbol)
 						}
@@ -236,7 +307,7 @@ func (pa *PackagesAnalyzer) extractSymbolsFromPackage(pkg *packages.Package) []S
 							methodSymbol := pa.convertObjectToSymbol(methodObj, pkg)
 							if methodSymbol != nil {
 								// Create qualified method name: "(*TypeName).MethodName" for pointer receiver
-								qualifiedName := fmt.Sprintf("(*%s).%s", typeName.Name(), methodObj.Name())
+								qualifiedName := fmt.Sprintf("(*%s).%s", typeNameWithParams(namedType), methodObj.Name())
 								
 								// Check if we already added this method from value method set
 								found := false
@@ -261,18 +332,73 @@ func (pa *PackagesAnalyzer) extractSymbolsFromPackage(pkg *packages.Package) []S
 	return symbols
 }
 
-// extractFileSymbolsAndReferences extracts symbols and references for a specific file
+// extractFileSymbolsAndReferences extracts symbols and references for a specific file,
+// along with its scope tree and definitions. References to objects defined in the file
+// are local and linked to their definition, the others are internal to the repository
+// or external.
 func (pa *PackagesAnalyzer) extractFileSymbolsAndReferences(file *ast.File, pkg *packages.Package, fileInfo *FileInfo) {
 	fset := pkg.Fset
+	info := pkg.TypesInfo
+
+	tree := buildScopeTree(file, fset, info)
+	fileInfo.Scopes = tree.Scopes()
+
+	byObject := make(map[types.Object]*Definition)
+	addDefinition := func(ident *ast.Ident, kind, scopeID, signature string) *Definition {
+		pos := fset.Position(ident.Pos())
+		def := &Definition{
+			ID:        definitionID(ident.Name, pos),
+			Name:      ident.Name,
+			Type:      kind,
+			Line:      pos.Line,
+			Column:    pos.Column,
+			ScopeID:   scopeID,
+			Signature: signature,
+		}
+		fileInfo.Definitions = append(fileInfo.Definitions, def)
+		return def
+	}
+
+	// Uses are linked once all definitions are known, as they may precede them
+	type use struct {
+		ref *Reference
+		obj types.Object
+	}
+	var uses []use
+
+	// Selectors of promoted fields and methods, by selected identifier
+	promoted := make(map[*ast.Ident][]*types.Var)
 
 	// Walk the AST to find identifiers and their usage
 	ast.Inspect(file, func(n ast.Node) bool {
 		switch node := n.(type) {
+		case *ast.SelectorExpr:
+			if sel, ok := info.Selections[node]; ok {
+				if path := promotionPath(sel); len(path) > 0 {
+					promoted[node.Sel] = path
+				}
+			}
+
+		case *ast.TypeSwitchStmt:
+			// The variable of "switch v := x.(type)" is declared once per clause
+			ident, typ, objects := typeSwitchVar(node, info)
+			if ident == nil {
+				break
+			}
+			signature := "var " + ident.Name
+			if typ != nil {
+				signature += " " + typ.String()
+			}
+			def := addDefinition(ident, "variable", tree.innermost(ident.Pos()), signature)
+			for _, obj := range objects {
+				byObject[obj] = def
+			}
+
 		case *ast.Ident:
 			pos := fset.Position(node.Pos())
 			
 			// Check if this identifier has type information
-			if obj, ok := pkg.TypesInfo.Uses[node]; ok {
+			if obj, ok := info.Uses[node]; ok {
 				// This is a use of an identifier
 				ref := &Reference{
 					Name:   node.Name,
@@ -283,38 +409,89 @@ func (pa *PackagesAnalyzer) extractFileSymbolsAndReferences(file *ast.File, pkg
 				
 				// Try to create target symbol
 				if targetSymbol := pa.convertObjectToSymbol(obj, pkg); targetSymbol != nil {
+					// Uses of generic functions and types, as in Map[int] or List[int],
+					// also record the instantiated signature
+					if inst, ok := info.Instances[node]; ok && targetSymbol.InstanceSignature == "" {
+						targetSymbol.InstanceSignature = instanceSignature(obj, inst)
+					}
 					ref.Target = targetSymbol
 				}
+				for _, embedded := range promoted[node] {
+					if embeddedSymbol := pa.convertObjectToSymbol(embedded, pkg); embeddedSymbol != nil {
+						ref.Promotion = append(ref.Promotion, embeddedSymbol)
+					}
+				}
 				
 				fileInfo.References = append(fileInfo.References, ref)
+				uses = append(uses, use{ref: ref, obj: obj})
 			}
 			
-			if obj, ok := pkg.TypesInfo.Defs[node]; ok && obj != nil {
+			if obj, ok := info.Defs[node]; ok && obj != nil {
 				// This is a definition of an identifier
 				symbol := pa.convertObjectToSymbol(obj, pkg)
 				if symbol != nil {
 					fileInfo.Symbols[symbol.Name] = symbol
 				}
 				
-				def := &Definition{
-					ID:     fmt.Sprintf("def_%s_%d", node.Name, pos.Line),
-					Name:   node.Name,
-					Type:   pa.getObjectKind(obj),
-					Line:   pos.Line,
-					Column: pos.Column,
-					ScopeID: "/", // Simplified for now
-					Signature: obj.String(),
-				}
-				fileInfo.Definitions = append(fileInfo.Definitions, def)
+				byObject[obj] = addDefinition(node, pa.getObjectKind(obj), tree.ScopeID(obj, node.Pos()), obj.String())
 			}
 		}
 		return true
 	})
+
+	linkShadowing(file, fset, info, byObject, func(filename string) string {
+		if relPath, err := filepath.Rel(pa.rootDir, filename); err == nil {
+			return filepath.ToSlash(relPath)
+		}
+		return filename
+	})
+
+	for _, u := range uses {
+		if def := byObject[genericOrigin(u.obj)]; def != nil {
+			u.ref.Type = "local"
+			u.ref.DefinitionID = def.ID
+		} else {
+			u.ref.Type = pa.referenceType(u.obj, pkg)
+		}
+	}
+}
+
+// referenceType classifies a reference to obj, which is not defined in the file being
+// analyzed: "internal" if obj is declared in the repository, "external" if it is
+// declared in a dependency, the standard library or the universe scope. Package names
+// are classified by the package they import.
+func (pa *PackagesAnalyzer) referenceType(obj types.Object, pkg *packages.Package) string {
+	if pkgName, ok := obj.(*types.PkgName); ok {
+		imported := pkg.Imports[pkgName.Imported().Path()]
+		if imported != nil && len(imported.GoFiles) > 0 && pa.inRepository(imported.GoFiles[0]) {
+			return "internal"
+		}
+		return "external"
+	}
+	if obj.Pkg() == nil {
+		return "external"
+	}
+	if obj.Pkg().Path() == pkg.PkgPath || pa.inRepository(pkg.Fset.Position(obj.Pos()).Filename) {
+		return "internal"
+	}
+	return "external"
 }
 
-// getQualifiedMethodName returns the qualified method name if obj is a method, otherwise returns obj.Name()
+// inRepository reports whether filename is inside the repository, including the
+// vendor tree and sibling modules replaced by a local path
+func (pa *PackagesAnalyzer) inRepository(filename string) bool {
+	if filename == "" {
+		return false
+	}
+	relPath, err := filepath.Rel(pa.rootDir, filename)
+	return err == nil && !strings.HasPrefix(relPath, "..")
+}
+
+// getQualifiedMethodName returns the qualified method name if obj is a method, otherwise returns obj.Name().
+// Methods of generic types are named after the generic receiver, as in "(*List[T]).Push".
 func (pa *PackagesAnalyzer) getQualifiedMethodName(obj types.Object) string {
 	if fn, ok := obj.(*types.Func); ok {
+		fn = fn.Origin()
 		// Check if this is a method (has a receiver)
 		sig := fn.Type().(*types.Signature)
 		if recv := sig.Recv(); recv != nil {
@@ -323,13 +500,13 @@ func (pa *PackagesAnalyzer) getQualifiedMethodName(obj types.Object) string {
 			// Handle pointer receiver
 			if ptr, ok := recvType.(*types.Pointer); ok {
 				if named, ok := ptr.Elem().(*types.Named); ok {
-					return fmt.Sprintf("(*%s).%s", named.Obj().Name(), fn.Name())
+					return fmt.Sprintf("(*%s).%s", typeNameWithParams(named), fn.Name())
 				}
 			}
 			
 			// Handle value receiver
 			if named, ok := recvType.(*types.Named); ok {
-				return fmt.Sprintf("%s.%s", named.Obj().Name(), fn.Name())
+				return fmt.Sprintf("%s.%s", typeNameWithParams(named), fn.Name())
 			}
 		}
 	}
@@ -340,28 +517,51 @@ func (pa *PackagesAnalyzer) getQualifiedMethodName(obj types.Object) string {
 
 // convertObjectToSymbol converts a types.Object to our Symbol format
 func (pa *PackagesAnalyzer) convertObjectToSymbol(obj types.Object, pkg *packages.Package) *Symbol {
+	parent := ""
+	if v, ok := obj.(*types.Var); ok && v.IsField() {
+		parent = pa.fieldParents.Parent(v)
+	}
+	return pa.convertObjectToSymbolWithParent(obj, pkg, parent)
+}
+
+// convertObjectToSymbolWithParent converts obj, a struct field of the type named
+// parent if parent is not empty, to our Symbol format
+func (pa *PackagesAnalyzer) convertObjectToSymbolWithParent(obj types.Object, pkg *packages.Package, parent string) *Symbol {
 	if obj == nil {
 		return nil
 	}
 
+	// Methods and fields of instantiated types navigate to their generic declaration
+	instance := ""
+	if origin := genericOrigin(obj); origin != obj {
+		instance = obj.String()
+		obj = origin
+	}
+
 	pos := pkg.Fset.Position(obj.Pos())
 	
 	// Handle file path - packages provides position info for external symbols too
 	file := ""
+	inRepository := false
+	vendorModule, vendorVersion, vendored := "", "", false
 	if pos.IsValid() && pos.Filename != "" {
 		if obj.Pkg() != nil && obj.Pkg().Path() == pkg.PkgPath {
 			// For current package symbols, use relative path
-			if relPath, err := filepath.Rel(pa.config.Dir, pos.Filename); err == nil {
+			if relPath, err := filepath.Rel(pa.rootDir, pos.Filename); err == nil {
 				file = filepath.ToSlash(relPath)
 			}
 		} else {
 			// For external symbols, we need to distinguish between same-repo and cross-repo
 			filename := pos.Filename
 			
-			// Check if this is from the same repository by checking if the path is within pa.config.Dir
-			if relPath, err := filepath.Rel(pa.config.Dir, filename); err == nil && !strings.HasPrefix(relPath, "..") {
+			// Check if this is from the same repository by checking if the path is within pa.rootDir.
+			// This includes sibling modules replaced by a local path inside the repository.
+			if pa.inRepository(filename) {
 				// Same repository, different package - use relative path
+				relPath, _ := filepath.Rel(pa.rootDir, filename)
 				file = filepath.ToSlash(relPath)
+				inRepository = true
+				vendorModule, vendorVersion, vendored = pa.vendoredModule(filename)
 			} else {
 				// Different repository - extract relative path within target repository
 				file = pa.extractRelativeFilePathFromCache(filename)
@@ -384,20 +584,38 @@ func (pa *PackagesAnalyzer) convertObjectToSymbol(obj types.Object, pkg *package
 	isStdLib := pa.isStandardLibraryImport(importPath)
 	
 	symbol := &Symbol{
-		Name:       pa.getQualifiedMethodName(obj), // Use qualified name for methods
-		Type:       pa.getObjectKind(obj),
-		File:       file,
-		Line:       pos.Line,
-		Column:     pos.Column,
-		Package:    packageName,
-		Signature:  obj.String(),
-		ImportPath: importPath,
-		IsExternal: isExternal,
-		IsStdLib:   isStdLib,
+		Name:              pa.getQualifiedMethodName(obj), // Use qualified name for methods
+		Type:              pa.getObjectKind(obj),
+		File:              file,
+		Line:              pos.Line,
+		Column:            pos.Column,
+		Package:           packageName,
+		Signature:         obj.String(),
+		ImportPath:        importPath,
+		IsExternal:        isExternal,
+		IsStdLib:          isStdLib,
+		InstanceSignature: instance,
 	}
 	
-	// For external references, resolve module@version format
-	if isExternal && pa.moduleInfo != nil && !isStdLib {
+	symbol.Deprecated = pa.deprecationNoticeOf(obj, pkg)
+
+	// Fields are named after the type declaring them, as methods are
+	if parent != "" {
+		symbol.Name = parent + "." + obj.Name()
+		symbol.Parent = parent
+	}
+
+	// Vendored symbols are navigated to in the vendor tree, labeled with the module
+	// and version recorded in vendor/modules.txt
+	if vendored {
+		symbol.Module = vendorModule
+		symbol.Version = vendorVersion
+		symbol.IsVendored = true
+	}
+	
+	// For external references, resolve module@version format. Symbols found inside the
+	// repository are navigated to directly, even when their module is a required dependency.
+	if isExternal && pa.moduleInfo != nil && !isStdLib && !inRepository {
 		resolvedPath, version := pa.moduleInfo.ResolveImport(importPath)
 		symbol.ImportPath = resolvedPath
 		symbol.Version = version
@@ -415,10 +633,16 @@ func (pa *PackagesAnalyzer) convertObjectToSymbol(obj types.Object, pkg *package
 
 // getObjectKind returns the kind of a types.Object
 func (pa *PackagesAnalyzer) getObjectKind(obj types.Object) string {
-	switch obj.(type) {
+	if isTypeParam(obj) {
+		return "typeparam"
+	}
+	switch o := obj.(type) {
 	case *types.Func:
 		return "function"
 	case *types.Var:
+		if o.IsField() {
+			return "field"
+		}
 		return "variable"
 	case *types.Const:
 		return "constant"
@@ -1,21 +1,14 @@
 package analyzer
 
 import (
+	"context"
 	"fmt"
-	"path/filepath"
-
-	"golang.org/x/tools/go/packages"
 )
 
 // AnalyzePackageWithQuality performs package analysis and returns enhanced results with quality assessment
-func (pa *PackagesAnalyzer) AnalyzePackageWithQuality(packagePath string) (*EnhancedAnalysisResponse, error) {
-	// Load the specific package
-	pattern := "./" + packagePath
-	if packagePath == "" {
-		pattern = "./..."
-	}
-
-	pkgs, err := packages.Load(pa.config, pattern)
+func (pa *PackagesAnalyzer) AnalyzePackageWithQuality(ctx context.Context, packagePath string) (*EnhancedAnalysisResponse, error) {
+	// Load the specific package from the module load
+	pkg, err := pa.loadPackage(ctx, pa.moduleRelativePath(packagePath))
 	if err != nil {
 		return &EnhancedAnalysisResponse{
 			Quality: &AnalysisQuality{
@@ -29,33 +22,20 @@ func (pa *PackagesAnalyzer) AnalyzePackageWithQuality(packagePath string) (*Enha
 			},
 		}, fmt.Errorf("failed to load package %s: %w", packagePath, err)
 	}
-
-	if len(pkgs) == 0 {
-		return &EnhancedAnalysisResponse{
-			Quality: &AnalysisQuality{
-				IsComplete:           false,
-				AnalysisMode:         AnalysisModeFailed,
-				EnhancementAvailable: false,
-				QualityScore:         0.0,
-			},
-		}, fmt.Errorf("no packages found for pattern %s", pattern)
-	}
-
-	pkg := pkgs[0]
 	
 	// Assess analysis quality
 	quality := AssessAnalysisQuality(pkg)
 	
 	// Log quality information
-	fmt.Printf("Analysis quality for %s: mode=%s, score=%.2f, missing_deps=%d\n", 
-		pkg.PkgPath, quality.AnalysisMode, quality.QualityScore, len(quality.MissingDependencies))
+	pa.logger.DebugContext(ctx, "Analysis quality", "package", pkg.PkgPath, "mode", quality.AnalysisMode,
+		"score", quality.QualityScore, "missing_dependencies", len(quality.MissingDependencies))
 	
 	// Convert to package info
 	packageInfo, err := pa.convertPackageToPackageInfo(pkg)
 	if err != nil {
 		return &EnhancedAnalysisResponse{
 			Quality: quality,
-		}, err
+		}, newAnalysisError(err, pkg)
 	}
 
 	response := &EnhancedAnalysisResponse{
@@ -72,20 +52,9 @@ func (pa *PackagesAnalyzer) AnalyzePackageWithQuality(packagePath string) (*Enha
 }
 
 // AnalyzeSingleFileWithQuality performs file analysis and returns enhanced results with quality assessment
-func (pa *PackagesAnalyzer) AnalyzeSingleFileWithQuality(filePath string) (*EnhancedAnalysisResponse, error) {
-	// First, determine which package this file belongs to
-	dir := filepath.Dir(filePath)
-	relativeDir, err := filepath.Rel(pa.config.Dir, dir)
-	if err != nil {
-		relativeDir = "."
-	}
-	
-	pattern := "./" + relativeDir
-	if relativeDir == "." {
-		pattern = "./..."
-	}
-
-	pkgs, err := packages.Load(pa.config, pattern)
+func (pa *PackagesAnalyzer) AnalyzeSingleFileWithQuality(ctx context.Context, filePath string) (*EnhancedAnalysisResponse, error) {
+	// Find the package containing our file in the module load
+	targetPkg, err := pa.loadPackageForFile(ctx, filePath)
 	if err != nil {
 		return &EnhancedAnalysisResponse{
 			Quality: &AnalysisQuality{
@@ -97,47 +66,22 @@ func (pa *PackagesAnalyzer) AnalyzeSingleFileWithQuality(filePath string) (*Enha
 					{Error: err.Error(), Severity: "error"},
 				},
 			},
-		}, fmt.Errorf("failed to load package for file %s: %w", filePath, err)
-	}
-
-	// Find the package containing our file
-	var targetPkg *packages.Package
-	for _, pkg := range pkgs {
-		for _, file := range pkg.CompiledGoFiles {
-			if filepath.Base(file) == filepath.Base(filePath) {
-				targetPkg = pkg
-				break
-			}
-		}
-		if targetPkg != nil {
-			break
-		}
-	}
-
-	if targetPkg == nil {
-		return &EnhancedAnalysisResponse{
-			Quality: &AnalysisQuality{
-				IsComplete:           false,
-				AnalysisMode:         AnalysisModeFailed,
-				EnhancementAvailable: false,
-				QualityScore:         0.0,
-			},
-		}, fmt.Errorf("could not find package containing file %s", filePath)
+		}, err
 	}
 
 	// Assess analysis quality
 	quality := AssessAnalysisQuality(targetPkg)
 	
 	// Log quality information
-	fmt.Printf("File analysis quality for %s: mode=%s, score=%.2f, missing_deps=%d\n", 
-		filePath, quality.AnalysisMode, quality.QualityScore, len(quality.MissingDependencies))
+	pa.logger.DebugContext(ctx, "File analysis quality", "file", filePath, "mode", quality.AnalysisMode,
+		"score", quality.QualityScore, "missing_dependencies", len(quality.MissingDependencies))
 	
 	// Convert to file info
 	fileInfo, err := pa.convertPackageToFileInfo(targetPkg, filePath)
 	if err != nil {
 		return &EnhancedAnalysisResponse{
 			Quality: quality,
-		}, err
+		}, newAnalysisError(err, targetPkg)
 	}
 
 	response := &EnhancedAnalysisResponse{
@@ -180,6 +124,6 @@ func (pa *PackagesAnalyzer) TriggerDependencyLoading(enhancementToken string) er
 	// 3. Update loading status as dependencies are resolved
 	// 4. Optionally notify when enhancement is ready
 	
-	fmt.Printf("Dependency loading triggered for token: %s\n", enhancementToken)
+	pa.logger.Info("Dependency loading triggered", "token", enhancementToken)
 	return nil
 }
\ No newline at end of file
@@ -1,6 +1,7 @@
 package analyzer
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
@@ -8,6 +9,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"gonav/internal/testutil"
 )
 
 func TestPackagesAnalyzer_Basic(t *testing.T) {
@@ -51,7 +54,7 @@ go 1.21
 	require.NotNil(t, packagesAnalyzer)
 
 	// Test package analysis
-	packageInfo, err := packagesAnalyzer.AnalyzePackageWithPackages("")
+	packageInfo, err := packagesAnalyzer.AnalyzePackageWithPackages(context.Background(), "")
 	require.NoError(t, err)
 	require.NotNil(t, packageInfo)
 
@@ -70,7 +73,7 @@ go 1.21
 	assert.True(t, found, "Should have found main.go file")
 
 	// Test single file analysis
-	fileInfo, err := packagesAnalyzer.AnalyzeSingleFileWithPackages("main.go")
+	fileInfo, err := packagesAnalyzer.AnalyzeSingleFileWithPackages(context.Background(), "main.go")
 	require.NoError(t, err)
 	require.NotNil(t, fileInfo)
 
@@ -82,6 +85,76 @@ go 1.21
 	assert.Greater(t, len(fileInfo.Symbols), 0, "Should have found symbols")
 }
 
+func TestPackagesAnalyzer_ReferenceTypes(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/refs\n\ngo 1.21\n",
+		"util/util.go": `package util
+
+func Helper() int { return 1 }
+`,
+		"main.go": `package main
+
+import (
+	"fmt"
+
+	"example.com/refs/util"
+)
+
+func main() {
+	total := util.Helper()
+	for i := range 3 {
+		total += i + other
+	}
+	fmt.Println(total, len("x"))
+}
+`,
+		"other.go": `package main
+
+var other = 2
+`,
+	}
+	testutil.WriteFiles(t, tempDir, files)
+
+	fileInfo, err := NewPackagesAnalyzer(tempDir, nil).AnalyzeSingleFileWithPackages(context.Background(), "main.go")
+	require.NoError(t, err)
+
+	definitions := make(map[string]*Definition)
+	for _, def := range fileInfo.Definitions {
+		definitions[def.Name] = def
+	}
+	require.Contains(t, definitions, "total")
+	require.Contains(t, definitions, "i")
+	assert.Equal(t, "/main", definitions["total"].ScopeID)
+	assert.Equal(t, "/main/range_1", definitions["i"].ScopeID)
+	assert.Equal(t, "/", definitions["main"].ScopeID)
+
+	scopeIDs := make([]string, 0, len(fileInfo.Scopes))
+	for _, scope := range fileInfo.Scopes {
+		scopeIDs = append(scopeIDs, scope.ID)
+	}
+	assert.Equal(t, []string{"/main", "/main/range_1"}, scopeIDs)
+
+	refTypes := make(map[string]string)
+	for _, ref := range fileInfo.References {
+		assert.NotEmpty(t, ref.Type, "reference %s at %d:%d", ref.Name, ref.Line, ref.Column)
+		refTypes[ref.Name] = ref.Type
+		if ref.Type == "local" {
+			assert.Equal(t, definitions[ref.Name].ID, ref.DefinitionID, "reference %s at %d:%d", ref.Name, ref.Line, ref.Column)
+		} else {
+			assert.Empty(t, ref.DefinitionID)
+		}
+	}
+	assert.Equal(t, "local", refTypes["total"])
+	assert.Equal(t, "local", refTypes["i"])
+	assert.Equal(t, "internal", refTypes["other"], "same package, other file")
+	assert.Equal(t, "internal", refTypes["Helper"], "other package of the repository")
+	assert.Equal(t, "external", refTypes["Println"], "standard library")
+	assert.Equal(t, "external", refTypes["len"], "builtin")
+	assert.Equal(t, "internal", refTypes["util"])
+	assert.Equal(t, "external", refTypes["fmt"])
+}
+
 func TestPackagesAnalyzer_WithStandardAnalyzer_Compatibility(t *testing.T) {
 	// Create a temporary directory with a simple Go package
 	tempDir, err := os.MkdirTemp("", "compat-test")
@@ -114,13 +187,13 @@ go 1.21
 	
 	// Standard analyzer
 	standardAnalyzer := New()
-	standardPackageInfo, err := standardAnalyzer.AnalyzePackage(tempDir, "")
+	standardPackageInfo, err := standardAnalyzer.AnalyzePackage(context.Background(), tempDir, "")
 	require.NoError(t, err)
 
 	// Packages analyzer
 	packagesAnalyzer := New()
 	packagesAnalyzer.SetRepositoryContext(tempDir, nil)
-	packagesPackageInfo, err := packagesAnalyzer.AnalyzePackage(tempDir, "")
+	packagesPackageInfo, err := packagesAnalyzer.AnalyzePackage(context.Background(), tempDir, "")
 	require.NoError(t, err)
 
 	// Both should identify the same package name
@@ -185,7 +258,7 @@ go 1.21
 	require.NotNil(t, packagesAnalyzer)
 
 	// Analyze the package
-	packageInfo, err := packagesAnalyzer.AnalyzePackageWithPackages("")
+	packageInfo, err := packagesAnalyzer.AnalyzePackageWithPackages(context.Background(), "")
 	require.NoError(t, err)
 	require.NotNil(t, packageInfo)
 
@@ -201,7 +274,7 @@ func TestPackagesAnalyzer_ErrorHandling(t *testing.T) {
 	// Test error handling for non-existent directory
 	packagesAnalyzer := NewPackagesAnalyzer("/nonexistent/path", nil)
 	
-	_, err := packagesAnalyzer.AnalyzePackageWithPackages("")
+	_, err := packagesAnalyzer.AnalyzePackageWithPackages(context.Background(), "")
 	assert.Error(t, err, "Should fail for non-existent path")
 	
 	// Test error handling for invalid package path
@@ -211,7 +284,7 @@ func TestPackagesAnalyzer_ErrorHandling(t *testing.T) {
 	
 	packagesAnalyzer = NewPackagesAnalyzer(tempDir, nil)
 	
-	_, err = packagesAnalyzer.AnalyzePackageWithPackages("nonexistent/package")
+	_, err = packagesAnalyzer.AnalyzePackageWithPackages(context.Background(), "nonexistent/package")
 	assert.Error(t, err, "Should fail for non-existent package")
 }
 
@@ -316,7 +389,7 @@ go 1.21
 	require.NotNil(t, packagesAnalyzer)
 
 	// Analyze the main file
-	fileInfo, err := packagesAnalyzer.AnalyzeSingleFileWithPackages("main.go")
+	fileInfo, err := packagesAnalyzer.AnalyzeSingleFileWithPackages(context.Background(), "main.go")
 	require.NoError(t, err)
 	require.NotNil(t, fileInfo)
 
@@ -1,6 +1,7 @@
 package analyzer
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
@@ -50,7 +51,7 @@ func main() {
 	require.NotNil(t, packagesAnalyzer)
 
 	// Analyze with quality assessment
-	response, err := packagesAnalyzer.AnalyzePackageWithQuality("")
+	response, err := packagesAnalyzer.AnalyzePackageWithQuality(context.Background(), "")
 	require.NoError(t, err)
 	require.NotNil(t, response)
 
@@ -112,7 +113,7 @@ func main() {
 	require.NotNil(t, packagesAnalyzer)
 
 	// Analyze with quality assessment
-	response, err := packagesAnalyzer.AnalyzePackageWithQuality("")
+	response, err := packagesAnalyzer.AnalyzePackageWithQuality(context.Background(), "")
 	require.NoError(t, err)
 	require.NotNil(t, response)
 
@@ -180,7 +181,7 @@ func example() {
 	require.NotNil(t, packagesAnalyzer)
 
 	// Analyze single file with quality assessment
-	response, err := packagesAnalyzer.AnalyzeSingleFileWithQuality("example.go")
+	response, err := packagesAnalyzer.AnalyzeSingleFileWithQuality(context.Background(), "example.go")
 	require.NoError(t, err)
 	require.NotNil(t, response)
 
@@ -1,8 +1,13 @@
 package analyzer
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"time"
+
+	"gonav/internal/flight"
+	"gonav/internal/logging"
 )
 
 // RevisionAnalyzer combines packages analysis with revision-based caching and progressive enhancement
@@ -10,6 +15,8 @@ type RevisionAnalyzer struct {
 	packagesAnalyzer *PackagesAnalyzer
 	cache            *AnalysisCache
 	dependencyQueue  *DependencyQueue
+	analyses         flight.Group[*CachedAnalysis] // Analyses in flight by cache key
+	logger           *slog.Logger
 	
 	// Configuration
 	repoPath string
@@ -33,10 +40,14 @@ type RevisionAnalysisResponse struct {
 
 // NewRevisionAnalyzer creates a new revision-based analyzer
 func NewRevisionAnalyzer(repoPath string, env []string, queueConfig DependencyQueueConfig) *RevisionAnalyzer {
-	dependencyChecker := &SimpleDependencyChecker{}
-	
+	logger := logging.OrDefault(queueConfig.Logger)
+	dependencyChecker := &SimpleDependencyChecker{Logger: logger}
+	packagesAnalyzer := NewPackagesAnalyzer(repoPath, env)
+	packagesAnalyzer.SetLogger(logger)
+
 	return &RevisionAnalyzer{
-		packagesAnalyzer: NewPackagesAnalyzer(repoPath, env),
+		packagesAnalyzer: packagesAnalyzer,
+		logger:          logger,
 		cache:           NewAnalysisCache(dependencyChecker),
 		dependencyQueue: NewDependencyQueue(queueConfig),
 		repoPath:        repoPath,
@@ -45,32 +56,32 @@ func NewRevisionAnalyzer(repoPath string, env []string, queueConfig DependencyQu
 }
 
 // AnalyzePackage performs revision-based package analysis
-func (ra *RevisionAnalyzer) AnalyzePackage(packagePath, clientRevision string) (*RevisionAnalysisResponse, error) {
+func (ra *RevisionAnalyzer) AnalyzePackage(ctx context.Context, packagePath, clientRevision string) (*RevisionAnalysisResponse, error) {
 	key := CacheKey{
 		Type:        CacheKeyTypePackage,
 		PackagePath: packagePath,
 	}
 	
-	return ra.analyzeWithCache(key, clientRevision, func() (*CachedAnalysis, error) {
-		return ra.performPackageAnalysis(packagePath)
+	return ra.analyzeWithCache(ctx, key, clientRevision, func(ctx context.Context) (*CachedAnalysis, error) {
+		return ra.performPackageAnalysis(ctx, packagePath)
 	})
 }
 
 // AnalyzeFile performs revision-based file analysis
-func (ra *RevisionAnalyzer) AnalyzeFile(packagePath, filePath, clientRevision string) (*RevisionAnalysisResponse, error) {
+func (ra *RevisionAnalyzer) AnalyzeFile(ctx context.Context, packagePath, filePath, clientRevision string) (*RevisionAnalysisResponse, error) {
 	key := CacheKey{
 		Type:        CacheKeyTypeFile,
 		PackagePath: packagePath,
 		FilePath:    filePath,
 	}
 	
-	return ra.analyzeWithCache(key, clientRevision, func() (*CachedAnalysis, error) {
-		return ra.performFileAnalysis(filePath)
+	return ra.analyzeWithCache(ctx, key, clientRevision, func(ctx context.Context) (*CachedAnalysis, error) {
+		return ra.performFileAnalysis(ctx, filePath)
 	})
 }
 
 // analyzeWithCache implements the core revision-based analysis logic
-func (ra *RevisionAnalyzer) analyzeWithCache(key CacheKey, clientRevision string, analyzer func() (*CachedAnalysis, error)) (*RevisionAnalysisResponse, error) {
+func (ra *RevisionAnalyzer) analyzeWithCache(ctx context.Context, key CacheKey, clientRevision string, analyzer func(ctx context.Context) (*CachedAnalysis, error)) (*RevisionAnalysisResponse, error) {
 	// Step 1: Check cache
 	cached, cacheResult := ra.cache.Get(key, clientRevision)
 	
@@ -91,7 +102,7 @@ func (ra *RevisionAnalyzer) analyzeWithCache(key CacheKey, clientRevision string
 		// First request or returning cached version
 		// Check if we should trigger dependency loading
 		if !cached.IsComplete && !ra.dependencyQueue.IsActive(key) {
-			ra.triggerDependencyLoading(key, cached)
+			ra.triggerDependencyLoading(ctx, key, cached)
 		}
 		return ra.buildResponse(cached), nil
 		
@@ -101,7 +112,7 @@ func (ra *RevisionAnalyzer) analyzeWithCache(key CacheKey, clientRevision string
 	}
 	
 	// Step 2: Check if we should recalculate (for cache miss or potential improvement)
-	shouldRecalc, availableDeps, err := ra.cache.ShouldRecalculate(key, ra.repoPath)
+	shouldRecalc, availableDeps, err := ra.cache.ShouldRecalculate(ctx, key, ra.repoPath)
 	if err != nil {
 		return nil, fmt.Errorf("error checking recalculation need: %w", err)
 	}
@@ -112,76 +123,53 @@ func (ra *RevisionAnalyzer) analyzeWithCache(key CacheKey, clientRevision string
 	}
 	
 	if shouldRecalc && len(availableDeps) > 0 {
-		fmt.Printf("Recalculating analysis for %s: %d new dependencies available\n", key.String(), len(availableDeps))
+		ra.logger.InfoContext(ctx, "Recalculating analysis with new dependencies", "key", key.String(), "available", len(availableDeps))
 	}
 	
-	// Step 3: Perform analysis
-	newAnalysis, err := analyzer()
+	// Step 3: Perform analysis and cache it, once for all concurrent requests of the key
+	newAnalysis, err, _ := ra.analyses.Do(ctx, key.String(), func(ctx context.Context) (*CachedAnalysis, error) {
+		analysis, err := analyzer(ctx)
+		if err != nil {
+			return nil, err
+		}
+		ra.cache.Set(key, analysis)
+		return analysis, nil
+	})
 	if err != nil {
 		return nil, err
 	}
 	
-	// Step 4: Cache the new analysis
-	ra.cache.Set(key, newAnalysis)
-	
-	// Step 5: Trigger dependency loading if incomplete
+	// Step 4: Trigger dependency loading if incomplete
 	if !newAnalysis.IsComplete && !ra.dependencyQueue.IsActive(key) {
-		ra.triggerDependencyLoading(key, newAnalysis)
+		ra.triggerDependencyLoading(ctx, key, newAnalysis)
 	}
 	
 	return ra.buildResponse(newAnalysis), nil
 }
 
 // performPackageAnalysis performs actual package analysis
-func (ra *RevisionAnalyzer) performPackageAnalysis(packagePath string) (*CachedAnalysis, error) {
+func (ra *RevisionAnalyzer) performPackageAnalysis(ctx context.Context, packagePath string) (*CachedAnalysis, error) {
 	// Use enhanced analysis to get quality information
-	enhancedResponse, err := ra.packagesAnalyzer.AnalyzePackageWithQuality(packagePath)
+	enhancedResponse, err := ra.packagesAnalyzer.AnalyzePackageWithQuality(ctx, packagePath)
 	if err != nil {
 		return nil, err
 	}
-	
-	// Calculate revision based on analysis state
-	symbolCount := len(enhancedResponse.PackageInfo.Symbols)
-	refCount := 0 // Package analysis doesn't have references
-	revision := GenerateRevision(packagePath, enhancedResponse.Quality, symbolCount, refCount)
-	
-	return &CachedAnalysis{
-		Revision:                revision,
-		PackageInfo:             enhancedResponse.PackageInfo,
-		Quality:                 enhancedResponse.Quality,
-		Timestamp:               time.Now(),
-		MissingDependencies:     enhancedResponse.Quality.MissingDependencies,
-		DependencyLoadingInProgress: false,
-		IsComplete:              enhancedResponse.Quality.IsComplete,
-	}, nil
+	return newCachedAnalysis(packagePath, enhancedResponse), nil
 }
 
 // performFileAnalysis performs actual file analysis
-func (ra *RevisionAnalyzer) performFileAnalysis(filePath string) (*CachedAnalysis, error) {
+func (ra *RevisionAnalyzer) performFileAnalysis(ctx context.Context, filePath string) (*CachedAnalysis, error) {
 	// Use enhanced analysis to get quality information
-	enhancedResponse, err := ra.packagesAnalyzer.AnalyzeSingleFileWithQuality(filePath)
+	enhancedResponse, err := ra.packagesAnalyzer.AnalyzeSingleFileWithQuality(ctx, filePath)
 	if err != nil {
 		return nil, err
 	}
-	
-	// Calculate revision based on analysis state
-	symbolCount := len(enhancedResponse.FileInfo.Symbols)
-	refCount := len(enhancedResponse.FileInfo.References)
-	revision := GenerateRevision(filePath, enhancedResponse.Quality, symbolCount, refCount)
-	
-	return &CachedAnalysis{
-		Revision:                revision,
-		FileInfo:                enhancedResponse.FileInfo,
-		Quality:                 enhancedResponse.Quality,
-		Timestamp:               time.Now(),
-		MissingDependencies:     enhancedResponse.Quality.MissingDependencies,
-		DependencyLoadingInProgress: false,
-		IsComplete:              enhancedResponse.Quality.IsComplete,
-	}, nil
+	return newCachedAnalysis(filePath, enhancedResponse), nil
 }
 
-// triggerDependencyLoading starts background dependency loading
-func (ra *RevisionAnalyzer) triggerDependencyLoading(key CacheKey, cached *CachedAnalysis) {
+// triggerDependencyLoading starts background dependency loading. The download is
+// identified by the request ID of ctx, if any, so its records can be correlated.
+func (ra *RevisionAnalyzer) triggerDependencyLoading(ctx context.Context, key CacheKey, cached *CachedAnalysis) {
 	if len(cached.MissingDependencies) == 0 {
 		return
 	}
@@ -190,18 +178,22 @@ func (ra *RevisionAnalyzer) triggerDependencyLoading(key CacheKey, cached *Cache
 	ra.cache.MarkDependencyLoadingInProgress(key, true)
 	
 	// Create download request
+	requestID := logging.RequestID(ctx)
+	if requestID == "" {
+		requestID = fmt.Sprintf("%s_%d", key.String(), time.Now().Unix())
+	}
 	req := DependencyDownloadRequest{
 		WorkDir:      ra.repoPath,
 		Dependencies: cached.MissingDependencies,
 		CacheKey:     key,
-		RequestID:    fmt.Sprintf("%s_%d", key.String(), time.Now().Unix()),
+		RequestID:    requestID,
 		ResultChan:   make(chan DependencyDownloadResult, 1),
 	}
 	
 	// Submit to queue
 	err := ra.dependencyQueue.SubmitDownloadRequest(req)
 	if err != nil {
-		fmt.Printf("Failed to submit dependency download request: %v\n", err)
+		ra.logger.WarnContext(ctx, "Failed to submit dependency download request", "key", key.String(), "error", err)
 		ra.cache.MarkDependencyLoadingInProgress(key, false)
 		return
 	}
@@ -209,7 +201,7 @@ func (ra *RevisionAnalyzer) triggerDependencyLoading(key CacheKey, cached *Cache
 	// Start goroutine to handle completion
 	go ra.handleDependencyLoadingResult(key, req.ResultChan)
 	
-	fmt.Printf("Triggered dependency loading for %s: %v\n", key.String(), cached.MissingDependencies)
+	ra.logger.InfoContext(ctx, "Triggered dependency loading", "key", key.String(), "dependencies", cached.MissingDependencies)
 }
 
 // handleDependencyLoadingResult handles the completion of dependency loading
@@ -219,15 +211,18 @@ func (ra *RevisionAnalyzer) handleDependencyLoadingResult(key CacheKey, resultCh
 		// Mark loading as complete
 		ra.cache.MarkDependencyLoadingInProgress(key, false)
 		
-		fmt.Printf("Dependency loading completed for %s: success=%d, failed=%d\n", 
-			key.String(), len(result.Successful), len(result.Failed))
+		ra.logger.Info("Dependency loading completed", "key", key.String(), logging.RequestIDKey, result.RequestID,
+			"successful", len(result.Successful), "failed", len(result.Failed))
 		
 		// If any dependencies were successfully loaded, the next analysis request will recalculate
-		// No need to pro-actively recalculate here
+		// from a fresh module load. No need to pro-actively recalculate here
+		if len(result.Successful) > 0 {
+			ra.packagesAnalyzer.InvalidateModuleLoad()
+		}
 		
 	case <-time.After(10 * time.Minute): // Timeout
 		ra.cache.MarkDependencyLoadingInProgress(key, false)
-		fmt.Printf("Dependency loading timed out for %s\n", key.String())
+		ra.logger.Warn("Dependency loading timed out", "key", key.String())
 	}
 }
 
@@ -264,12 +259,22 @@ func (ra *RevisionAnalyzer) GetQueueStats() DependencyQueueStats {
 func (ra *RevisionAnalyzer) Cleanup(maxAge time.Duration) {
 	removed := ra.cache.Cleanup(maxAge)
 	if removed > 0 {
-		fmt.Printf("Cleaned up %d old cache entries\n", removed)
+		ra.logger.Info("Cleaned up old cache entries", "removed", removed)
+	}
+}
+
+// Evict removes cache entries not accessed within ttl and keeps at most maxEntries.
+// Register it with repo.Manager.OnEvict to apply it on the cache eviction schedule.
+func (ra *RevisionAnalyzer) Evict(ttl time.Duration, maxEntries int) int {
+	removed := ra.cache.Evict(ttl, maxEntries)
+	if removed > 0 {
+		ra.logger.Info("Evicted cache entries", "removed", removed)
 	}
+	return removed
 }
 
 // Shutdown gracefully shuts down the revision analyzer
 func (ra *RevisionAnalyzer) Shutdown(timeout time.Duration) error {
-	fmt.Println("Shutting down revision analyzer...")
+	ra.logger.Info("Shutting down revision analyzer")
 	return ra.dependencyQueue.Shutdown(timeout)
 }
\ No newline at end of file
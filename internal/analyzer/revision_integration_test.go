@@ -1,8 +1,11 @@
 package analyzer
 
 import (
+	"context"
 	"os"
 	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -69,7 +72,7 @@ func (t TestStruct) Method() string {
 
 	// Test 1: Initial package request (no revision)
 	t.Run("InitialPackageRequest", func(t *testing.T) {
-		response, err := analyzer.AnalyzePackage("", "")
+		response, err := analyzer.AnalyzePackage(context.Background(), "", "")
 		require.NoError(t, err)
 		require.NotNil(t, response)
 
@@ -94,11 +97,11 @@ func (t TestStruct) Method() string {
 	// Test 2: Second request with same revision (should return no change)
 	t.Run("SameRevisionRequest", func(t *testing.T) {
 		// First get initial response
-		initialResponse, err := analyzer.AnalyzePackage("", "")
+		initialResponse, err := analyzer.AnalyzePackage(context.Background(), "", "")
 		require.NoError(t, err)
 
 		// Request again with same revision
-		response, err := analyzer.AnalyzePackage("", initialResponse.Revision)
+		response, err := analyzer.AnalyzePackage(context.Background(), "", initialResponse.Revision)
 		require.NoError(t, err)
 
 		// Should return no change
@@ -109,7 +112,7 @@ func (t TestStruct) Method() string {
 
 	// Test 3: File analysis with revision tracking
 	t.Run("FileAnalysisWithRevision", func(t *testing.T) {
-		response, err := analyzer.AnalyzeFile("", "main.go", "")
+		response, err := analyzer.AnalyzeFile(context.Background(), "", "main.go", "")
 		require.NoError(t, err)
 		require.NotNil(t, response)
 
@@ -128,7 +131,7 @@ func (t TestStruct) Method() string {
 			len(response.FileInfo.Symbols), len(response.FileInfo.References))
 
 		// Test same revision request for file
-		sameRevisionResponse, err := analyzer.AnalyzeFile("", "main.go", response.Revision)
+		sameRevisionResponse, err := analyzer.AnalyzeFile(context.Background(), "", "main.go", response.Revision)
 		require.NoError(t, err)
 		assert.True(t, sameRevisionResponse.NoChange)
 	})
@@ -199,7 +202,7 @@ const Version = "1.0.0"
 	analyzer := NewRevisionAnalyzer(tempDir, nil, queueConfig)
 
 	// Analyze package
-	response, err := analyzer.AnalyzePackage("", "")
+	response, err := analyzer.AnalyzePackage(context.Background(), "", "")
 	require.NoError(t, err)
 	require.NotNil(t, response)
 
@@ -219,7 +222,7 @@ const Version = "1.0.0"
 		response.Revision, response.Complete, response.Quality.QualityScore)
 
 	// Second request should return same complete result
-	response2, err := analyzer.AnalyzePackage("", "")
+	response2, err := analyzer.AnalyzePackage(context.Background(), "", "")
 	require.NoError(t, err)
 	assert.Equal(t, response.Revision, response2.Revision)
 	assert.True(t, response2.Complete)
@@ -229,6 +232,56 @@ const Version = "1.0.0"
 	assert.NoError(t, err)
 }
 
+func TestRevisionAnalyzer_ConcurrentAnalysis(t *testing.T) {
+	analyzer := NewRevisionAnalyzer(t.TempDir(), nil, DefaultDependencyQueueConfig())
+	defer analyzer.Shutdown(time.Second)
+
+	var runs atomic.Int32
+	release := make(chan struct{})
+	perform := func(ctx context.Context) (*CachedAnalysis, error) {
+		runs.Add(1)
+		<-release
+		return &CachedAnalysis{
+			Revision:    "r1",
+			PackageInfo: &PackageInfo{Name: "gin"},
+			Quality:     &AnalysisQuality{IsComplete: true, QualityScore: 1.0},
+			IsComplete:  true,
+		}, nil
+	}
+
+	// Concurrent requests for the same key wait for a single analysis
+	key := CacheKey{Type: CacheKeyTypePackage, PackagePath: "github.com/gin-gonic/gin@v1.9.1"}
+	const requests = 4
+	var wg sync.WaitGroup
+	responses := make([]*RevisionAnalysisResponse, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			response, err := analyzer.analyzeWithCache(context.Background(), key, "", perform)
+			assert.NoError(t, err)
+			responses[i] = response
+		}(i)
+	}
+
+	require.Eventually(t, func() bool { return analyzer.analyses.InFlight() == 1 && runs.Load() == 1 }, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), runs.Load())
+	for _, response := range responses {
+		require.NotNil(t, response)
+		assert.Equal(t, "r1", response.Revision)
+		assert.Equal(t, "gin", response.PackageInfo.Name)
+	}
+
+	// The shared result is cached for later requests
+	response, err := analyzer.analyzeWithCache(context.Background(), key, "r1", perform)
+	require.NoError(t, err)
+	assert.True(t, response.NoChange)
+	assert.Equal(t, int32(1), runs.Load())
+}
+
 func TestRevisionGeneration_Consistency(t *testing.T) {
 	// Test that revision generation is consistent
 	quality1 := &AnalysisQuality{
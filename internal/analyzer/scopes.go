@@ -0,0 +1,292 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// GlobalScopeID is the implicit ID of the package scope, the parent of top-level scopes
+const GlobalScopeID = "/"
+
+// scopeTree holds the lexical scopes of a file. Scopes are found by walking the AST,
+// and the types.Scope of each scope-introducing node in types.Info.Scopes is bound to
+// its entry, so objects can be mapped to the scope declaring them.
+//
+// Statement bodies are merged into the scope of their statement: the body of
+// an if, for or range statement, or of a function, does not get its own entry, as
+// the variables declared by the statement header are visible in it anyway.
+type scopeTree struct {
+	scopes  []*treeScope                // In pre-order, parents before children
+	byScope map[*types.Scope]*treeScope // Bound types scopes, nil without type information
+	ids     map[string]bool             // IDs in use
+}
+
+type treeScope struct {
+	info     *ScopeInfo
+	pos, end token.Pos
+	counters map[string]int // Children per kind, to number them
+}
+
+// buildScopeTree builds the scope tree of file. info may be nil or lack Scopes,
+// in which case objects are mapped to scopes by position.
+func buildScopeTree(file *ast.File, fset *token.FileSet, info *types.Info) *scopeTree {
+	tree := &scopeTree{ids: make(map[string]bool)}
+	if info != nil && info.Scopes != nil {
+		tree.byScope = make(map[*types.Scope]*treeScope)
+	}
+
+	root := &treeScope{info: &ScopeInfo{ID: GlobalScopeID}, counters: make(map[string]int)}
+
+	// Stack of visited nodes and of the scope each one is in, or opens
+	var nodes []ast.Node
+	var scopes []*treeScope
+	current := func() *treeScope {
+		if len(scopes) == 0 {
+			return root
+		}
+		return scopes[len(scopes)-1]
+	}
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		if n == nil {
+			nodes = nodes[:len(nodes)-1]
+			scopes = scopes[:len(scopes)-1]
+			return true
+		}
+
+		var parentNode ast.Node
+		if len(nodes) > 0 {
+			parentNode = nodes[len(nodes)-1]
+		}
+		scope := current()
+
+		if kind, typ, name := scopeKind(n, parentNode); kind != "" {
+			scope = tree.add(scope, n, kind, typ, name, fset)
+		}
+		tree.bind(info, n, parentNode, scope)
+
+		nodes = append(nodes, n)
+		scopes = append(scopes, scope)
+		return true
+	})
+
+	return tree
+}
+
+// scopeKind returns the kind of the scope opened by n, a child of parent, with the
+// type and name of its entry. The kind is empty if n opens no scope of its own.
+func scopeKind(n, parent ast.Node) (kind, typ, name string) {
+	switch node := n.(type) {
+	case *ast.FuncDecl:
+		if recv := receiverTypeName(node); recv != "" {
+			return recv + "_" + node.Name.Name, "method", recv + "." + node.Name.Name
+		}
+		return node.Name.Name, "function", node.Name.Name
+	case *ast.FuncLit:
+		return "func", "closure", ""
+	case *ast.TypeSpec:
+		// Generic types scope their type parameters
+		if node.TypeParams != nil {
+			return node.Name.Name, "type", node.Name.Name
+		}
+	case *ast.IfStmt:
+		if ifStmt, ok := parent.(*ast.IfStmt); ok && ifStmt.Else == n {
+			return "else", "block", ""
+		}
+		return "if", "block", ""
+	case *ast.ForStmt:
+		return "for", "block", ""
+	case *ast.RangeStmt:
+		return "range", "block", ""
+	case *ast.SwitchStmt:
+		return "switch", "block", ""
+	case *ast.TypeSwitchStmt:
+		return "typeswitch", "block", ""
+	case *ast.SelectStmt:
+		return "select", "block", ""
+	case *ast.CaseClause, *ast.CommClause:
+		return "case", "block", ""
+	case *ast.BlockStmt:
+		switch p := parent.(type) {
+		case *ast.FuncDecl, *ast.FuncLit, *ast.ForStmt, *ast.RangeStmt,
+			*ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt:
+			return "", "", ""
+		case *ast.IfStmt:
+			if p.Else == n {
+				return "else", "block", ""
+			}
+			return "", "", ""
+		}
+		return "block", "block", ""
+	}
+	return "", "", ""
+}
+
+// receiverTypeName returns the name of the receiver base type of a method, or "" for
+// a function
+func receiverTypeName(decl *ast.FuncDecl) string {
+	if decl.Recv == nil || len(decl.Recv.List) == 0 {
+		return ""
+	}
+	expr := decl.Recv.List[0].Type
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// add adds the scope opened by n as a child of parent. Functions and generic types are
+// named after themselves, other scopes after their kind, numbered within the parent.
+func (t *scopeTree) add(parent *treeScope, n ast.Node, kind, typ, name string, fset *token.FileSet) *treeScope {
+	base := kind
+	if typ != "function" && typ != "method" && typ != "type" {
+		parent.counters[kind]++
+		base = fmt.Sprintf("%s_%d", kind, parent.counters[kind])
+	}
+
+	prefix := parent.info.ID
+	if prefix != GlobalScopeID {
+		prefix += "/"
+	}
+	id := prefix + base
+	// Functions may share a name, as init functions do
+	for i := 2; t.ids[id]; i++ {
+		id = fmt.Sprintf("%s%s_%d", prefix, base, i)
+	}
+	t.ids[id] = true
+
+	start := fset.Position(n.Pos())
+	end := fset.Position(n.End())
+	scope := &treeScope{
+		info: &ScopeInfo{
+			ID:     id,
+			Type:   typ,
+			Name:   name,
+			Parent: parent.info.ID,
+			Range: Range{
+				Start: Position{Line: start.Line, Column: start.Column},
+				End:   Position{Line: end.Line, Column: end.Column},
+			},
+		},
+		pos:      n.Pos(),
+		end:      n.End(),
+		counters: make(map[string]int),
+	}
+	parent.info.Children = append(parent.info.Children, id)
+	t.scopes = append(t.scopes, scope)
+	return scope
+}
+
+// bind records that the types scope of n, if any, is scope. The scope of a function
+// is recorded for its type, a child of the declaration or literal.
+func (t *scopeTree) bind(info *types.Info, n, parent ast.Node, scope *treeScope) {
+	if t.byScope == nil {
+		return
+	}
+	if _, ok := n.(*ast.FuncType); ok {
+		switch parent.(type) {
+		case *ast.FuncDecl, *ast.FuncLit:
+		default:
+			// Parameters of function types are placed in the enclosing scope
+			return
+		}
+	}
+	if s := info.Scopes[n]; s != nil {
+		t.byScope[s] = scope
+	}
+}
+
+// Scopes returns the scope entries in pre-order
+func (t *scopeTree) Scopes() []*ScopeInfo {
+	scopes := make([]*ScopeInfo, 0, len(t.scopes))
+	for _, scope := range t.scopes {
+		scopes = append(scopes, scope.info)
+	}
+	return scopes
+}
+
+// ScopeID returns the ID of the scope declaring obj, the identifier of which is at pos
+func (t *scopeTree) ScopeID(obj types.Object, pos token.Pos) string {
+	parent := obj.Parent()
+	if parent == nil {
+		// Labels are scoped to their function, fields and methods to their type
+		if _, ok := obj.(*types.Label); ok {
+			return t.innermost(pos)
+		}
+		return GlobalScopeID
+	}
+	if isPackageLevel(obj) {
+		return GlobalScopeID
+	}
+
+	if t.byScope == nil {
+		return t.innermost(pos)
+	}
+	for s := parent; s != nil; s = s.Parent() {
+		if scope, ok := t.byScope[s]; ok {
+			return scope.info.ID
+		}
+	}
+	return GlobalScopeID
+}
+
+// innermost returns the ID of the innermost scope containing pos
+func (t *scopeTree) innermost(pos token.Pos) string {
+	id := GlobalScopeID
+	for _, scope := range t.scopes {
+		if scope.pos <= pos && pos < scope.end {
+			id = scope.info.ID
+		}
+	}
+	return id
+}
+
+// isPackageLevel reports whether obj is declared in its package or file scope
+func isPackageLevel(obj types.Object) bool {
+	parent := obj.Parent()
+	if parent == nil || obj.Pkg() == nil {
+		return false
+	}
+	pkgScope := obj.Pkg().Scope()
+	return parent == pkgScope || parent.Parent() == pkgScope
+}
+
+// typeSwitchVar returns the variable of a "switch v := x.(type)" statement, with the
+// type of x and the objects declaring v in each clause, as v itself defines no object.
+// It returns a nil identifier if the statement declares no variable.
+func typeSwitchVar(node *ast.TypeSwitchStmt, info *types.Info) (*ast.Ident, types.Type, []types.Object) {
+	assign, ok := node.Assign.(*ast.AssignStmt)
+	if !ok || len(assign.Lhs) != 1 || len(assign.Rhs) != 1 {
+		return nil, nil, nil
+	}
+	ident, ok := assign.Lhs[0].(*ast.Ident)
+	if !ok || ident.Name == "_" {
+		return nil, nil, nil
+	}
+
+	var typ types.Type
+	if assert, ok := assign.Rhs[0].(*ast.TypeAssertExpr); ok && info.Types != nil {
+		typ = info.Types[assert.X].Type
+	}
+	var objects []types.Object
+	for _, clause := range node.Body.List {
+		if obj := info.Implicits[clause]; obj != nil {
+			objects = append(objects, obj)
+		}
+	}
+	return ident, typ, objects
+}
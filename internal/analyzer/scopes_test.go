@@ -0,0 +1,244 @@
+package analyzer
+
+import (
+	"context"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const scopeTreeSource = `package main
+
+type T struct{}
+
+func (t *T) Run(a int) (r int) {
+	if x := a; x > 0 {
+		y := x
+		_ = y
+	} else if z := 2; z > 1 {
+	} else {
+		w := 1
+		_ = w
+	}
+	for i := 0; i < 3; i++ {
+		_ = i
+	}
+	for k, v := range []int{} {
+		_, _ = k, v
+	}
+	switch s := a; s {
+	case 1:
+		c := 1
+		_ = c
+	default:
+	}
+	var e interface{}
+	switch tv := e.(type) {
+	case int:
+		_ = tv
+	case string:
+		_ = tv
+	}
+	ch := make(chan int)
+	select {
+	case m := <-ch:
+		_ = m
+	default:
+	}
+	f := func(p int) int { return p }
+	{
+		b := f(1)
+		_ = b
+	}
+	return
+}
+
+func init() {}
+
+func init() {}
+`
+
+func checkScopeTreeSource(t *testing.T) (*token.FileSet, *ast.File, *types.Info) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "test.go", scopeTreeSource, parser.ParseComments)
+	require.NoError(t, err)
+
+	info := &types.Info{
+		Defs:      make(map[*ast.Ident]types.Object),
+		Uses:      make(map[*ast.Ident]types.Object),
+		Types:     make(map[ast.Expr]types.TypeAndValue),
+		Scopes:    make(map[ast.Node]*types.Scope),
+		Implicits: make(map[ast.Node]types.Object),
+	}
+	config := &types.Config{Importer: importer.Default()}
+	_, err = config.Check("main", fset, []*ast.File{file}, info)
+	require.NoError(t, err)
+	return fset, file, info
+}
+
+func TestScopeTree(t *testing.T) {
+	fset, file, info := checkScopeTreeSource(t)
+
+	scopes, err := New().extractScopes(file, fset, info)
+	require.NoError(t, err)
+
+	byID := make(map[string]*ScopeInfo)
+	ids := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		byID[scope.ID] = scope
+		ids = append(ids, scope.ID)
+	}
+
+	assert.Equal(t, []string{
+		"/T_Run",
+		"/T_Run/if_1",
+		"/T_Run/if_1/else_1",
+		"/T_Run/if_1/else_1/else_1",
+		"/T_Run/for_1",
+		"/T_Run/range_1",
+		"/T_Run/switch_1",
+		"/T_Run/switch_1/case_1",
+		"/T_Run/switch_1/case_2",
+		"/T_Run/typeswitch_1",
+		"/T_Run/typeswitch_1/case_1",
+		"/T_Run/typeswitch_1/case_2",
+		"/T_Run/select_1",
+		"/T_Run/select_1/case_1",
+		"/T_Run/select_1/case_2",
+		"/T_Run/func_1",
+		"/T_Run/block_1",
+		"/init",
+		"/init_2",
+	}, ids)
+
+	run := byID["/T_Run"]
+	assert.Equal(t, "method", run.Type)
+	assert.Equal(t, "T.Run", run.Name)
+	assert.Equal(t, GlobalScopeID, run.Parent)
+	assert.Equal(t, Range{Start: Position{Line: 5, Column: 1}, End: Position{Line: 45, Column: 2}}, run.Range)
+	assert.Equal(t, []string{
+		"/T_Run/if_1", "/T_Run/for_1", "/T_Run/range_1", "/T_Run/switch_1", "/T_Run/typeswitch_1",
+		"/T_Run/select_1", "/T_Run/func_1", "/T_Run/block_1",
+	}, run.Children)
+
+	assert.Equal(t, "/T_Run/if_1", byID["/T_Run/if_1/else_1"].Parent)
+	assert.Equal(t, []string{"/T_Run/if_1/else_1/else_1"}, byID["/T_Run/if_1/else_1"].Children)
+	assert.Equal(t, "closure", byID["/T_Run/func_1"].Type)
+	assert.Equal(t, "function", byID["/init_2"].Type)
+	assert.Empty(t, byID["/init"].Children)
+}
+
+func TestDefinitionScopes(t *testing.T) {
+	fset, file, info := checkScopeTreeSource(t)
+
+	definitions, err := New().extractDefinitions(file, fset, info)
+	require.NoError(t, err)
+
+	scopeIDs := make(map[string]string)
+	for _, def := range definitions {
+		if def.Name != "_" {
+			scopeIDs[def.Name] = def.ScopeID
+		}
+	}
+
+	expected := map[string]string{
+		"T":    "/",
+		"Run":  "/",
+		"t":    "/T_Run",
+		"a":    "/T_Run",
+		"r":    "/T_Run",
+		"x":    "/T_Run/if_1",
+		"y":    "/T_Run/if_1",
+		"z":    "/T_Run/if_1/else_1",
+		"w":    "/T_Run/if_1/else_1/else_1",
+		"i":    "/T_Run/for_1",
+		"k":    "/T_Run/range_1",
+		"v":    "/T_Run/range_1",
+		"s":    "/T_Run/switch_1",
+		"c":    "/T_Run/switch_1/case_1",
+		"e":    "/T_Run",
+		"tv":   "/T_Run/typeswitch_1",
+		"ch":   "/T_Run",
+		"m":    "/T_Run/select_1/case_1",
+		"f":    "/T_Run",
+		"p":    "/T_Run/func_1",
+		"b":    "/T_Run/block_1",
+		"init": "/",
+	}
+	for name, scopeID := range expected {
+		assert.Equal(t, scopeID, scopeIDs[name], "scope of %s", name)
+	}
+}
+
+func TestLocalReferenceLinks(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "test.go"), []byte(scopeTreeSource), 0644))
+
+	fileInfo, err := New().AnalyzeSingleFile(context.Background(), tmpDir, "test.go")
+	require.NoError(t, err)
+
+	definitions := make(map[string]*Definition)
+	for _, def := range fileInfo.Definitions {
+		definitions[def.ID] = def
+	}
+
+	linked := make(map[string][]int) // Name -> lines of the linked references
+	for _, ref := range fileInfo.References {
+		if ref.DefinitionID == "" {
+			continue
+		}
+		def := definitions[ref.DefinitionID]
+		require.NotNil(t, def, "reference %s links to unknown definition %s", ref.Name, ref.DefinitionID)
+		assert.Equal(t, "local", ref.Type)
+		assert.Equal(t, ref.Name, def.Name)
+		linked[ref.Name] = append(linked[ref.Name], ref.Line)
+	}
+
+	assert.Equal(t, []int{6, 7}, linked["x"])
+	assert.Equal(t, []int{29, 31}, linked["tv"], "the type switch variable of every clause")
+	assert.Equal(t, []int{36}, linked["m"])
+	assert.Equal(t, []int{39}, linked["p"])
+	assert.Equal(t, []int{41}, linked["f"])
+	assert.Equal(t, []int{5}, linked["T"])
+}
+
+func TestDefinitionIDs(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/ids\n\ngo 1.21\n"), 0644))
+	source := "package ids\n\nfunc F() int { x := 1; if x := 2; x > 0 { return x }; return x }\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ids.go"), []byte(source), 0644))
+
+	fileInfo, err := NewPackagesAnalyzer(dir, nil).AnalyzeSingleFileWithPackages(context.Background(), "ids.go")
+	require.NoError(t, err)
+
+	// Definitions of the same name on the same line are told apart by their column
+	ids := make(map[string]bool)
+	for _, def := range fileInfo.Definitions {
+		assert.False(t, ids[def.ID], "duplicate definition ID %s", def.ID)
+		ids[def.ID] = true
+	}
+	assert.True(t, ids["def_x_3_16"])
+	assert.True(t, ids["def_x_3_27"])
+
+	// The fallback analyzer gives the same definitions the same IDs
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "ids.go", source, 0)
+	require.NoError(t, err)
+	info := &types.Info{Defs: make(map[*ast.Ident]types.Object)}
+	_, _ = (&types.Config{Importer: importer.Default()}).Check("ids", fset, []*ast.File{file}, info)
+	for _, typeInfo := range []*types.Info{info, nil} {
+		definitions, err := New().extractDefinitions(file, fset, typeInfo)
+		require.NoError(t, err)
+		for _, def := range definitions {
+			assert.True(t, ids[def.ID], "unknown definition ID %s", def.ID)
+		}
+	}
+}
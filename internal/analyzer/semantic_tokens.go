@@ -0,0 +1,196 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+// Semantic token types, in the order of TokenTypes
+const (
+	TokenPackage = iota
+	TokenType
+	TokenTypeParameter
+	TokenFunction
+	TokenMethod
+	TokenParameter
+	TokenVariable
+	TokenField
+	TokenConstant
+	TokenLabel
+	TokenString
+	TokenNumber
+)
+
+// Semantic token modifiers, as bits of the modifier set of a token
+const (
+	ModifierDefinition = 1 << iota // The token declares the identifier
+	ModifierReadonly               // Constants
+	ModifierDeprecated             // Declared with a "Deprecated:" notice
+	ModifierStdlib                 // Declared in the standard library or the universe scope
+	ModifierExternal               // Declared outside the repository
+)
+
+// TokenTypes and TokenModifiers form the legend of semantic tokens: token types are
+// indexes into TokenTypes, and bit i of a modifier set stands for TokenModifiers[i]
+var (
+	TokenTypes = []string{
+		"package", "type", "typeParameter", "function", "method", "parameter", "variable",
+		"field", "constant", "label", "string", "number",
+	}
+	TokenModifiers = []string{"definition", "readonly", "deprecated", "stdlib", "external"}
+)
+
+// SemanticTokens classifies the identifiers and literals of a file, encoded as LSP
+// semantic tokens: five integers per token, the line of the token relative to the
+// previous token, its start column relative to the previous token if on the same line,
+// its length, its type and its modifier set. Lines, columns and lengths count bytes,
+// from 0. Literals spanning several lines are split into one token per line.
+type SemanticTokens struct {
+	TokenTypes     []string `json:"tokenTypes"`
+	TokenModifiers []string `json:"tokenModifiers"`
+	Data           []uint32 `json:"data"`
+}
+
+// tokenOrigin reports whether obj is declared in the standard library, and whether it
+// is declared outside the repository
+type tokenOrigin func(obj types.Object) (stdlib, external bool)
+
+type semanticToken struct {
+	line, column, length int // Line and column from 0
+	typ, modifiers       int
+}
+
+// semanticTokens returns the semantic tokens of file. Identifiers of objects for which
+// deprecated returns true carry the deprecated modifier, none if deprecated is nil.
+func semanticTokens(file *ast.File, fset *token.FileSet, info *types.Info, origin tokenOrigin, deprecated func(obj types.Object) bool) *SemanticTokens {
+	params := parameterObjects(file, info)
+	var tokens []semanticToken
+
+	add := func(pos token.Pos, text string, typ, modifiers int) {
+		position := fset.Position(pos)
+		line, column := position.Line-1, position.Column-1
+		for i, segment := range strings.Split(text, "\n") {
+			if i > 0 {
+				line, column = line+1, 0
+			}
+			if segment != "" {
+				tokens = append(tokens, semanticToken{line: line, column: column, length: len(segment), typ: typ, modifiers: modifiers})
+			}
+		}
+	}
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.BasicLit:
+			typ := TokenNumber
+			if node.Kind == token.STRING || node.Kind == token.CHAR {
+				typ = TokenString
+			}
+			add(node.Pos(), node.Value, typ, 0)
+
+		case *ast.Ident:
+			if node == file.Name {
+				add(node.Pos(), node.Name, TokenPackage, ModifierDefinition)
+				break
+			}
+			obj, isDef := info.Defs[node]
+			if isDef && obj == nil {
+				// The variable of "switch v := x.(type)" declares no object
+				add(node.Pos(), node.Name, TokenVariable, ModifierDefinition)
+				break
+			}
+			modifiers := 0
+			if isDef {
+				modifiers |= ModifierDefinition
+			} else if obj = info.Uses[node]; obj == nil {
+				break
+			}
+
+			typ := objectTokenType(obj, params)
+			if typ == TokenConstant {
+				modifiers |= ModifierReadonly
+			}
+			if deprecated != nil && deprecated(obj) {
+				modifiers |= ModifierDeprecated
+			}
+			stdlib, external := origin(obj)
+			if stdlib {
+				modifiers |= ModifierStdlib
+			}
+			if external {
+				modifiers |= ModifierExternal
+			}
+			add(node.Pos(), node.Name, typ, modifiers)
+		}
+		return true
+	})
+
+	sort.SliceStable(tokens, func(i, j int) bool {
+		if tokens[i].line != tokens[j].line {
+			return tokens[i].line < tokens[j].line
+		}
+		return tokens[i].column < tokens[j].column
+	})
+
+	data := make([]uint32, 0, 5*len(tokens))
+	prevLine, prevColumn := 0, 0
+	for _, t := range tokens {
+		deltaColumn := t.column
+		if t.line == prevLine {
+			deltaColumn -= prevColumn
+		}
+		data = append(data, uint32(t.line-prevLine), uint32(deltaColumn), uint32(t.length), uint32(t.typ), uint32(t.modifiers))
+		prevLine, prevColumn = t.line, t.column
+	}
+
+	return &SemanticTokens{TokenTypes: TokenTypes, TokenModifiers: TokenModifiers, Data: data}
+}
+
+// objectPackagePath returns the path of the package declaring obj, or of the package
+// it imports for package names. It is "" for objects of the universe scope.
+func objectPackagePath(obj types.Object) string {
+	if pkgName, ok := obj.(*types.PkgName); ok {
+		return pkgName.Imported().Path()
+	}
+	if obj.Pkg() == nil {
+		return ""
+	}
+	return obj.Pkg().Path()
+}
+
+// objectTokenType returns the token type of identifiers denoting obj. params holds
+// the parameters, results and receivers of the file.
+func objectTokenType(obj types.Object, params map[types.Object]bool) int {
+	switch o := obj.(type) {
+	case *types.PkgName:
+		return TokenPackage
+	case *types.TypeName:
+		if isTypeParam(o) {
+			return TokenTypeParameter
+		}
+		return TokenType
+	case *types.Func:
+		if sig, ok := o.Type().(*types.Signature); ok && sig.Recv() != nil {
+			return TokenMethod
+		}
+		return TokenFunction
+	case *types.Builtin:
+		return TokenFunction
+	case *types.Var:
+		if o.IsField() {
+			return TokenField
+		}
+		if params[o] {
+			return TokenParameter
+		}
+		return TokenVariable
+	case *types.Const, *types.Nil:
+		return TokenConstant
+	case *types.Label:
+		return TokenLabel
+	}
+	return TokenVariable
+}
@@ -0,0 +1,138 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const semanticTokensSource = `package main
+
+import "fmt"
+
+const limit = 3
+
+type Point struct{ X int }
+
+// Old does nothing.
+//
+// Deprecated: use New.
+func Old() {}
+
+func Max[T int | float64](a, b T) T {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func main() {
+	p := Point{X: limit}
+outer:
+	for {
+		break outer
+	}
+	Old()
+	fmt.Println(p.X, Max(1, 2.5), ` + "`a\nbc`" + `, nil)
+}
+`
+
+// decodeTokens renders the tokens of source as "text:type:modifier,modifier", in order
+func decodeTokens(t *testing.T, tokens *SemanticTokens, source string) []string {
+	require.Zero(t, len(tokens.Data)%5)
+	lines := strings.Split(source, "\n")
+
+	var decoded []string
+	line, column := 0, 0
+	for i := 0; i < len(tokens.Data); i += 5 {
+		deltaLine, deltaColumn, length := int(tokens.Data[i]), int(tokens.Data[i+1]), int(tokens.Data[i+2])
+		if deltaLine > 0 {
+			line, column = line+deltaLine, deltaColumn
+		} else {
+			column += deltaColumn
+		}
+
+		var modifiers []string
+		for bit, name := range tokens.TokenModifiers {
+			if tokens.Data[i+4]&(1<<bit) != 0 {
+				modifiers = append(modifiers, name)
+			}
+		}
+		text := lines[line][column : column+length]
+		decoded = append(decoded, text+":"+tokens.TokenTypes[tokens.Data[i+3]]+":"+strings.Join(modifiers, ","))
+	}
+	return decoded
+}
+
+func TestSemanticTokens(t *testing.T) {
+	tempDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "go.mod"), []byte("module example.com/tokens\n\ngo 1.21\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "main.go"), []byte(semanticTokensSource), 0644))
+
+	fileInfo, err := NewPackagesAnalyzer(tempDir, nil).AnalyzeSingleFileWithPackages(context.Background(), "main.go")
+	require.NoError(t, err)
+	require.NotNil(t, fileInfo.SemanticTokens)
+	assert.Equal(t, TokenTypes, fileInfo.SemanticTokens.TokenTypes)
+
+	tokens := decodeTokens(t, fileInfo.SemanticTokens, semanticTokensSource)
+	assert.Equal(t, []string{
+		"main:package:definition",
+		`"fmt":string:`,
+		"limit:constant:definition,readonly",
+		"3:number:",
+		"Point:type:definition",
+		"X:field:definition",
+		"int:type:stdlib,external",
+		"Old:function:definition,deprecated",
+		"Max:function:definition",
+		"T:typeParameter:definition",
+		"int:type:stdlib,external",
+		"float64:type:stdlib,external",
+		"a:parameter:definition",
+		"b:parameter:definition",
+		"T:typeParameter:",
+		"T:typeParameter:",
+		"a:parameter:",
+		"b:parameter:",
+		"a:parameter:",
+		"b:parameter:",
+		"main:function:definition",
+		"p:variable:definition",
+		"Point:type:",
+		"X:field:",
+		"limit:constant:readonly",
+		"outer:label:definition",
+		"outer:label:",
+		"Old:function:deprecated",
+		"fmt:package:stdlib,external",
+		"Println:function:stdlib,external",
+		"p:variable:",
+		"X:field:",
+		"Max:function:",
+		"1:number:",
+		"2.5:number:",
+		"`a:string:",
+		"bc`:string:",
+		"nil:constant:readonly,stdlib,external",
+	}, tokens)
+}
+
+func TestSemanticTokensLegacyAnalyzer(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "test.go"), []byte(scopeTreeSource), 0644))
+
+	fileInfo, err := New().AnalyzeSingleFile(context.Background(), tmpDir, "test.go")
+	require.NoError(t, err)
+	require.NotNil(t, fileInfo.SemanticTokens)
+
+	tokens := decodeTokens(t, fileInfo.SemanticTokens, scopeTreeSource)
+	assert.Contains(t, tokens, "Run:method:definition")
+	assert.Contains(t, tokens, "a:parameter:definition")
+	assert.Contains(t, tokens, "tv:variable:definition")
+	assert.Contains(t, tokens, "make:function:stdlib,external")
+}
@@ -0,0 +1,185 @@
+package analyzer
+
+import (
+	"context"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+)
+
+// Kinds of declarations a definition can shadow
+const (
+	ShadowKindPackage   = "package"   // Package-level declaration, possibly in another file
+	ShadowKindImport    = "import"    // Imported package name
+	ShadowKindParameter = "parameter" // Parameter, result or receiver of an enclosing function
+	ShadowKindBlock     = "block"     // Declaration of an enclosing block
+)
+
+// Shadowing links a definition to the outer declaration it hides
+type Shadowing struct {
+	Kind         string `json:"kind"`                   // One of the ShadowKind constants
+	DefinitionID string `json:"definitionId,omitempty"` // Shadowed definition, if declared in the same file
+	File         string `json:"file"`                   // File of the shadowed declaration, relative to the repository root
+	Line         int    `json:"line"`
+	Column       int    `json:"column"`
+}
+
+// ShadowedDefinition is a definition shadowing an outer declaration, as listed in a
+// shadowing report
+type ShadowedDefinition struct {
+	File string `json:"file"` // File of the definition, relative to the repository root
+	*Definition
+}
+
+// linkShadowing sets Shadows on the definitions of file hiding a declaration of an
+// enclosing scope. byObject holds the definition of each object defined in the file,
+// and relFile converts file names to paths relative to the repository root.
+func linkShadowing(file *ast.File, fset *token.FileSet, info *types.Info, byObject map[types.Object]*Definition, relFile func(filename string) string) {
+	params := parameterObjects(file, info)
+
+	for obj, def := range byObject {
+		if def.Shadows != nil {
+			continue // Type switch variables are defined by one object per clause
+		}
+		outer := shadowedObject(obj)
+		if outer == nil {
+			continue
+		}
+
+		pos := fset.Position(outer.Pos())
+		shadowing := &Shadowing{
+			Kind:   ShadowKindBlock,
+			File:   relFile(pos.Filename),
+			Line:   pos.Line,
+			Column: pos.Column,
+		}
+		switch {
+		case isPackageLevel(outer):
+			shadowing.Kind = ShadowKindPackage
+			if _, ok := outer.(*types.PkgName); ok {
+				shadowing.Kind = ShadowKindImport
+			}
+		case params[outer]:
+			shadowing.Kind = ShadowKindParameter
+		}
+		if outerDef := byObject[outer]; outerDef != nil {
+			shadowing.DefinitionID = outerDef.ID
+		}
+		def.Shadows = shadowing
+	}
+}
+
+// shadowedObject returns the declaration of an enclosing scope hidden by obj, or nil.
+// Predeclared identifiers are not reported, nor are fields, methods and labels, which
+// do not live in lexical scopes. Function scopes are children of the file scope, so
+// imports are found like any declaration of an enclosing scope.
+func shadowedObject(obj types.Object) types.Object {
+	if obj.Name() == "_" || obj.Parent() == nil || isPackageLevel(obj) {
+		return nil
+	}
+	outerScope := obj.Parent().Parent()
+	if outerScope == nil {
+		return nil
+	}
+	_, outer := outerScope.LookupParent(obj.Name(), obj.Pos())
+	if outer == nil || outer.Parent() == types.Universe {
+		return nil
+	}
+	return outer
+}
+
+// parameterObjects returns the parameters, results and receivers of the functions and
+// closures of file
+func parameterObjects(file *ast.File, info *types.Info) map[types.Object]bool {
+	params := make(map[types.Object]bool)
+	addFields := func(fields *ast.FieldList) {
+		if fields == nil {
+			return
+		}
+		for _, field := range fields.List {
+			for _, name := range field.Names {
+				if obj := info.Defs[name]; obj != nil {
+					params[obj] = true
+				}
+			}
+		}
+	}
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.FuncDecl:
+			addFields(node.Recv)
+		case *ast.FuncType:
+			addFields(node.Params)
+			addFields(node.Results)
+		}
+		return true
+	})
+	return params
+}
+
+// ShadowingReport lists the definitions of the module shadowing an outer declaration,
+// only the ones named name if it is not empty. The module is loaded if needed.
+func (pa *PackagesAnalyzer) ShadowingReport(ctx context.Context, name string) ([]*ShadowedDefinition, error) {
+	moduleLoad, err := pa.LoadModule(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := make([]*ShadowedDefinition, 0)
+	for _, pkg := range moduleLoad.Packages {
+		if pkg.TypesInfo == nil {
+			continue
+		}
+		for i, file := range pkg.Syntax {
+			if i >= len(pkg.CompiledGoFiles) || !pa.inRepository(pkg.CompiledGoFiles[i]) {
+				continue
+			}
+			relPath, err := filepath.Rel(pa.rootDir, pkg.CompiledGoFiles[i])
+			if err != nil {
+				continue
+			}
+
+			fileInfo := &FileInfo{Symbols: make(map[string]*Symbol)}
+			pa.extractFileSymbolsAndReferences(file, pkg, fileInfo)
+			for _, def := range fileInfo.Definitions {
+				if def.Shadows != nil && (name == "" || def.Name == name) {
+					report = append(report, &ShadowedDefinition{File: filepath.ToSlash(relPath), Definition: def})
+				}
+			}
+		}
+	}
+	return report, nil
+}
+
+// ShadowingReport lists the definitions shadowing an outer declaration in every module
+// of the repository at repoPath, only the ones named name if it is not empty, sorted
+// by file and position
+func (a *PackageAnalyzer) ShadowingReport(ctx context.Context, repoPath, name string) ([]*ShadowedDefinition, error) {
+	analyzers, err := a.repositoryAnalyzers(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	report := make([]*ShadowedDefinition, 0)
+	for _, packagesAnalyzer := range analyzers {
+		shadowed, err := packagesAnalyzer.ShadowingReport(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		report = append(report, shadowed...)
+	}
+
+	sort.SliceStable(report, func(i, j int) bool {
+		if report[i].File != report[j].File {
+			return report[i].File < report[j].File
+		}
+		if report[i].Line != report[j].Line {
+			return report[i].Line < report[j].Line
+		}
+		return report[i].Column < report[j].Column
+	})
+	return report, nil
+}
@@ -0,0 +1,138 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gonav/internal/testutil"
+)
+
+// shadowingModule is a repository with shadowed declarations of every kind and a
+// nested module
+var shadowingModule = map[string]string{
+	"go.mod": "module example.com/shadow\n\ngo 1.21\n",
+	"config.go": `package shadow
+
+var timeout = 10
+`,
+	"shadow.go": `package shadow
+
+import (
+	"errors"
+	"strings"
+)
+
+func run(name string) (err error) {
+	timeout := 5
+	if name := strings.TrimSpace(name); name != "" {
+		err := errors.New(name)
+		_ = err
+	}
+	for _, err := range []error{err} {
+		_ = err
+	}
+	switch err := err.(type) {
+	case nil:
+		_ = err
+	}
+	check := func(timeout int) bool { return timeout > 0 }
+	strings := []string{}
+	{
+		later := 1
+		_ = later
+	}
+	later := 2
+	len := 3
+	_, _, _, _, _ = timeout, check, strings, later, len
+	return nil
+}
+`,
+	"tools/go.mod": "module example.com/shadow/tools\n\ngo 1.21\n",
+	"tools/tools.go": `package tools
+
+func Do() (err error) {
+	if err := Do(); err != nil {
+		return err
+	}
+	return nil
+}
+`,
+}
+
+func TestShadowingDetection(t *testing.T) {
+	dir := testutil.TempFiles(t, shadowingModule)
+
+	fileInfo, err := NewPackagesAnalyzer(dir, nil).AnalyzeSingleFileWithPackages(context.Background(), "shadow.go")
+	require.NoError(t, err)
+
+	byID := make(map[string]*Definition)
+	shadows := make(map[int]*Definition) // Line -> shadowing definition
+	for _, def := range fileInfo.Definitions {
+		byID[def.ID] = def
+		if def.Shadows != nil {
+			_, exists := shadows[def.Line]
+			require.False(t, exists, "one shadowing definition per line")
+			shadows[def.Line] = def
+		}
+	}
+
+	expected := map[int]struct {
+		name, kind string
+		line       int // Line of the shadowed declaration
+		file       string
+	}{
+		9:  {"timeout", ShadowKindPackage, 3, "config.go"},
+		10: {"name", ShadowKindParameter, 8, "shadow.go"},
+		11: {"err", ShadowKindParameter, 8, "shadow.go"},
+		14: {"err", ShadowKindParameter, 8, "shadow.go"},
+		17: {"err", ShadowKindParameter, 8, "shadow.go"},
+		21: {"timeout", ShadowKindBlock, 9, "shadow.go"},
+		22: {"strings", ShadowKindImport, 5, "shadow.go"},
+	}
+	for line, want := range expected {
+		def := shadows[line]
+		if !assert.NotNil(t, def, "line %d", line) {
+			continue
+		}
+		assert.Equal(t, want.name, def.Name, "line %d", line)
+		assert.Equal(t, want.kind, def.Shadows.Kind, "line %d", line)
+		assert.Equal(t, want.file, def.Shadows.File, "line %d", line)
+		assert.Equal(t, want.line, def.Shadows.Line, "line %d", line)
+
+		// Imports without an explicit name have no definition
+		if want.file == "shadow.go" && want.kind != ShadowKindImport {
+			shadowed := byID[def.Shadows.DefinitionID]
+			require.NotNil(t, shadowed, "line %d", line)
+			assert.Equal(t, want.name, shadowed.Name)
+			assert.Equal(t, want.line, shadowed.Line)
+		} else {
+			assert.Empty(t, def.Shadows.DefinitionID)
+		}
+	}
+	// Declarations after the inner scope and predeclared identifiers are not shadowed
+	assert.Len(t, shadows, len(expected))
+}
+
+func TestShadowingReport(t *testing.T) {
+	dir := testutil.TempFiles(t, shadowingModule)
+	analyzer := New()
+	analyzer.SetRepositoryContext(dir, nil)
+
+	report, err := analyzer.ShadowingReport(context.Background(), dir, "err")
+	require.NoError(t, err)
+
+	locations := make([]string, 0, len(report))
+	for _, def := range report {
+		assert.Equal(t, "err", def.Name)
+		locations = append(locations, fmt.Sprintf("%s:%d", def.File, def.Line))
+	}
+	assert.Equal(t, []string{"shadow.go:11", "shadow.go:14", "shadow.go:17", "tools/tools.go:4"}, locations)
+
+	all, err := analyzer.ShadowingReport(context.Background(), dir, "")
+	require.NoError(t, err)
+	assert.Len(t, all, 8)
+}
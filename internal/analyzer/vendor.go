@@ -0,0 +1,131 @@
+package analyzer
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// VendorInfo describes the modules copied into a vendor directory, as recorded in
+// vendor/modules.txt by "go mod vendor"
+type VendorInfo struct {
+	Modules  map[string]string // module path -> version
+	Packages map[string]string // package import path -> module path
+}
+
+// HasVendorModules returns true if moduleDir has a vendor directory with a modules.txt
+func HasVendorModules(moduleDir string) bool {
+	_, err := os.Stat(filepath.Join(moduleDir, "vendor", "modules.txt"))
+	return err == nil
+}
+
+// ParseVendorModules reads vendor/modules.txt of the module at moduleDir
+func ParseVendorModules(moduleDir string) (*VendorInfo, error) {
+	file, err := os.Open(filepath.Join(moduleDir, "vendor", "modules.txt"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info := &VendorInfo{
+		Modules:  make(map[string]string),
+		Packages: make(map[string]string),
+	}
+
+	// Module lines are "# path version" or "# path [version] => replacement [version]",
+	// followed by "## " marker lines and one line per vendored package
+	currentModule := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "## "):
+			continue
+		case strings.HasPrefix(line, "# "):
+			fields := strings.Fields(line[2:])
+			if len(fields) == 0 {
+				continue
+			}
+			currentModule = fields[0]
+			version := ""
+			if len(fields) > 1 && fields[1] != "=>" {
+				version = fields[1]
+			}
+			info.Modules[currentModule] = version
+		case currentModule != "":
+			info.Packages[line] = currentModule
+		}
+	}
+
+	return info, scanner.Err()
+}
+
+// ModuleForPackage returns the module path and version of a vendored package
+func (v *VendorInfo) ModuleForPackage(importPath string) (string, string, bool) {
+	if modulePath, exists := v.Packages[importPath]; exists {
+		return modulePath, v.Modules[modulePath], true
+	}
+
+	// Packages that are not listed still belong to the longest matching module
+	bestMatch := ""
+	for modulePath := range v.Modules {
+		if (importPath == modulePath || strings.HasPrefix(importPath, modulePath+"/")) && len(modulePath) > len(bestMatch) {
+			bestMatch = modulePath
+		}
+	}
+	if bestMatch == "" {
+		return "", "", false
+	}
+	return bestMatch, v.Modules[bestMatch], true
+}
+
+// vendorEnv returns env with -mod=vendor added to GOFLAGS, replacing any other -mod flag
+func vendorEnv(env []string) []string {
+	if env == nil {
+		env = os.Environ()
+	}
+
+	result := make([]string, 0, len(env)+1)
+	goFlags := make([]string, 0)
+	for _, variable := range env {
+		if value, found := strings.CutPrefix(variable, "GOFLAGS="); found {
+			// Later values win, as with the environment of exec.Cmd
+			goFlags = goFlags[:0]
+			for _, flag := range strings.Fields(value) {
+				if !strings.HasPrefix(flag, "-mod=") {
+					goFlags = append(goFlags, flag)
+				}
+			}
+			continue
+		}
+		result = append(result, variable)
+	}
+
+	goFlags = append(goFlags, "-mod=vendor")
+	return append(result, "GOFLAGS="+strings.Join(goFlags, " "))
+}
+
+// vendoredModule returns the module path and version of a file in the vendor directory
+// of the module being analyzed
+func (pa *PackagesAnalyzer) vendoredModule(filename string) (string, string, bool) {
+	if pa.vendor == nil {
+		return "", "", false
+	}
+
+	relPath, err := filepath.Rel(filepath.Join(pa.config.Dir, "vendor"), filename)
+	if err != nil || strings.HasPrefix(relPath, "..") {
+		return "", "", false
+	}
+	return pa.vendor.ModuleForPackage(filepath.ToSlash(filepath.Dir(relPath)))
+}
+
+// vendorForDir returns the vendor directory containing relDir and its vendored modules
+func vendorForDir(vendors map[string]*VendorInfo, relDir string) (string, *VendorInfo) {
+	for vendorDir, info := range vendors {
+		if strings.HasPrefix(relDir, vendorDir+"/") {
+			return vendorDir, info
+		}
+	}
+	return "", nil
+}
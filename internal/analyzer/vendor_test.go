@@ -0,0 +1,153 @@
+package analyzer
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gonav/internal/logging"
+	"gonav/internal/testutil"
+)
+
+// vendoredRepo is a module whose only dependency is vendored
+var vendoredRepo = map[string]string{
+	"go.mod": `module example.com/app
+
+go 1.21
+
+require example.com/lib v1.2.0
+`,
+	"main.go": `package main
+
+import "example.com/lib/greet"
+
+func main() {
+	greet.Hello()
+}
+`,
+	"vendor/modules.txt": `# example.com/lib v1.2.0
+## explicit; go 1.21
+example.com/lib/greet
+`,
+	"vendor/example.com/lib/greet/greet.go": `package greet
+
+// Hello greets
+func Hello() string { return "hello" }
+`,
+}
+
+func TestParseVendorModules(t *testing.T) {
+	repoDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(repoDir, "vendor"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(repoDir, "vendor", "modules.txt"), []byte(`# example.com/lib v1.2.0
+## explicit; go 1.21
+example.com/lib
+example.com/lib/sub
+# example.com/old v1.0.0 => example.com/new v1.1.0
+## explicit
+example.com/old/pkg
+# example.com/local => ../local
+example.com/local
+`), 0644))
+
+	assert.True(t, HasVendorModules(repoDir))
+	assert.False(t, HasVendorModules(t.TempDir()))
+
+	info, err := ParseVendorModules(repoDir)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"example.com/lib":   "v1.2.0",
+		"example.com/old":   "v1.0.0",
+		"example.com/local": "",
+	}, info.Modules)
+	assert.Equal(t, "example.com/lib", info.Packages["example.com/lib/sub"])
+
+	modulePath, version, ok := info.ModuleForPackage("example.com/old/pkg")
+	require.True(t, ok)
+	assert.Equal(t, "example.com/old", modulePath)
+	assert.Equal(t, "v1.0.0", version)
+
+	// Unlisted packages fall back to the enclosing module
+	modulePath, _, ok = info.ModuleForPackage("example.com/lib/internal/x")
+	require.True(t, ok)
+	assert.Equal(t, "example.com/lib", modulePath)
+
+	_, _, ok = info.ModuleForPackage("example.com/other")
+	assert.False(t, ok)
+}
+
+func TestVendorEnv(t *testing.T) {
+	env := vendorEnv([]string{"HOME=/home", "GOFLAGS=-mod=mod -trimpath", "GOPROXY=off"})
+	assert.Equal(t, []string{"HOME=/home", "GOPROXY=off", "GOFLAGS=-trimpath -mod=vendor"}, env)
+
+	env = vendorEnv([]string{"HOME=/home"})
+	assert.Equal(t, []string{"HOME=/home", "GOFLAGS=-mod=vendor"}, env)
+}
+
+func TestAnalyzeVendoredRepository(t *testing.T) {
+	repoDir := testutil.TempFiles(t, vendoredRepo)
+
+	// Vendored analysis must not need the network or a module cache
+	env := append(os.Environ(), "GOPROXY=off", "GOFLAGS=-mod=mod", "GOMODCACHE="+t.TempDir())
+	analyzer := New()
+	analyzer.SetRepositoryContext(repoDir, env)
+
+	fileInfo, err := analyzer.AnalyzeSingleFile(context.Background(), repoDir, "main.go")
+	require.NoError(t, err)
+
+	var helloRef *Reference
+	for _, ref := range fileInfo.References {
+		if ref.Name == "Hello" {
+			helloRef = ref
+		}
+	}
+	require.NotNil(t, helloRef)
+	require.NotNil(t, helloRef.Target)
+
+	// The reference navigates into the vendor tree, labeled with its module version
+	assert.Equal(t, "vendor/example.com/lib/greet/greet.go", helloRef.Target.File)
+	assert.Equal(t, 4, helloRef.Target.Line)
+	assert.True(t, helloRef.Target.IsVendored)
+	assert.True(t, helloRef.Target.IsExternal)
+	assert.Equal(t, "example.com/lib", helloRef.Target.Module)
+	assert.Equal(t, "v1.2.0", helloRef.Target.Version)
+	assert.Equal(t, "example.com/lib/greet", helloRef.Target.ImportPath)
+	assert.NotContains(t, helloRef.Target.Package, "@")
+
+	// Vendored packages are discovered with their import path
+	packages, err := analyzer.DiscoverPackages(repoDir)
+	require.NoError(t, err)
+	require.Contains(t, packages, "vendor/example.com/lib/greet")
+	vendored := packages["vendor/example.com/lib/greet"]
+	assert.True(t, vendored.Vendored)
+	assert.Equal(t, "example.com/lib", vendored.Module)
+	assert.Equal(t, "example.com/lib/greet", vendored.ImportPath)
+	assert.False(t, packages[""].Vendored)
+}
+
+func TestVendorParseErrorLogged(t *testing.T) {
+	dir := testutil.TempFiles(t, map[string]string{
+		"go.mod":                      "module example.com/app\n\ngo 1.21\n",
+		"main.go":                     "package main\n\nfunc main() {}\n",
+		"vendor/modules.txt/unusable": "",
+	})
+
+	var output bytes.Buffer
+	logger, err := logging.New(&output, "warn", logging.FormatJSON)
+	require.NoError(t, err)
+
+	// The parse error is reported with the analyzer's logger and the loading request
+	pa := NewPackagesAnalyzer(dir, nil)
+	pa.SetLogger(logger)
+	assert.Nil(t, pa.vendor)
+	assert.Empty(t, output.String())
+
+	_, _ = pa.LoadModule(logging.WithRequestID(context.Background(), "req-1"))
+	assert.Contains(t, output.String(), `"msg":"Failed to parse vendor/modules.txt, loading from the module cache"`)
+	assert.Contains(t, output.String(), `"request_id":"req-1"`)
+}
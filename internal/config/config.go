@@ -0,0 +1,178 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"gonav/internal/env"
+)
+
+// Config holds the server configuration
+type Config struct {
+	// Env configures the Go toolchain used for module downloads and analysis
+	Env env.Config `json:"env"`
+
+	// Cache bounds the disk space used by downloaded modules
+	Cache CacheConfig `json:"cache"`
+
+	// Timeouts bounds the duration of slow operations done for a request
+	Timeouts TimeoutConfig `json:"timeouts"`
+
+	// Log configures the verbosity and format of the server logs
+	Log LogConfig `json:"log"`
+
+	// Admin configures the administration endpoints
+	Admin AdminConfig `json:"admin"`
+}
+
+// CacheConfig holds the module cache location and eviction settings
+type CacheConfig struct {
+	Dir        string `json:"dir,omitempty"`        // Base directory, the system temp directory by default
+	Persistent bool   `json:"persistent,omitempty"` // Keep downloaded modules when the server stops
+
+	MaxSizeMB int64    `json:"maxSizeMB,omitempty"` // Maximum cache size in megabytes, 0 for no limit
+	TTL       Duration `json:"ttl,omitempty"`       // Maximum time since last access, 0 for no limit
+	Interval  Duration `json:"interval,omitempty"`  // Time between eviction passes
+}
+
+// TimeoutConfig holds per-operation timeouts, 0 for no limit
+type TimeoutConfig struct {
+	Download Duration `json:"download,omitempty"` // Downloading a repository with go mod download or git
+	Versions Duration `json:"versions,omitempty"` // Listing the versions of a module
+	Analysis Duration `json:"analysis,omitempty"` // Loading and type-checking the packages of a module
+}
+
+// LogConfig holds the logging settings
+type LogConfig struct {
+	Level  string `json:"level,omitempty"`  // "debug", "info", "warn" or "error"
+	Format string `json:"format,omitempty"` // "text" or "json"
+}
+
+// AdminConfig holds the settings of the administration endpoints
+type AdminConfig struct {
+	Token string `json:"token,omitempty"` // Bearer token required by the endpoints, none if empty
+}
+
+// Duration is a time.Duration written as a string such as "24h" in configuration files
+type Duration time.Duration
+
+// MarshalJSON encodes the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON decodes a duration string such as "90m"
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("duration must be a string such as \"24h\": %w", err)
+	}
+	parsed, err := time.ParseDuration(text)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Default returns the configuration used when no file or flags are given
+func Default() *Config {
+	return &Config{
+		Cache: CacheConfig{
+			Interval: Duration(10 * time.Minute),
+		},
+		Timeouts: TimeoutConfig{
+			Download: Duration(5 * time.Minute),
+			Versions: Duration(30 * time.Second),
+			Analysis: Duration(2 * time.Minute),
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "text",
+		},
+	}
+}
+
+// Load reads a JSON configuration file on top of the default configuration
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file: %w", err)
+	}
+
+	cfg := Default()
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("error parsing config file %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// Parse builds the configuration from command line arguments. Settings from the
+// file given with -config are overridden by flags set explicitly on the command line.
+// It returns the remaining non-flag arguments.
+func Parse(name string, args []string) (*Config, []string, error) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	configPath := fs.String("config", "", "path to a JSON configuration file")
+
+	// Parse into a scratch configuration first, so we know which flags were set
+	flagValues := Default()
+	bindFlags(fs, flagValues)
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	cfg := Default()
+	if *configPath != "" {
+		loaded, err := Load(*configPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		cfg = loaded
+	}
+
+	// Re-apply explicitly set flags on top of the file configuration
+	overrides := flag.NewFlagSet(name, flag.ContinueOnError)
+	bindFlags(overrides, cfg)
+	var setErr error
+	fs.Visit(func(f *flag.Flag) {
+		if overrides.Lookup(f.Name) == nil || setErr != nil {
+			return
+		}
+		setErr = overrides.Set(f.Name, f.Value.String())
+	})
+	if setErr != nil {
+		return nil, nil, setErr
+	}
+
+	return cfg, fs.Args(), nil
+}
+
+// bindFlags registers the command line flags that map onto cfg
+func bindFlags(fs *flag.FlagSet, cfg *Config) {
+	fs.StringVar(&cfg.Env.GoProxy, "goproxy", cfg.Env.GoProxy, "GOPROXY used for module downloads")
+	fs.StringVar(&cfg.Env.GoPrivate, "goprivate", cfg.Env.GoPrivate, "GOPRIVATE patterns of private modules")
+	fs.StringVar(&cfg.Env.GoNoProxy, "gonoproxy", cfg.Env.GoNoProxy, "GONOPROXY patterns fetched without the proxy")
+	fs.StringVar(&cfg.Env.GoNoSumDB, "gonosumdb", cfg.Env.GoNoSumDB, "GONOSUMDB patterns excluded from checksum verification")
+	fs.StringVar(&cfg.Env.GoSumDB, "gosumdb", cfg.Env.GoSumDB, "GOSUMDB checksum database (\"off\" to disable)")
+	fs.StringVar(&cfg.Env.GoInsecure, "goinsecure", cfg.Env.GoInsecure, "GOINSECURE patterns allowed over plain HTTP")
+	fs.StringVar(&cfg.Env.GoFlags, "goflags", cfg.Env.GoFlags, "GOFLAGS for go commands run by the server")
+	fs.StringVar(&cfg.Env.GoAuth, "goauth", cfg.Env.GoAuth, "GOAUTH credential helper for private module hosts")
+	fs.StringVar(&cfg.Env.NetrcFile, "netrc", cfg.Env.NetrcFile, ".netrc file with credentials for private module hosts")
+	fs.BoolVar(&cfg.Env.Offline, "offline", cfg.Env.Offline, "never access the network; serve modules from -proxy-dir or the local module cache")
+	fs.StringVar(&cfg.Env.ProxyDir, "proxy-dir", cfg.Env.ProxyDir, "local module proxy directory used in offline mode")
+	fs.StringVar(&cfg.Cache.Dir, "cache-dir", cfg.Cache.Dir, "base directory for downloaded modules and repositories")
+	fs.BoolVar(&cfg.Cache.Persistent, "persistent-cache", cfg.Cache.Persistent, "keep downloaded modules across restarts (remove them with the clean command)")
+	fs.Int64Var(&cfg.Cache.MaxSizeMB, "cache-max-size-mb", cfg.Cache.MaxSizeMB, "maximum size of the module cache in megabytes (0 for no limit)")
+	fs.DurationVar((*time.Duration)(&cfg.Cache.TTL), "cache-ttl", time.Duration(cfg.Cache.TTL), "evict cached modules not accessed for this long (0 for no limit)")
+	fs.DurationVar((*time.Duration)(&cfg.Cache.Interval), "cache-interval", time.Duration(cfg.Cache.Interval), "time between cache eviction passes")
+	fs.DurationVar((*time.Duration)(&cfg.Timeouts.Download), "download-timeout", time.Duration(cfg.Timeouts.Download), "maximum time to download a repository (0 for no limit)")
+	fs.DurationVar((*time.Duration)(&cfg.Timeouts.Versions), "versions-timeout", time.Duration(cfg.Timeouts.Versions), "maximum time to list the versions of a module (0 for no limit)")
+	fs.DurationVar((*time.Duration)(&cfg.Timeouts.Analysis), "analysis-timeout", time.Duration(cfg.Timeouts.Analysis), "maximum time to load and type-check packages (0 for no limit)")
+	fs.StringVar(&cfg.Log.Level, "log-level", cfg.Log.Level, "minimum level of logged messages: debug, info, warn or error")
+	fs.StringVar(&cfg.Log.Format, "log-format", cfg.Log.Format, "log format: text or json")
+	fs.StringVar(&cfg.Admin.Token, "admin-token", cfg.Admin.Token, "bearer token required by the /api/admin/ endpoints (none if empty)")
+}
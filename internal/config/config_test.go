@@ -0,0 +1,98 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gonav.json")
+	content := `{
+  "env": {
+    "goproxy": "https://proxy.internal.example.com,direct",
+    "goprivate": "git.example.com/*",
+    "gonosumdb": "git.example.com/*",
+    "netrc": "/etc/gonav/netrc"
+  },
+  "cache": {
+    "maxSizeMB": 2048,
+    "ttl": "24h"
+  }
+}`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, "https://proxy.internal.example.com,direct", cfg.Env.GoProxy)
+	assert.Equal(t, "git.example.com/*", cfg.Env.GoPrivate)
+	assert.Equal(t, "git.example.com/*", cfg.Env.GoNoSumDB)
+	assert.Equal(t, "/etc/gonav/netrc", cfg.Env.NetrcFile)
+	assert.Equal(t, int64(2048), cfg.Cache.MaxSizeMB)
+	assert.Equal(t, Duration(24*time.Hour), cfg.Cache.TTL)
+	assert.Equal(t, Duration(10*time.Minute), cfg.Cache.Interval) // Default kept
+	assert.Equal(t, Duration(2*time.Minute), cfg.Timeouts.Analysis)
+	assert.Equal(t, LogConfig{Level: "info", Format: "text"}, cfg.Log)
+}
+
+func TestLoad_Errors(t *testing.T) {
+	_, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+
+	path := filepath.Join(t.TempDir(), "invalid.json")
+	require.NoError(t, os.WriteFile(path, []byte("{not json"), 0644))
+	_, err = Load(path)
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"cache": {"ttl": 3600}}`), 0644))
+	_, err = Load(path)
+	assert.Error(t, err)
+}
+
+func TestParse(t *testing.T) {
+	// Flags alone
+	cfg, rest, err := Parse("gonav", []string{"-goproxy", "off", "-gosumdb", "off"})
+	require.NoError(t, err)
+	assert.Equal(t, "off", cfg.Env.GoProxy)
+	assert.Equal(t, "off", cfg.Env.GoSumDB)
+	assert.Empty(t, rest)
+
+	// Explicit flags override the file, unset flags keep the file values
+	path := filepath.Join(t.TempDir(), "gonav.json")
+	content := `{"env": {"goproxy": "https://file-proxy.example.com", "goprivate": "git.example.com/*"}}`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	cfg, rest, err = Parse("gonav", []string{"-config", path, "-goproxy", "https://flag-proxy.example.com", "extra"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://flag-proxy.example.com", cfg.Env.GoProxy)
+	assert.Equal(t, "git.example.com/*", cfg.Env.GoPrivate)
+	assert.Equal(t, []string{"extra"}, rest)
+
+	cfg, _, err = Parse("gonav", []string{"-cache-ttl", "90m", "-cache-max-size-mb", "512"})
+	require.NoError(t, err)
+	assert.Equal(t, Duration(90*time.Minute), cfg.Cache.TTL)
+	assert.Equal(t, int64(512), cfg.Cache.MaxSizeMB)
+
+	cfg, _, err = Parse("gonav", []string{"-cache-dir", "/var/cache/gonav", "-persistent-cache"})
+	require.NoError(t, err)
+	assert.Equal(t, "/var/cache/gonav", cfg.Cache.Dir)
+	assert.True(t, cfg.Cache.Persistent)
+
+	cfg, _, err = Parse("gonav", []string{"-download-timeout", "10m", "-analysis-timeout", "0"})
+	require.NoError(t, err)
+	assert.Equal(t, Duration(10*time.Minute), cfg.Timeouts.Download)
+	assert.Equal(t, Duration(30*time.Second), cfg.Timeouts.Versions)
+	assert.Equal(t, Duration(0), cfg.Timeouts.Analysis)
+
+	cfg, _, err = Parse("gonav", []string{"-log-level", "debug", "-log-format", "json"})
+	require.NoError(t, err)
+	assert.Equal(t, LogConfig{Level: "debug", Format: "json"}, cfg.Log)
+
+	cfg, _, err = Parse("gonav", []string{"-admin-token", "s3cret"})
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", cfg.Admin.Token)
+}
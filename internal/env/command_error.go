@@ -0,0 +1,52 @@
+package env
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// CommandError reports a go command that failed, with what it printed about the failure
+type CommandError struct {
+	Command string // Command line, e.g. "go mod download -json example.com/lib@v1.0.0"
+	Stderr  string // Standard error, and the error reported in the -json output if any
+	Err     error
+}
+
+func (e *CommandError) Error() string {
+	if e.Stderr == "" {
+		return fmt.Sprintf("%s: %v", e.Command, e.Err)
+	}
+	return fmt.Sprintf("%s: %v: %s", e.Command, e.Err, e.Stderr)
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// NewCommandError builds the CommandError of cmd, which failed with err after
+// writing output to its standard output
+func NewCommandError(cmd *exec.Cmd, output []byte, err error) *CommandError {
+	var messages []string
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if stderr := strings.TrimSpace(string(exitErr.Stderr)); stderr != "" {
+			messages = append(messages, stderr)
+		}
+	}
+
+	// With -json the go command reports module errors in its output instead of stderr
+	var reported struct{ Error string }
+	if json.Unmarshal(output, &reported) == nil && reported.Error != "" {
+		messages = append(messages, reported.Error)
+	}
+
+	return &CommandError{
+		Command: strings.Join(cmd.Args, " "),
+		Stderr:  strings.Join(messages, "\n"),
+		Err:     err,
+	}
+}
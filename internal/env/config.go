@@ -0,0 +1,102 @@
+package env
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Config holds the Go toolchain settings of an isolated environment.
+// Empty fields keep the value inherited from the host environment.
+type Config struct {
+	GoProxy    string `json:"goproxy,omitempty"`    // Module proxy chain (GOPROXY)
+	GoPrivate  string `json:"goprivate,omitempty"`  // Private module patterns (GOPRIVATE)
+	GoNoProxy  string `json:"gonoproxy,omitempty"`  // Patterns fetched directly (GONOPROXY)
+	GoNoSumDB  string `json:"gonosumdb,omitempty"`  // Patterns excluded from checksum verification (GONOSUMDB)
+	GoSumDB    string `json:"gosumdb,omitempty"`    // Checksum database, or "off" (GOSUMDB)
+	GoInsecure string `json:"goinsecure,omitempty"` // Patterns allowed over plain HTTP (GOINSECURE)
+	GoFlags    string `json:"goflags,omitempty"`    // Default flags for go commands (GOFLAGS)
+	GoAuth     string `json:"goauth,omitempty"`     // Credential helper for module fetches (GOAUTH)
+	NetrcFile  string `json:"netrc,omitempty"`      // .netrc file with host credentials (NETRC)
+
+	// Offline disables all network access of the go command. Modules are then only
+	// served from ProxyDir if set, or from the local module cache otherwise.
+	Offline bool `json:"offline,omitempty"`
+
+	// ProxyDir is a local module proxy tree, used as a file:// GOPROXY in offline mode
+	ProxyDir string `json:"proxyDir,omitempty"`
+}
+
+// Validate checks that the files referenced by the configuration exist
+func (c Config) Validate() error {
+	if c.NetrcFile != "" {
+		if _, err := os.Stat(c.NetrcFile); err != nil {
+			return fmt.Errorf("netrc file is not accessible: %w", err)
+		}
+	}
+	if c.Offline && c.ProxyDir != "" {
+		if info, err := os.Stat(c.ProxyDir); err != nil || !info.IsDir() {
+			return fmt.Errorf("offline proxy directory is not accessible: %s", c.ProxyDir)
+		}
+	}
+	return nil
+}
+
+// OfflineProxy returns the GOPROXY value used in offline mode
+func (c Config) OfflineProxy() string {
+	if c.ProxyDir == "" {
+		return "off"
+	}
+	absDir, err := filepath.Abs(c.ProxyDir)
+	if err != nil {
+		absDir = c.ProxyDir
+	}
+	return "file://" + filepath.ToSlash(absDir)
+}
+
+// Variables returns the environment variables set by this configuration
+func (c Config) Variables() map[string]string {
+	vars := make(map[string]string)
+	set := func(key, value string) {
+		if value != "" {
+			vars[key] = value
+		}
+	}
+
+	set("GOPROXY", c.GoProxy)
+	set("GOPRIVATE", c.GoPrivate)
+	set("GONOPROXY", c.GoNoProxy)
+	set("GONOSUMDB", c.GoNoSumDB)
+	set("GOSUMDB", c.GoSumDB)
+	set("GOINSECURE", c.GoInsecure)
+	set("GOFLAGS", c.GoFlags)
+	set("GOAUTH", c.GoAuth)
+	set("NETRC", c.NetrcFile)
+
+	if c.Offline {
+		// Nothing may reach the network: modules, checksums or toolchains
+		vars["GOPROXY"] = c.OfflineProxy()
+		if c.GoSumDB == "" {
+			vars["GOSUMDB"] = "off"
+		}
+		vars["GOTOOLCHAIN"] = "local"
+		// Private patterns would otherwise still be fetched directly from version control
+		vars["GONOPROXY"] = "none"
+		vars["GOVCS"] = "*:off"
+	}
+
+	return vars
+}
+
+// setEnv returns environ with key set to value, replacing any existing entry
+func setEnv(environ []string, key, value string) []string {
+	result := make([]string, 0, len(environ)+1)
+	for _, entry := range environ {
+		if strings.HasPrefix(entry, key+"=") {
+			continue
+		}
+		result = append(result, entry)
+	}
+	return append(result, key+"="+value)
+}
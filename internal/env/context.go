@@ -0,0 +1,31 @@
+package env
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// WithTimeout bounds ctx by timeout. A zero timeout leaves ctx unbounded.
+func WithTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// ContextError returns an error describing why operation stopped if ctx is done,
+// wrapping context.DeadlineExceeded or context.Canceled, and err otherwise. Commands
+// killed by their context only report "signal: killed", which is not helpful.
+func ContextError(ctx context.Context, operation string, timeout time.Duration, err error) error {
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		if timeout > 0 {
+			return fmt.Errorf("%s timed out after %v: %w", operation, timeout, context.DeadlineExceeded)
+		}
+		return fmt.Errorf("%s timed out: %w", operation, context.DeadlineExceeded)
+	case context.Canceled:
+		return fmt.Errorf("%s canceled: %w", operation, context.Canceled)
+	}
+	return err
+}
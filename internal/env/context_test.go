@@ -0,0 +1,52 @@
+package env
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gonav/internal/testutil"
+)
+
+func TestContextError(t *testing.T) {
+	original := errors.New("signal: killed")
+
+	// A live context keeps the original error
+	assert.Equal(t, original, ContextError(context.Background(), "download", time.Minute, original))
+
+	ctx, cancel := WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	err := ContextError(ctx, "download of example.com/lib@v1.0.0", time.Nanosecond, original)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "download of example.com/lib@v1.0.0 timed out after 1ns")
+
+	ctx, cancel = WithTimeout(context.Background(), 0)
+	cancel()
+	err = ContextError(ctx, "download", 0, original)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Contains(t, err.Error(), "download canceled")
+}
+
+func TestIsolatedEnv_DownloadModuleCanceled(t *testing.T) {
+	proxyDir := t.TempDir()
+	testutil.WriteProxyModule(t, proxyDir, "example.com/lib", "v1.0.0")
+	testutil.UseLocalProxy(t, proxyDir)
+
+	env, err := NewIsolated(t.TempDir())
+	require.NoError(t, err)
+	defer env.Cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = env.DownloadModule(ctx, "example.com/lib@v1.0.0")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = env.ListVersions(ctx, "example.com/lib")
+	assert.ErrorIs(t, err, context.Canceled)
+}
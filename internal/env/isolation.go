@@ -1,11 +1,19 @@
 package env
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
 )
 
 // IsolatedEnv provides an isolated Go environment for module operations
@@ -14,6 +22,7 @@ type IsolatedEnv struct {
 	GoModCache string
 	GoCache    string
 	GoPath     string
+	config     Config
 	env        []string
 }
 
@@ -29,13 +38,30 @@ type GoModDownloadInfo struct {
 	GoModSum string `json:"GoModSum"` // GoMod checksum
 }
 
+// GoListVersionsInfo represents the JSON output from 'go list -m -versions -json'
+type GoListVersionsInfo struct {
+	Path     string   `json:"Path"`     // Module path
+	Versions []string `json:"Versions"` // Tagged versions known to the module proxy
+}
+
 // NewIsolated creates a new isolated Go environment
 func NewIsolated(baseDir string) (*IsolatedEnv, error) {
+	return NewIsolatedWithConfig(baseDir, Config{})
+}
+
+// NewIsolatedWithConfig creates a new isolated Go environment with the given
+// toolchain settings. The settings only apply to commands run in this environment.
+func NewIsolatedWithConfig(baseDir string, config Config) (*IsolatedEnv, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
 	env := &IsolatedEnv{
 		BaseDir:    baseDir,
 		GoModCache: filepath.Join(baseDir, "gomodcache"),
 		GoCache:    filepath.Join(baseDir, "gocache"),
 		GoPath:     filepath.Join(baseDir, "gopath"),
+		config:     config,
 	}
 
 	// Create directories
@@ -57,9 +83,19 @@ func NewIsolated(baseDir string) (*IsolatedEnv, error) {
 		"GO111MODULE=on",
 	)
 
+	// Configured settings replace the values inherited from the host
+	for key, value := range config.Variables() {
+		env.env = setEnv(env.env, key, value)
+	}
+
 	return env, nil
 }
 
+// Config returns the toolchain settings of this isolated environment
+func (e *IsolatedEnv) Config() Config {
+	return e.config
+}
+
 // Environment returns the environment variables for this isolated environment
 func (e *IsolatedEnv) Environment() []string {
 	return e.env
@@ -72,13 +108,24 @@ func (e *IsolatedEnv) ExecCommand(name string, args ...string) *exec.Cmd {
 	return cmd
 }
 
+// ExecCommandContext creates a command that will run in this isolated environment
+// and is killed when ctx is done
+func (e *IsolatedEnv) ExecCommandContext(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = e.env
+	return cmd
+}
+
 // DownloadModule downloads a module to the isolated cache and returns the directory path
-func (e *IsolatedEnv) DownloadModule(moduleAtVersion string) (*GoModDownloadInfo, error) {
-	cmd := e.ExecCommand("go", "mod", "download", "-json", moduleAtVersion)
-	
+func (e *IsolatedEnv) DownloadModule(ctx context.Context, moduleAtVersion string) (*GoModDownloadInfo, error) {
+	cmd := e.ExecCommandContext(ctx, "go", "mod", "download", "-json", moduleAtVersion)
+	// Run outside of any module so the host go.mod is neither used nor updated
+	cmd.Dir = e.BaseDir
+
 	output, err := cmd.Output()
 	if err != nil {
-		return nil, fmt.Errorf("go mod download failed for %s: %w", moduleAtVersion, err)
+		return nil, ContextError(ctx, "go mod download of "+moduleAtVersion, 0,
+			fmt.Errorf("go mod download failed for %s: %w", moduleAtVersion, NewCommandError(cmd, output, err)))
 	}
 
 	var downloadInfo GoModDownloadInfo
@@ -98,6 +145,68 @@ func (e *IsolatedEnv) DownloadModule(moduleAtVersion string) (*GoModDownloadInfo
 	return &downloadInfo, nil
 }
 
+// ListVersions returns the tagged versions of a module known to the configured module proxy
+func (e *IsolatedEnv) ListVersions(ctx context.Context, modulePath string) ([]string, error) {
+	cmd := e.ExecCommandContext(ctx, "go", "list", "-m", "-versions", "-json", modulePath)
+	// Run outside of any module so the host working directory cannot interfere
+	cmd.Dir = e.BaseDir
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, ContextError(ctx, "listing versions of "+modulePath, 0,
+			fmt.Errorf("go list -versions failed for %s: %w", modulePath, NewCommandError(cmd, output, err)))
+	}
+
+	var listInfo GoListVersionsInfo
+	if err := json.Unmarshal(output, &listInfo); err != nil {
+		return nil, fmt.Errorf("failed to parse go list output: %w", err)
+	}
+
+	return listInfo.Versions, nil
+}
+
+// CachedVersions returns the versions of a module present in the isolated download cache,
+// including pseudo-versions that are never listed by the module proxy
+func (e *IsolatedEnv) CachedVersions(modulePath string) ([]string, error) {
+	return ReadCachedVersions(e.GoModCache, modulePath)
+}
+
+// ReadCachedVersions lists the versions of a module recorded in the download cache of modCache
+func ReadCachedVersions(modCache, modulePath string) ([]string, error) {
+	escapedPath, err := module.EscapePath(modulePath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid module path %s: %w", modulePath, err)
+	}
+
+	versionDir := filepath.Join(modCache, "cache", "download", escapedPath, "@v")
+	entries, err := os.ReadDir(versionDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+
+	versions := make([]string, 0)
+	for _, entry := range entries {
+		name := entry.Name()
+		// Only versions with a downloaded zip have their sources in the cache;
+		// the go command also caches .info and .mod files for metadata queries
+		if entry.IsDir() || !strings.HasSuffix(name, ".zip") {
+			continue
+		}
+		// Cached file names use the escaped form of the version
+		version, err := module.UnescapeVersion(strings.TrimSuffix(name, ".zip"))
+		if err != nil || !semver.IsValid(version) {
+			continue
+		}
+		versions = append(versions, version)
+	}
+
+	semver.Sort(versions)
+	return versions, nil
+}
+
 // ModuleCachePath returns the path to a specific module in the cache
 func (e *IsolatedEnv) ModuleCachePath(modulePath, version string) string {
 	return filepath.Join(e.GoModCache, modulePath+"@"+version)
@@ -105,20 +214,26 @@ func (e *IsolatedEnv) ModuleCachePath(modulePath, version string) string {
 
 // Cleanup removes the isolated environment directory
 func (e *IsolatedEnv) Cleanup() error {
+	return RemoveDir(e.BaseDir)
+}
+
+// RemoveDir removes dir and everything below it, including the read-only files of
+// a module cache. It does nothing if dir does not exist.
+func RemoveDir(dir string) error {
 	// Go module cache may contain read-only files, so we need to make them writable first
-	err := filepath.Walk(e.BaseDir, func(path string, info os.FileInfo, err error) error {
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 		// Make files and directories writable so they can be removed
 		return os.Chmod(path, 0755)
 	})
-	if err != nil {
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
 		// If chmod fails, continue with removal anyway
-		fmt.Printf("Warning: failed to make files writable during cleanup: %v\n", err)
+		slog.Warn("Failed to make files writable during cleanup", "dir", dir, "error", err)
 	}
-	
-	return os.RemoveAll(e.BaseDir)
+
+	return os.RemoveAll(dir)
 }
 
 // Stats returns information about the isolated environment
@@ -128,17 +243,23 @@ func (e *IsolatedEnv) Stats() map[string]interface{} {
 	stats["gomodcache"] = e.GoModCache
 	stats["gocache"] = e.GoCache
 	stats["gopath"] = e.GoPath
+	if e.config.GoProxy != "" {
+		stats["goproxy"] = e.config.GoProxy
+	}
+	if e.config.GoPrivate != "" {
+		stats["goprivate"] = e.config.GoPrivate
+	}
 	
-	// Count modules in cache
-	if entries, err := os.ReadDir(e.GoModCache); err == nil {
-		moduleCount := 0
-		for _, entry := range entries {
-			if entry.IsDir() && filepath.Base(entry.Name()) != "cache" {
-				moduleCount++
-			}
+	// Report cache usage
+	if modules, err := e.CachedModules(); err == nil {
+		var moduleBytes int64
+		for _, mod := range modules {
+			moduleBytes += mod.Size
 		}
-		stats["cached_modules"] = moduleCount
+		stats["cached_modules"] = len(modules)
+		stats["module_cache_bytes"] = moduleBytes
 	}
+	stats["disk_usage_bytes"] = e.DiskUsage()
 	
 	return stats
 }
\ No newline at end of file
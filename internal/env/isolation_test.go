@@ -1,6 +1,7 @@
 package env
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -10,6 +11,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"gonav/internal/testutil"
 )
 
 func TestNewIsolated(t *testing.T) {
@@ -39,6 +42,57 @@ func TestNewIsolated(t *testing.T) {
 	}
 }
 
+func TestNewIsolatedWithConfig(t *testing.T) {
+	t.Setenv("GOPROXY", "https://host-proxy.example.com")
+	t.Setenv("GOPRIVATE", "")
+
+	netrc := filepath.Join(t.TempDir(), "netrc")
+	require.NoError(t, os.WriteFile(netrc, []byte("machine git.example.com login bot password secret\n"), 0600))
+
+	config := Config{
+		GoProxy:   "https://proxy.internal.example.com,direct",
+		GoPrivate: "git.example.com/*",
+		GoNoSumDB: "git.example.com/*",
+		GoFlags:   "-mod=mod",
+		NetrcFile: netrc,
+	}
+	env, err := NewIsolatedWithConfig(t.TempDir(), config)
+	require.NoError(t, err)
+	assert.Equal(t, config, env.Config())
+
+	// Configured values replace the inherited host values instead of being appended
+	envVars := env.Environment()
+	assert.Contains(t, envVars, "GOPROXY=https://proxy.internal.example.com,direct")
+	assert.NotContains(t, envVars, "GOPROXY=https://host-proxy.example.com")
+	assert.Contains(t, envVars, "GOPRIVATE=git.example.com/*")
+	assert.Contains(t, envVars, "GONOSUMDB=git.example.com/*")
+	assert.Contains(t, envVars, "NETRC="+netrc)
+
+	// The go command sees the configured settings
+	output, err := env.ExecCommand("go", "env", "GOPROXY", "GOPRIVATE", "GONOSUMDB").Output()
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"https://proxy.internal.example.com,direct",
+		"git.example.com/*",
+		"git.example.com/*",
+	}, strings.Fields(string(output)))
+
+	// The host environment is left untouched
+	assert.Equal(t, "https://host-proxy.example.com", os.Getenv("GOPROXY"))
+	assert.Equal(t, "", os.Getenv("GOPRIVATE"))
+
+	// Unset fields keep the host value
+	env, err = NewIsolatedWithConfig(t.TempDir(), Config{GoPrivate: "git.example.com/*"})
+	require.NoError(t, err)
+	assert.Contains(t, env.Environment(), "GOPROXY=https://host-proxy.example.com")
+}
+
+func TestNewIsolatedWithConfig_MissingNetrc(t *testing.T) {
+	_, err := NewIsolatedWithConfig(t.TempDir(), Config{NetrcFile: filepath.Join(t.TempDir(), "missing")})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "netrc")
+}
+
 func TestIsolatedEnv_ExecCommand(t *testing.T) {
 	tempDir, err := os.MkdirTemp("", "gonav-test-*")
 	require.NoError(t, err)
@@ -65,7 +119,7 @@ func TestIsolatedEnv_DownloadModule(t *testing.T) {
 	require.NoError(t, err)
 
 	// Test downloading a known module
-	downloadInfo, err := env.DownloadModule("github.com/arnodel/golua@v0.1.0")
+	downloadInfo, err := env.DownloadModule(context.Background(), "github.com/arnodel/golua@v0.1.0")
 	require.NoError(t, err)
 
 	// Verify download info
@@ -96,6 +150,41 @@ func TestIsolatedEnv_DownloadModule(t *testing.T) {
 	assert.True(t, hasGoFiles, "Module should contain .go files")
 }
 
+func TestIsolatedEnv_ListVersions(t *testing.T) {
+	proxyDir := t.TempDir()
+	testutil.WriteProxyModule(t, proxyDir, "example.com/versioned", "v1.1.0")
+	testutil.WriteProxyModule(t, proxyDir, "example.com/versioned", "v1.0.0")
+	testutil.WriteProxyModule(t, proxyDir, "example.com/versioned", "v0.0.0-20240101000000-abcdefabcdef")
+	testutil.UseLocalProxy(t, proxyDir)
+
+	env, err := NewIsolated(t.TempDir())
+	require.NoError(t, err)
+
+	// Only tagged versions are listed by the proxy, in semver order
+	versions, err := env.ListVersions(context.Background(), "example.com/versioned")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v1.0.0", "v1.1.0"}, versions)
+
+	// Nothing is cached before a download
+	cached, err := env.CachedVersions("example.com/versioned")
+	require.NoError(t, err)
+	assert.Empty(t, cached)
+
+	// Downloading a pseudo-version makes it show up in the cache
+	_, err = env.DownloadModule(context.Background(), "example.com/versioned@v0.0.0-20240101000000-abcdefabcdef")
+	require.NoError(t, err)
+	_, err = env.DownloadModule(context.Background(), "example.com/versioned@v1.0.0")
+	require.NoError(t, err)
+
+	cached, err = env.CachedVersions("example.com/versioned")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v0.0.0-20240101000000-abcdefabcdef", "v1.0.0"}, cached)
+
+	// Unknown modules are reported as errors by the proxy
+	_, err = env.ListVersions(context.Background(), "example.com/missing")
+	assert.Error(t, err)
+}
+
 func TestIsolatedEnv_ModuleCachePath(t *testing.T) {
 	tempDir, err := os.MkdirTemp("", "gonav-test-*")
 	require.NoError(t, err)
@@ -124,7 +213,7 @@ func TestIsolatedEnv_Stats(t *testing.T) {
 	assert.Equal(t, 0, stats["cached_modules"])
 
 	// Download a module and check stats again
-	_, err = env.DownloadModule("github.com/arnodel/golua@v0.1.0")
+	_, err = env.DownloadModule(context.Background(), "github.com/arnodel/golua@v0.1.0")
 	require.NoError(t, err)
 
 	stats = env.Stats()
@@ -167,7 +256,7 @@ func TestIsolatedEnv_HostIsolation(t *testing.T) {
 	require.NoError(t, err)
 
 	// Download module in isolation
-	_, err = env.DownloadModule("github.com/arnodel/golua@v0.1.0")
+	_, err = env.DownloadModule(context.Background(), "github.com/arnodel/golua@v0.1.0")
 	require.NoError(t, err)
 
 	// Verify host GOMODCACHE is unchanged
@@ -191,7 +280,7 @@ func TestIsolatedEnv_ErrorHandling(t *testing.T) {
 	require.NoError(t, err)
 
 	// Test downloading non-existent module
-	_, err = env.DownloadModule("github.com/nonexistent/fake-module@v1.0.0")
+	_, err = env.DownloadModule(context.Background(), "github.com/nonexistent/fake-module@v1.0.0")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "go mod download failed")
 }
@@ -208,7 +297,7 @@ func BenchmarkIsolatedDownload(b *testing.B) {
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		// Download the same module repeatedly (should be cached after first time)
-		_, err := env.DownloadModule("github.com/arnodel/golua@v0.1.0")
+		_, err := env.DownloadModule(context.Background(), "github.com/arnodel/golua@v0.1.0")
 		require.NoError(b, err)
 	}
 }
\ No newline at end of file
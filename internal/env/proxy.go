@@ -0,0 +1,319 @@
+package env
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/module"
+	modzip "golang.org/x/mod/zip"
+)
+
+// proxyInfo is the content of a module proxy .info file
+type proxyInfo struct {
+	Version string    `json:"Version"`
+	Time    time.Time `json:"Time"`
+}
+
+// ImportModuleZips adds every module zip found in srcDir to the local module proxy
+// tree at proxyDir, so it can be served with GOPROXY=file://. It returns the modules
+// that were imported.
+func ImportModuleZips(proxyDir, srcDir string) ([]module.Version, error) {
+	imported := make([]module.Version, 0)
+
+	err := filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".zip") {
+			return nil
+		}
+
+		mod, err := ImportModuleZip(proxyDir, path)
+		if err != nil {
+			return fmt.Errorf("failed to import %s: %w", path, err)
+		}
+		imported = append(imported, mod)
+		return nil
+	})
+
+	return imported, err
+}
+
+// ImportModuleZip adds a single module zip to the local module proxy tree at proxyDir.
+// The module path and version are taken from the zip's path@version/ prefix.
+func ImportModuleZip(proxyDir, zipPath string) (module.Version, error) {
+	reader, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return module.Version{}, err
+	}
+	defer reader.Close()
+
+	mod, err := zipModuleVersion(&reader.Reader)
+	if err != nil {
+		return module.Version{}, err
+	}
+
+	// Reject zips the go command would refuse to extract
+	if _, err := modzip.CheckZip(mod, zipPath); err != nil {
+		return module.Version{}, fmt.Errorf("invalid module zip for %s@%s: %w", mod.Path, mod.Version, err)
+	}
+
+	goMod := []byte(fmt.Sprintf("module %s\n", mod.Path))
+	if f, err := reader.Open(mod.Path + "@" + mod.Version + "/go.mod"); err == nil {
+		goMod, err = io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			return module.Version{}, err
+		}
+	}
+
+	info := proxyInfo{Version: mod.Version, Time: time.Now().UTC()}
+	if module.IsPseudoVersion(mod.Version) {
+		if pseudoTime, err := module.PseudoVersionTime(mod.Version); err == nil {
+			info.Time = pseudoTime
+		}
+	}
+	infoData, err := json.Marshal(info)
+	if err != nil {
+		return module.Version{}, err
+	}
+
+	zipData, err := os.ReadFile(zipPath)
+	if err != nil {
+		return module.Version{}, err
+	}
+
+	return mod, writeProxyVersion(proxyDir, mod, infoData, goMod, zipData)
+}
+
+// ExportToProxy copies every module downloaded in this environment into the local
+// module proxy tree at proxyDir. Modules already present in the tree are kept.
+// It returns the number of module versions copied.
+func (e *IsolatedEnv) ExportToProxy(proxyDir string) (int, error) {
+	copied := 0
+
+	err := walkDownloadedModules(e.GoModCache, func(mod module.Version, path string) error {
+		added, err := exportVersion(proxyDir, mod, path)
+		if added {
+			copied++
+		}
+		return err
+	})
+
+	return copied, err
+}
+
+// ExportModuleToProxy copies a module version downloaded in this environment into the
+// local module proxy tree at proxyDir, with the downloaded modules listed in the go.sum
+// file of its directory moduleDir. Modules already present in the tree are kept.
+// It returns the number of module versions copied.
+func (e *IsolatedEnv) ExportModuleToProxy(proxyDir string, mod module.Version, moduleDir string) (int, error) {
+	mods := append([]module.Version{mod}, goSumModules(filepath.Join(moduleDir, "go.sum"))...)
+
+	copied := 0
+	for _, mod := range mods {
+		zipPath, err := e.downloadedZip(mod)
+		if err != nil {
+			continue // Only the go.mod of the dependency was needed
+		}
+		added, err := exportVersion(proxyDir, mod, zipPath)
+		if err != nil {
+			return copied, err
+		}
+		if added {
+			copied++
+		}
+	}
+	return copied, nil
+}
+
+// downloadedZip returns the path of the zip of a module version in the download cache,
+// or an error if it was not downloaded
+func (e *IsolatedEnv) downloadedZip(mod module.Version) (string, error) {
+	escapedPath, err := module.EscapePath(mod.Path)
+	if err != nil {
+		return "", err
+	}
+	escapedVersion, err := module.EscapeVersion(mod.Version)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(e.GoModCache, "cache", "download", filepath.FromSlash(escapedPath), "@v", escapedVersion+".zip")
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// goSumModules returns the module versions whose content is checksummed in the go.sum
+// file at path, without the ones only listed for their go.mod
+func goSumModules(path string) []module.Version {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	seen := make(map[module.Version]bool)
+	mods := make([]module.Version, 0)
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.HasSuffix(fields[1], "/go.mod") {
+			continue
+		}
+		mod := module.Version{Path: fields[0], Version: fields[1]}
+		if !seen[mod] {
+			seen[mod] = true
+			mods = append(mods, mod)
+		}
+	}
+	return mods
+}
+
+// exportVersion copies the module version downloaded at zipPath, next to its .info and
+// .mod files, into the proxy tree at proxyDir. It reports whether it was copied, which
+// it is not when already present or when the download is incomplete.
+func exportVersion(proxyDir string, mod module.Version, zipPath string) (bool, error) {
+	base := strings.TrimSuffix(zipPath, ".zip")
+	if _, err := os.Stat(filepath.Join(proxyVersionDir(proxyDir, mod), filepath.Base(base)+".zip")); err == nil {
+		return false, nil
+	}
+
+	infoData, err := os.ReadFile(base + ".info")
+	if err != nil {
+		return false, nil // Incomplete download, skip
+	}
+	goMod, err := os.ReadFile(base + ".mod")
+	if err != nil {
+		return false, nil
+	}
+	zipData, err := os.ReadFile(zipPath)
+	if err != nil {
+		return false, err
+	}
+
+	if err := writeProxyVersion(proxyDir, mod, infoData, goMod, zipData); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// zipModuleVersion determines the module path and version from the entries of a module zip
+func zipModuleVersion(reader *zip.Reader) (module.Version, error) {
+	if len(reader.File) == 0 {
+		return module.Version{}, fmt.Errorf("empty module zip")
+	}
+
+	// Every entry starts with path@version/; module paths cannot contain '@'
+	name := reader.File[0].Name
+	at := strings.Index(name, "@")
+	slash := -1
+	if at >= 0 {
+		slash = strings.Index(name[at:], "/")
+	}
+	if at < 0 || slash < 0 {
+		return module.Version{}, fmt.Errorf("zip entry %q is not prefixed with module@version/", name)
+	}
+	slash += at
+
+	mod := module.Version{Path: name[:at], Version: name[at+1 : slash]}
+	if err := module.Check(mod.Path, mod.Version); err != nil {
+		return module.Version{}, err
+	}
+	return mod, nil
+}
+
+// proxyVersionDir returns the @v directory of a module in a proxy tree
+func proxyVersionDir(proxyDir string, mod module.Version) string {
+	escapedPath, err := module.EscapePath(mod.Path)
+	if err != nil {
+		escapedPath = mod.Path
+	}
+	return filepath.Join(proxyDir, filepath.FromSlash(escapedPath), "@v")
+}
+
+// writeProxyVersion writes the .mod, .zip and .info files of a module version and
+// records tagged versions in the module's version list. Each file is replaced
+// atomically, and the .info file and list entry come last so that readers never
+// see a version whose zip is missing or truncated.
+func writeProxyVersion(proxyDir string, mod module.Version, info, goMod, zipData []byte) error {
+	escapedVersion, err := module.EscapeVersion(mod.Version)
+	if err != nil {
+		return err
+	}
+
+	versionDir := proxyVersionDir(proxyDir, mod)
+	if err := os.MkdirAll(versionDir, 0755); err != nil {
+		return err
+	}
+
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{escapedVersion + ".mod", goMod},
+		{escapedVersion + ".zip", zipData},
+		{escapedVersion + ".info", info},
+	}
+	for _, file := range files {
+		if err := writeFileAtomic(filepath.Join(versionDir, file.name), file.data); err != nil {
+			return err
+		}
+	}
+
+	// Pseudo-versions are resolvable but never listed, like on a real proxy
+	if module.IsPseudoVersion(mod.Version) {
+		return nil
+	}
+
+	listPath := filepath.Join(versionDir, "list")
+	existing, err := os.ReadFile(listPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, line := range strings.Split(string(existing), "\n") {
+		if strings.TrimSpace(line) == mod.Version {
+			return nil
+		}
+	}
+
+	list := string(existing)
+	if list != "" && !strings.HasSuffix(list, "\n") {
+		list += "\n"
+	}
+	return writeFileAtomic(listPath, []byte(list+mod.Version+"\n"))
+}
+
+// writeFileAtomic writes data to a temporary file in the directory of path and
+// renames it into place, so that path holds either its old or its new content
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package env
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/mod/module"
+
+	"gonav/internal/testutil"
+)
+
+func TestImportModuleZips(t *testing.T) {
+	// Use the zips of a source proxy as the directory to import
+	srcDir := t.TempDir()
+	testutil.WriteProxyModule(t, srcDir, "example.com/lib", "v1.0.0")
+	testutil.WriteProxyModule(t, srcDir, "example.com/lib", "v1.1.0")
+	testutil.WriteProxyModule(t, srcDir, "example.com/other", "v0.0.0-20240101000000-abcdefabcdef")
+
+	proxyDir := t.TempDir()
+	imported, err := ImportModuleZips(proxyDir, srcDir)
+	require.NoError(t, err)
+	assert.Len(t, imported, 3)
+
+	list, err := os.ReadFile(filepath.Join(proxyDir, "example.com", "lib", "@v", "list"))
+	require.NoError(t, err)
+	assert.Equal(t, "v1.0.0\nv1.1.0\n", string(list))
+
+	// Pseudo-versions are stored but not listed
+	otherDir := filepath.Join(proxyDir, "example.com", "other", "@v")
+	assert.FileExists(t, filepath.Join(otherDir, "v0.0.0-20240101000000-abcdefabcdef.zip"))
+	assert.FileExists(t, filepath.Join(otherDir, "v0.0.0-20240101000000-abcdefabcdef.info"))
+	assert.NoFileExists(t, filepath.Join(otherDir, "list"))
+
+	goMod, err := os.ReadFile(filepath.Join(proxyDir, "example.com", "lib", "@v", "v1.1.0.mod"))
+	require.NoError(t, err)
+	assert.Contains(t, string(goMod), "module example.com/lib")
+
+	// Files are renamed into place, no temporary file is left behind
+	entries, err := os.ReadDir(filepath.Join(proxyDir, "example.com", "lib", "@v"))
+	require.NoError(t, err)
+	for _, entry := range entries {
+		assert.NotContains(t, entry.Name(), ".tmp-")
+	}
+
+	// Importing again must not duplicate list entries
+	_, err = ImportModuleZips(proxyDir, srcDir)
+	require.NoError(t, err)
+	list, err = os.ReadFile(filepath.Join(proxyDir, "example.com", "lib", "@v", "list"))
+	require.NoError(t, err)
+	assert.Equal(t, "v1.0.0\nv1.1.0\n", string(list))
+
+	// The imported tree serves downloads in offline mode
+	env, err := NewIsolatedWithConfig(t.TempDir(), Config{Offline: true, ProxyDir: proxyDir})
+	require.NoError(t, err)
+	defer env.Cleanup()
+
+	info, err := env.DownloadModule(context.Background(), "example.com/lib@v1.1.0")
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(info.Dir, "lib.go"))
+
+	_, err = env.DownloadModule(context.Background(), "example.com/missing@v1.0.0")
+	assert.Error(t, err)
+}
+
+func TestImportModuleZip_Invalid(t *testing.T) {
+	srcDir := t.TempDir()
+	zipPath := filepath.Join(srcDir, "broken.zip")
+	require.NoError(t, os.WriteFile(zipPath, []byte("not a zip"), 0644))
+
+	_, err := ImportModuleZip(t.TempDir(), zipPath)
+	assert.Error(t, err)
+}
+
+func TestIsolatedEnv_ExportToProxy(t *testing.T) {
+	srcProxy := t.TempDir()
+	testutil.WriteProxyModule(t, srcProxy, "example.com/lib", "v1.0.0")
+	testutil.UseLocalProxy(t, srcProxy)
+
+	env, err := NewIsolated(t.TempDir())
+	require.NoError(t, err)
+	defer env.Cleanup()
+
+	_, err = env.DownloadModule(context.Background(), "example.com/lib@v1.0.0")
+	require.NoError(t, err)
+
+	proxyDir := t.TempDir()
+	count, err := env.ExportToProxy(proxyDir)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.FileExists(t, filepath.Join(proxyDir, "example.com", "lib", "@v", "v1.0.0.zip"))
+
+	// Versions already in the proxy are skipped
+	count, err = env.ExportToProxy(proxyDir)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestIsolatedEnv_ExportModuleToProxy(t *testing.T) {
+	srcProxy := t.TempDir()
+	for _, path := range []string{"example.com/app", "example.com/dep", "example.com/other"} {
+		testutil.WriteProxyModule(t, srcProxy, path, "v1.0.0")
+	}
+	testutil.UseLocalProxy(t, srcProxy)
+
+	env, err := NewIsolated(t.TempDir())
+	require.NoError(t, err)
+	defer env.Cleanup()
+
+	for _, path := range []string{"example.com/app", "example.com/dep", "example.com/other"} {
+		_, err = env.DownloadModule(context.Background(), path+"@v1.0.0")
+		require.NoError(t, err)
+	}
+
+	// Only the module and the dependencies of its go.sum are copied
+	moduleDir := t.TempDir()
+	testutil.WriteFiles(t, moduleDir, map[string]string{
+		"go.sum": "example.com/dep v1.0.0 h1:x=\nexample.com/dep v1.0.0/go.mod h1:y=\nexample.com/mod v1.0.0/go.mod h1:z=\n",
+	})
+	proxyDir := t.TempDir()
+	count, err := env.ExportModuleToProxy(proxyDir, module.Version{Path: "example.com/app", Version: "v1.0.0"}, moduleDir)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.FileExists(t, filepath.Join(proxyDir, "example.com", "app", "@v", "v1.0.0.zip"))
+	assert.FileExists(t, filepath.Join(proxyDir, "example.com", "dep", "@v", "v1.0.0.zip"))
+	assert.NoDirExists(t, filepath.Join(proxyDir, "example.com", "other"))
+}
+
+func TestConfig_Offline(t *testing.T) {
+	vars := Config{Offline: true}.Variables()
+	assert.Equal(t, "off", vars["GOPROXY"])
+	assert.Equal(t, "off", vars["GOSUMDB"])
+	assert.Equal(t, "local", vars["GOTOOLCHAIN"])
+	assert.Equal(t, "none", vars["GONOPROXY"])
+	assert.Equal(t, "*:off", vars["GOVCS"])
+
+	// Private patterns, configured or inherited from the host, are not fetched directly
+	vars = Config{Offline: true, GoPrivate: "github.com/x/*", GoNoProxy: "github.com/y/*"}.Variables()
+	assert.Equal(t, "none", vars["GONOPROXY"])
+	assert.Equal(t, "*:off", vars["GOVCS"])
+
+	t.Setenv("GOPRIVATE", "github.com/x/*")
+	t.Setenv("GONOPROXY", "github.com/x/*")
+	t.Setenv("GOVCS", "*:git")
+	env, err := NewIsolatedWithConfig(t.TempDir(), Config{Offline: true})
+	require.NoError(t, err)
+	defer env.Cleanup()
+	assert.Contains(t, env.Environment(), "GONOPROXY=none")
+	assert.Contains(t, env.Environment(), "GOVCS=*:off")
+	assert.NotContains(t, env.Environment(), "GONOPROXY=github.com/x/*")
+	assert.NotContains(t, env.Environment(), "GOVCS=*:git")
+
+	proxyDir := t.TempDir()
+	vars = Config{Offline: true, ProxyDir: proxyDir, GoProxy: "https://proxy.golang.org"}.Variables()
+	assert.Equal(t, "file://"+filepath.ToSlash(proxyDir), vars["GOPROXY"])
+
+	err = Config{Offline: true, ProxyDir: filepath.Join(proxyDir, "missing")}.Validate()
+	assert.Error(t, err)
+}
@@ -0,0 +1,163 @@
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/module"
+)
+
+// CachedModule describes a module version stored in the isolated module cache
+type CachedModule struct {
+	Path     string    `json:"path"`
+	Version  string    `json:"version"`
+	Dir      string    `json:"dir"`      // Extracted sources, may not exist yet
+	Size     int64     `json:"size"`     // Bytes used by the sources and download files
+	Modified time.Time `json:"modified"` // When the module was downloaded or extracted
+}
+
+// CachedModules returns every module version with downloaded sources in the isolated cache
+func (e *IsolatedEnv) CachedModules() ([]CachedModule, error) {
+	modules := make([]CachedModule, 0)
+
+	err := walkDownloadedModules(e.GoModCache, func(mod module.Version, zipPath string) error {
+		cached := CachedModule{
+			Path:    mod.Path,
+			Version: mod.Version,
+			Dir:     e.moduleDir(mod),
+		}
+
+		// Count the download files (.info, .mod, .zip, .ziphash, ...) of this version
+		base := strings.TrimSuffix(zipPath, ".zip")
+		if matches, err := filepath.Glob(base + ".*"); err == nil {
+			for _, match := range matches {
+				if info, err := os.Stat(match); err == nil {
+					cached.Size += info.Size()
+					if info.ModTime().After(cached.Modified) {
+						cached.Modified = info.ModTime()
+					}
+				}
+			}
+		}
+
+		if info, err := os.Stat(cached.Dir); err == nil {
+			cached.Size += DirSize(cached.Dir)
+			if info.ModTime().After(cached.Modified) {
+				cached.Modified = info.ModTime()
+			}
+		}
+
+		modules = append(modules, cached)
+		return nil
+	})
+
+	return modules, err
+}
+
+// RemoveModule deletes the sources and download files of a module version from the
+// isolated cache. The go command downloads it again when it is next needed.
+func (e *IsolatedEnv) RemoveModule(modulePath, version string) error {
+	mod := module.Version{Path: modulePath, Version: version}
+	escapedVersion, err := module.EscapeVersion(version)
+	if err != nil {
+		return err
+	}
+	escapedPath, err := module.EscapePath(modulePath)
+	if err != nil {
+		return err
+	}
+
+	// Extracted sources are read-only
+	if err := removeAllWritable(e.moduleDir(mod)); err != nil {
+		return err
+	}
+
+	versionDir := filepath.Join(e.GoModCache, "cache", "download", filepath.FromSlash(escapedPath), "@v")
+	matches, err := filepath.Glob(filepath.Join(versionDir, escapedVersion+".*"))
+	if err != nil {
+		return err
+	}
+	for _, match := range matches {
+		if err := os.Remove(match); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// DiskUsage returns the number of bytes used by the isolated environment
+func (e *IsolatedEnv) DiskUsage() int64 {
+	return DirSize(e.BaseDir)
+}
+
+// moduleDir returns the directory holding the extracted sources of a module version
+func (e *IsolatedEnv) moduleDir(mod module.Version) string {
+	escapedPath, err := module.EscapePath(mod.Path)
+	if err != nil {
+		escapedPath = mod.Path
+	}
+	escapedVersion, err := module.EscapeVersion(mod.Version)
+	if err != nil {
+		escapedVersion = mod.Version
+	}
+	return filepath.Join(e.GoModCache, filepath.FromSlash(escapedPath)+"@"+escapedVersion)
+}
+
+// walkDownloadedModules calls fn for every module version with a downloaded zip in
+// the download cache of modCache
+func walkDownloadedModules(modCache string, fn func(mod module.Version, zipPath string) error) error {
+	downloadDir := filepath.Join(modCache, "cache", "download")
+
+	return filepath.Walk(downloadDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(path, ".zip") || filepath.Base(filepath.Dir(path)) != "@v" {
+			return nil
+		}
+
+		// Layout is <escaped module path>/@v/<escaped version>.zip
+		escapedPath, err := filepath.Rel(downloadDir, filepath.Dir(filepath.Dir(path)))
+		if err != nil {
+			return err
+		}
+		modulePath, err := module.UnescapePath(filepath.ToSlash(escapedPath))
+		if err != nil {
+			return nil
+		}
+		version, err := module.UnescapeVersion(strings.TrimSuffix(info.Name(), ".zip"))
+		if err != nil {
+			return nil
+		}
+
+		return fn(module.Version{Path: modulePath, Version: version}, path)
+	})
+}
+
+// DirSize returns the total size of the regular files below dir
+func DirSize(dir string) int64 {
+	var size int64
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// removeAllWritable removes dir after making its contents writable
+func removeAllWritable(dir string) error {
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil {
+			os.Chmod(path, 0755)
+		}
+		return nil
+	})
+	return os.RemoveAll(dir)
+}
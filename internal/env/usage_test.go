@@ -0,0 +1,65 @@
+package env
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gonav/internal/testutil"
+)
+
+func TestIsolatedEnv_CachedModules(t *testing.T) {
+	proxyDir := t.TempDir()
+	testutil.WriteProxyModule(t, proxyDir, "example.com/lib", "v1.0.0")
+	testutil.WriteProxyModule(t, proxyDir, "example.com/Upper", "v1.2.0")
+	testutil.UseLocalProxy(t, proxyDir)
+
+	env, err := NewIsolated(t.TempDir())
+	require.NoError(t, err)
+	defer env.Cleanup()
+
+	modules, err := env.CachedModules()
+	require.NoError(t, err)
+	assert.Empty(t, modules)
+
+	_, err = env.DownloadModule(context.Background(), "example.com/lib@v1.0.0")
+	require.NoError(t, err)
+	info, err := env.DownloadModule(context.Background(), "example.com/Upper@v1.2.0")
+	require.NoError(t, err)
+
+	modules, err = env.CachedModules()
+	require.NoError(t, err)
+	require.Len(t, modules, 2)
+	for _, mod := range modules {
+		assert.Greater(t, mod.Size, int64(0))
+		assert.False(t, mod.Modified.IsZero())
+		if mod.Path == "example.com/Upper" {
+			// Module paths with upper case letters are escaped on disk
+			assert.Equal(t, info.Dir, mod.Dir)
+		}
+	}
+
+	stats := env.Stats()
+	assert.Equal(t, 2, stats["cached_modules"])
+	assert.Greater(t, stats["module_cache_bytes"], int64(0))
+	assert.GreaterOrEqual(t, stats["disk_usage_bytes"], stats["module_cache_bytes"])
+
+	// Removing a module deletes its sources and download files
+	require.NoError(t, env.RemoveModule("example.com/Upper", "v1.2.0"))
+	assert.NoDirExists(t, info.Dir)
+
+	modules, err = env.CachedModules()
+	require.NoError(t, err)
+	require.Len(t, modules, 1)
+	assert.Equal(t, "example.com/lib", modules[0].Path)
+
+	versions, err := env.CachedVersions("example.com/Upper")
+	require.NoError(t, err)
+	assert.Empty(t, versions)
+
+	// A removed module can be downloaded again
+	_, err = env.DownloadModule(context.Background(), "example.com/Upper@v1.2.0")
+	require.NoError(t, err)
+}
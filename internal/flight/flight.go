@@ -0,0 +1,137 @@
+// Package flight coalesces concurrent calls doing the same work into a single
+// in-flight operation whose result is shared by every caller.
+package flight
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+// Group runs at most one operation per key at a time
+type Group[T any] struct {
+	mutex sync.Mutex
+	calls map[string]*call[T]
+}
+
+// call is an operation in flight and the callers waiting for it
+type call[T any] struct {
+	done     chan struct{}
+	cancel   context.CancelFunc
+	waiters  int
+	shared   bool
+	canceled bool // Every caller left, fn is stopping
+
+	value T
+	err   error
+}
+
+// Do runs fn for key unless an operation for key is already in flight, in which case
+// it waits for that operation instead. Every caller receives the same result, and
+// shared reports whether it went to more than one caller.
+//
+// fn runs with a context that keeps the values of the first caller's ctx but is only
+// canceled when every waiting caller is gone, so one client leaving does not fail
+// the others. A caller whose ctx is done returns ctx.Err() without waiting. The
+// operation stays in flight until fn returns, so a caller arriving after it was
+// canceled waits for fn to return before running it again, and fn never runs twice
+// at once for the same key.
+func (g *Group[T]) Do(ctx context.Context, key string, fn func(ctx context.Context) (T, error)) (value T, err error, shared bool) {
+	if err := ctx.Err(); err != nil {
+		return value, err, false
+	}
+
+	g.mutex.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call[T])
+	}
+	c, exists := g.calls[key]
+	for exists && c.canceled {
+		g.mutex.Unlock()
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			return value, ctx.Err(), false
+		}
+		g.mutex.Lock()
+		c, exists = g.calls[key]
+	}
+	if exists {
+		c.shared = true
+	} else {
+		callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		c = &call[T]{done: make(chan struct{}), cancel: cancel}
+		g.calls[key] = c
+		go g.run(callCtx, key, c, fn)
+	}
+	c.waiters++
+	g.mutex.Unlock()
+
+	select {
+	case <-c.done:
+		g.mutex.Lock()
+		defer g.mutex.Unlock()
+		return c.value, c.err, c.shared
+	case <-ctx.Done():
+		g.mutex.Lock()
+		defer g.mutex.Unlock()
+		c.waiters--
+		if c.waiters == 0 {
+			// Nobody is waiting anymore: stop the work, later callers start afresh
+			// once fn returns
+			c.canceled = true
+			c.cancel()
+		}
+		var zero T
+		return zero, ctx.Err(), c.shared
+	}
+}
+
+// InFlight returns the number of operations currently running
+func (g *Group[T]) InFlight() int {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return len(g.calls)
+}
+
+// PanicError is the error returned to the callers of an operation that panicked.
+// Its message only holds the panic value, the stack is kept for server logs.
+type PanicError struct {
+	Value any    // Value passed to panic
+	Stack []byte // Stack of the panicking goroutine
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// run executes the operation of c and publishes its result. A panic in fn is
+// returned to the callers as a *PanicError instead of crashing the process.
+func (g *Group[T]) run(ctx context.Context, key string, c *call[T], fn func(ctx context.Context) (T, error)) {
+	var value T
+	var err error
+	defer func() {
+		if r := recover(); r != nil {
+			var zero T
+			value, err = zero, &PanicError{Value: r, Stack: debug.Stack()}
+		}
+
+		g.mutex.Lock()
+		c.value, c.err = value, err
+		g.forget(key, c)
+		g.mutex.Unlock()
+
+		c.cancel()
+		close(c.done)
+	}()
+
+	value, err = fn(ctx)
+}
+
+// forget removes c from the calls in flight. The caller must hold the mutex.
+func (g *Group[T]) forget(key string, c *call[T]) {
+	if g.calls[key] == c {
+		delete(g.calls, key)
+	}
+}
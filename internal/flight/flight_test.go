@@ -0,0 +1,224 @@
+package flight
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroup_Do(t *testing.T) {
+	var group Group[string]
+	var runs atomic.Int32
+	release := make(chan struct{})
+
+	fn := func(ctx context.Context) (string, error) {
+		runs.Add(1)
+		<-release
+		return "loaded", nil
+	}
+
+	const callers = 3
+	var wg sync.WaitGroup
+	results := make([]string, callers)
+	sharedResults := make([]bool, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			value, err, shared := group.Do(context.Background(), "gin@v1.9.1", fn)
+			assert.NoError(t, err)
+			results[i] = value
+			sharedResults[i] = shared
+		}(i)
+	}
+
+	// Wait until every caller joined the operation in flight
+	require.Eventually(t, func() bool {
+		group.mutex.Lock()
+		defer group.mutex.Unlock()
+		c := group.calls["gin@v1.9.1"]
+		return c != nil && c.waiters == callers
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, 1, group.InFlight())
+
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), runs.Load())
+	assert.Equal(t, []string{"loaded", "loaded", "loaded"}, results)
+	assert.Equal(t, []bool{true, true, true}, sharedResults)
+	assert.Equal(t, 0, group.InFlight())
+
+	// Later calls run again
+	value, err, shared := group.Do(context.Background(), "gin@v1.9.1", func(ctx context.Context) (string, error) {
+		return "reloaded", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "reloaded", value)
+	assert.False(t, shared)
+}
+
+func TestGroup_DoError(t *testing.T) {
+	var group Group[int]
+	failure := errors.New("download failed")
+
+	_, err, _ := group.Do(context.Background(), "key", func(ctx context.Context) (int, error) {
+		return 0, failure
+	})
+	assert.ErrorIs(t, err, failure)
+}
+
+func TestGroup_DoPanic(t *testing.T) {
+	var group Group[string]
+	release := make(chan struct{})
+
+	fn := func(ctx context.Context) (string, error) {
+		<-release
+		panic("corrupt module zip")
+	}
+
+	const callers = 2
+	var wg sync.WaitGroup
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i], _ = group.Do(context.Background(), "key", fn)
+		}(i)
+	}
+
+	require.Eventually(t, func() bool {
+		group.mutex.Lock()
+		defer group.mutex.Unlock()
+		c := group.calls["key"]
+		return c != nil && c.waiters == callers
+	}, time.Second, time.Millisecond)
+
+	close(release)
+	wg.Wait()
+
+	// Every waiter receives the panic as an error and the key is released
+	for _, err := range errs {
+		var panicErr *PanicError
+		require.ErrorAs(t, err, &panicErr)
+		assert.Equal(t, "corrupt module zip", panicErr.Value)
+		assert.Equal(t, "panic: corrupt module zip", panicErr.Error())
+		assert.NotEmpty(t, panicErr.Stack)
+	}
+	assert.Equal(t, 0, group.InFlight())
+
+	value, err, _ := group.Do(context.Background(), "key", func(ctx context.Context) (string, error) {
+		return "loaded", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "loaded", value)
+}
+
+func TestGroup_DoCanceled(t *testing.T) {
+	var group Group[string]
+	started := make(chan struct{})
+	stopped := make(chan error, 1)
+
+	fn := func(ctx context.Context) (string, error) {
+		close(started)
+		<-ctx.Done()
+		stopped <- ctx.Err()
+		return "", ctx.Err()
+	}
+
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	secondCtx, cancelSecond := context.WithCancel(context.Background())
+
+	firstDone := make(chan error, 1)
+	go func() {
+		_, err, _ := group.Do(firstCtx, "key", fn)
+		firstDone <- err
+	}()
+	<-started
+
+	secondDone := make(chan error, 1)
+	go func() {
+		_, err, _ := group.Do(secondCtx, "key", fn)
+		secondDone <- err
+	}()
+	require.Eventually(t, func() bool {
+		group.mutex.Lock()
+		defer group.mutex.Unlock()
+		return group.calls["key"].waiters == 2
+	}, time.Second, time.Millisecond)
+
+	// One caller leaving does not stop the work for the other
+	cancelFirst()
+	assert.ErrorIs(t, <-firstDone, context.Canceled)
+	select {
+	case <-stopped:
+		t.Fatal("operation stopped while a caller was still waiting")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// The last caller leaving stops it
+	cancelSecond()
+	assert.ErrorIs(t, <-secondDone, context.Canceled)
+	assert.ErrorIs(t, <-stopped, context.Canceled)
+	require.Eventually(t, func() bool {
+		return group.InFlight() == 0
+	}, time.Second, time.Millisecond)
+}
+
+func TestGroup_DoAfterCancel(t *testing.T) {
+	var group Group[string]
+	stopping := make(chan struct{})
+	release := make(chan struct{})
+	var running atomic.Int32
+
+	// The first operation takes a while to stop once canceled
+	first := func(ctx context.Context) (string, error) {
+		running.Add(1)
+		defer running.Add(-1)
+		<-ctx.Done()
+		close(stopping)
+		<-release
+		return "", ctx.Err()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	firstDone := make(chan error, 1)
+	go func() {
+		_, err, _ := group.Do(ctx, "key", first)
+		firstDone <- err
+	}()
+	require.Eventually(t, func() bool {
+		return running.Load() == 1
+	}, time.Second, time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-firstDone, context.Canceled)
+	<-stopping
+
+	// The canceled operation stays in flight until it returns, and a new caller
+	// starts afresh only then
+	assert.Equal(t, 1, group.InFlight())
+	secondDone := make(chan string, 1)
+	go func() {
+		value, err, _ := group.Do(context.Background(), "key", func(ctx context.Context) (string, error) {
+			assert.Equal(t, int32(0), running.Load())
+			return "reloaded", nil
+		})
+		assert.NoError(t, err)
+		secondDone <- value
+	}()
+	select {
+	case <-secondDone:
+		t.Fatal("operation ran again before the canceled one returned")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	assert.Equal(t, "reloaded", <-secondDone)
+}
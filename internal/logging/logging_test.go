@@ -0,0 +1,54 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "info", FormatJSON)
+	require.NoError(t, err)
+
+	// Records below the level are dropped
+	logger.Debug("Found reference", "name", "Println")
+	assert.Empty(t, buf.String())
+
+	// Records carry the request ID of their context
+	ctx := WithRequestID(context.Background(), "abc123")
+	logger.With("component", "analyzer").InfoContext(ctx, "Analyzed package", "symbols", 12)
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "INFO", record["level"])
+	assert.Equal(t, "Analyzed package", record["msg"])
+	assert.Equal(t, "abc123", record[RequestIDKey])
+	assert.Equal(t, "analyzer", record["component"])
+	assert.Equal(t, float64(12), record["symbols"])
+
+	buf.Reset()
+	logger, err = New(&buf, "DEBUG", FormatText)
+	require.NoError(t, err)
+	logger.Debug("Found reference", "name", "Println")
+	assert.Contains(t, buf.String(), `level=DEBUG msg="Found reference" name=Println`)
+	assert.NotContains(t, buf.String(), RequestIDKey)
+
+	_, err = New(&buf, "verbose", FormatText)
+	assert.ErrorContains(t, err, "invalid log level")
+	_, err = New(&buf, "info", "xml")
+	assert.ErrorContains(t, err, "invalid log format")
+}
+
+func TestRequestID(t *testing.T) {
+	assert.Equal(t, "", RequestID(context.Background()))
+	assert.Equal(t, "id", RequestID(WithRequestID(context.Background(), "id")))
+
+	first, second := NewRequestID(), NewRequestID()
+	assert.Len(t, first, 16)
+	assert.NotEqual(t, first, second)
+}
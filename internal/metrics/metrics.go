@@ -0,0 +1,402 @@
+// Package metrics implements the counters, gauges and histograms of the server and
+// exposes them in the Prometheus text exposition format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Metric types, as written in the TYPE comments
+const (
+	TypeCounter   = "counter"
+	TypeGauge     = "gauge"
+	TypeHistogram = "histogram"
+)
+
+// DefaultBuckets are histogram buckets, in seconds, suited to request and load durations
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+// Default is the registry exposed by the server. Packages register their metrics in
+// it when initialized.
+var Default = NewRegistry()
+
+// Sample is a value of a metric with the given label values
+type Sample struct {
+	LabelValues []string
+	Value       float64
+}
+
+// metric is implemented by every registered metric
+type metric interface {
+	// write writes the samples of the metric, named name, to w
+	write(w io.Writer, name string) error
+}
+
+type registration struct {
+	name, help, typ string
+	metric          metric
+}
+
+// Registry holds metrics and writes them in the Prometheus text format
+type Registry struct {
+	mu      sync.Mutex
+	metrics map[string]*registration
+}
+
+// NewRegistry returns an empty registry
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]*registration)}
+}
+
+// register adds m under name, panicking if the name is invalid or already taken,
+// as both are programming errors
+func (r *Registry) register(name, help, typ string, m metric) {
+	if !validName(name) {
+		panic(fmt.Sprintf("metrics: invalid metric name %q", name))
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.metrics[name]; exists {
+		panic(fmt.Sprintf("metrics: duplicate metric %q", name))
+	}
+	r.metrics[name] = &registration{name: name, help: help, typ: typ, metric: m}
+}
+
+// NewCounterVec registers a counter partitioned by the given labels
+func (r *Registry) NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	c := &CounterVec{labelNames: labelNames, counters: make(map[string]*Counter)}
+	r.register(name, help, TypeCounter, c)
+	return c
+}
+
+// NewHistogramVec registers a histogram with the given upper bounds, partitioned by
+// the given labels
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	h := &HistogramVec{labelNames: labelNames, buckets: buckets, histograms: make(map[string]*Histogram)}
+	r.register(name, help, TypeHistogram, h)
+	return h
+}
+
+// NewFunc registers a counter or gauge whose samples are returned by collect when
+// the metrics are written, for values maintained elsewhere
+func (r *Registry) NewFunc(name, help, typ string, labelNames []string, collect func() []Sample) {
+	r.register(name, help, typ, &funcMetric{labelNames: labelNames, collect: collect})
+}
+
+// Write writes every metric in the text exposition format, sorted by name
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	registrations := make([]*registration, 0, len(r.metrics))
+	for _, reg := range r.metrics {
+		registrations = append(registrations, reg)
+	}
+	r.mu.Unlock()
+	sort.Slice(registrations, func(i, j int) bool { return registrations[i].name < registrations[j].name })
+
+	for _, reg := range registrations {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", reg.name, escapeHelp(reg.help), reg.name, reg.typ); err != nil {
+			return err
+		}
+		if err := reg.metric.write(w, reg.name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handler returns an HTTP handler serving the metrics of the registry
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.Write(w)
+	})
+}
+
+// Counter is a value that only increases
+type Counter struct {
+	bits atomic.Uint64
+}
+
+// Inc adds 1 to the counter
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add adds delta, which must not be negative, to the counter
+func (c *Counter) Add(delta float64) {
+	if delta < 0 {
+		panic("metrics: counters cannot decrease")
+	}
+	addFloat(&c.bits, delta)
+}
+
+// Value returns the current value of the counter
+func (c *Counter) Value() float64 {
+	return math.Float64frombits(c.bits.Load())
+}
+
+// CounterVec is a set of counters with the same name and different label values
+type CounterVec struct {
+	labelNames []string
+	mu         sync.RWMutex
+	counters   map[string]*Counter
+	values     [][]string
+}
+
+// With returns the counter with the given label values, creating it if needed
+func (v *CounterVec) With(labelValues ...string) *Counter {
+	key := seriesKey(v.labelNames, labelValues)
+	v.mu.RLock()
+	c, ok := v.counters[key]
+	v.mu.RUnlock()
+	if ok {
+		return c
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if c, ok = v.counters[key]; !ok {
+		c = &Counter{}
+		v.counters[key] = c
+		v.values = append(v.values, append([]string(nil), labelValues...))
+	}
+	return c
+}
+
+func (v *CounterVec) write(w io.Writer, name string) error {
+	v.mu.RLock()
+	samples := make([]Sample, 0, len(v.values))
+	for _, values := range v.values {
+		samples = append(samples, Sample{LabelValues: values, Value: v.counters[seriesKey(v.labelNames, values)].Value()})
+	}
+	v.mu.RUnlock()
+	return writeSamples(w, name, v.labelNames, samples)
+}
+
+// Histogram counts observations in buckets
+type Histogram struct {
+	buckets []float64
+	mu      sync.Mutex
+	counts  []uint64 // Observations per bucket, not cumulative, the last one for +Inf
+	count   uint64
+	sum     float64
+}
+
+// Observe records the value v
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.buckets, v)
+	h.mu.Lock()
+	h.counts[i]++
+	h.count++
+	h.sum += v
+	h.mu.Unlock()
+}
+
+// Count returns the number of observations
+func (h *Histogram) Count() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.count
+}
+
+// Sum returns the sum of the observations
+func (h *Histogram) Sum() float64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.sum
+}
+
+// HistogramVec is a set of histograms with the same name and buckets and different
+// label values
+type HistogramVec struct {
+	labelNames []string
+	buckets    []float64
+	mu         sync.RWMutex
+	histograms map[string]*Histogram
+	values     [][]string
+}
+
+// With returns the histogram with the given label values, creating it if needed
+func (v *HistogramVec) With(labelValues ...string) *Histogram {
+	key := seriesKey(v.labelNames, labelValues)
+	v.mu.RLock()
+	h, ok := v.histograms[key]
+	v.mu.RUnlock()
+	if ok {
+		return h
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if h, ok = v.histograms[key]; !ok {
+		h = &Histogram{buckets: v.buckets, counts: make([]uint64, len(v.buckets)+1)}
+		v.histograms[key] = h
+		v.values = append(v.values, append([]string(nil), labelValues...))
+	}
+	return h
+}
+
+func (v *HistogramVec) write(w io.Writer, name string) error {
+	v.mu.RLock()
+	values := append([][]string(nil), v.values...)
+	v.mu.RUnlock()
+	sortValues(values)
+
+	labelNames := append(append([]string(nil), v.labelNames...), "le")
+	for _, labelValues := range values {
+		v.mu.RLock()
+		h := v.histograms[seriesKey(v.labelNames, labelValues)]
+		v.mu.RUnlock()
+
+		h.mu.Lock()
+		counts := append([]uint64(nil), h.counts...)
+		count, sum := h.count, h.sum
+		h.mu.Unlock()
+
+		var cumulative uint64
+		for i, upper := range v.buckets {
+			cumulative += counts[i]
+			bucketValues := append(append([]string(nil), labelValues...), formatFloat(upper))
+			if err := writeSample(w, name+"_bucket", labelNames, bucketValues, float64(cumulative)); err != nil {
+				return err
+			}
+		}
+		infValues := append(append([]string(nil), labelValues...), "+Inf")
+		if err := writeSample(w, name+"_bucket", labelNames, infValues, float64(count)); err != nil {
+			return err
+		}
+		if err := writeSample(w, name+"_sum", v.labelNames, labelValues, sum); err != nil {
+			return err
+		}
+		if err := writeSample(w, name+"_count", v.labelNames, labelValues, float64(count)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// funcMetric is a metric whose samples are collected when written
+type funcMetric struct {
+	labelNames []string
+	collect    func() []Sample
+}
+
+func (f *funcMetric) write(w io.Writer, name string) error {
+	return writeSamples(w, name, f.labelNames, f.collect())
+}
+
+// writeSamples writes samples sorted by label values
+func writeSamples(w io.Writer, name string, labelNames []string, samples []Sample) error {
+	sort.Slice(samples, func(i, j int) bool { return lessValues(samples[i].LabelValues, samples[j].LabelValues) })
+	for _, sample := range samples {
+		if err := writeSample(w, name, labelNames, sample.LabelValues, sample.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSample writes a sample line: name{label="value",...} value
+func writeSample(w io.Writer, name string, labelNames, labelValues []string, value float64) error {
+	var b strings.Builder
+	b.WriteString(name)
+	if len(labelNames) > 0 {
+		b.WriteByte('{')
+		for i, label := range labelNames {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(label)
+			b.WriteString(`="`)
+			if i < len(labelValues) {
+				b.WriteString(escapeLabel(labelValues[i]))
+			}
+			b.WriteByte('"')
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(formatFloat(value))
+	b.WriteByte('\n')
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// seriesKey identifies a series by its label values, panicking if their number does
+// not match the label names
+func seriesKey(labelNames, labelValues []string) string {
+	if len(labelNames) != len(labelValues) {
+		panic(fmt.Sprintf("metrics: got %d label values for labels %v", len(labelValues), labelNames))
+	}
+	return strings.Join(labelValues, "\xff")
+}
+
+func sortValues(values [][]string) {
+	sort.Slice(values, func(i, j int) bool { return lessValues(values[i], values[j]) })
+}
+
+func lessValues(a, b []string) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return len(a) < len(b)
+}
+
+func addFloat(bits *atomic.Uint64, delta float64) {
+	for {
+		old := bits.Load()
+		updated := math.Float64bits(math.Float64frombits(old) + delta)
+		if bits.CompareAndSwap(old, updated) {
+			return
+		}
+	}
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+
+func escapeHelp(s string) string { return helpEscaper.Replace(s) }
+
+// validName reports whether name is a valid metric name
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_', c == ':':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
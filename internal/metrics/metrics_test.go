@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	registry := NewRegistry()
+
+	requests := registry.NewCounterVec("test_requests_total", "Requests served.", "endpoint", "status")
+	requests.With("/api/repo/", "200").Inc()
+	requests.With("/api/repo/", "200").Add(2)
+	requests.With("/api/file/", "404").Inc()
+
+	durations := registry.NewHistogramVec("test_duration_seconds", "Request durations.", []float64{1, 0.1}, "endpoint")
+	durations.With("/api/repo/").Observe(0.05)
+	durations.With("/api/repo/").Observe(0.5)
+	durations.With("/api/repo/").Observe(3)
+
+	registry.NewFunc("test_queue_length", "Pending \"requests\"\nin queue.", TypeGauge, nil, func() []Sample {
+		return []Sample{{Value: 4}}
+	})
+	registry.NewFunc("test_disk_bytes", "Disk usage.", TypeGauge, []string{"path"}, func() []Sample {
+		return []Sample{{LabelValues: []string{`C:\cache "x"`}, Value: 1.5e9}}
+	})
+
+	var output bytes.Buffer
+	require.NoError(t, registry.Write(&output))
+
+	expected := `# HELP test_disk_bytes Disk usage.
+# TYPE test_disk_bytes gauge
+test_disk_bytes{path="C:\\cache \"x\""} 1.5e+09
+# HELP test_duration_seconds Request durations.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{endpoint="/api/repo/",le="0.1"} 1
+test_duration_seconds_bucket{endpoint="/api/repo/",le="1"} 2
+test_duration_seconds_bucket{endpoint="/api/repo/",le="+Inf"} 3
+test_duration_seconds_sum{endpoint="/api/repo/"} 3.55
+test_duration_seconds_count{endpoint="/api/repo/"} 3
+# HELP test_queue_length Pending "requests"\nin queue.
+# TYPE test_queue_length gauge
+test_queue_length 4
+# HELP test_requests_total Requests served.
+# TYPE test_requests_total counter
+test_requests_total{endpoint="/api/file/",status="404"} 1
+test_requests_total{endpoint="/api/repo/",status="200"} 3
+`
+	assert.Equal(t, expected, output.String())
+}
+
+func TestRegistryHandler(t *testing.T) {
+	registry := NewRegistry()
+	registry.NewCounterVec("test_total", "Test counter.").With().Inc()
+
+	recorder := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Body.String(), "test_total 1\n")
+}
+
+func TestRegistryInvalidMetrics(t *testing.T) {
+	registry := NewRegistry()
+	counter := registry.NewCounterVec("test_total", "Test counter.", "label")
+
+	assert.Panics(t, func() { registry.NewCounterVec("test_total", "Duplicate.") })
+	assert.Panics(t, func() { registry.NewCounterVec("0invalid", "Invalid name.") })
+	assert.Panics(t, func() { counter.With("a", "b") })
+	assert.Panics(t, func() { counter.With("a").Add(-1) })
+}
+
+func TestCounterConcurrent(t *testing.T) {
+	counter := NewRegistry().NewCounterVec("test_total", "Test counter.", "label")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				counter.With("value").Inc()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, float64(5000), counter.With("value").Value())
+}
@@ -1,11 +1,11 @@
 package parser
 
 import (
-	"fmt"
 	"go/ast"
 	"go/parser"
 	"go/token"
 	"io/ioutil"
+	"log/slog"
 	"strings"
 )
 
@@ -70,7 +70,7 @@ func (p *GoParser) ParseFile(absolutePath, relativePath string) (*FileContent, e
 
 func (p *GoParser) extractSymbols(file *ast.File, relativePath string) map[string]Symbol {
 	symbols := make(map[string]Symbol)
-	fmt.Printf("Extracting symbols from file: '%s'\n", relativePath)
+	slog.Debug("Extracting symbols", "file", relativePath)
 
 	// Walk the AST and extract symbols
 	ast.Inspect(file, func(n ast.Node) bool {
@@ -85,7 +85,7 @@ func (p *GoParser) extractSymbols(file *ast.File, relativePath string) map[strin
 					Line: pos.Line,
 				}
 				symbols[node.Name.Name] = symbol
-				fmt.Printf("Found function: %s in file: %s\n", node.Name.Name, relativePath)
+				slog.Debug("Found function", "name", node.Name.Name, "file", relativePath)
 			}
 
 		case *ast.GenDecl:
@@ -101,7 +101,7 @@ func (p *GoParser) extractSymbols(file *ast.File, relativePath string) map[strin
 							Line: pos.Line,
 						}
 						symbols[s.Name.Name] = symbol
-						fmt.Printf("Found type: %s in file: %s\n", s.Name.Name, relativePath)
+						slog.Debug("Found type", "name", s.Name.Name, "file", relativePath)
 					}
 
 				case *ast.ValueSpec:
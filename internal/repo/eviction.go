@@ -0,0 +1,428 @@
+package repo
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"gonav/internal/env"
+)
+
+// ErrRepositoryNotLoaded is returned when unloading or evicting a repository that is
+// not loaded or cached
+var ErrRepositoryNotLoaded = errors.New("repository not loaded")
+
+// ErrRepositoryInUse is returned when unloading or evicting a repository that is
+// being served or analyzed
+var ErrRepositoryInUse = errors.New("repository in use")
+
+// CachePolicy bounds the disk space used by downloaded modules and cloned repositories
+type CachePolicy struct {
+	MaxSize int64         // Maximum size in bytes of all cached modules, 0 for no limit
+	TTL     time.Duration // Maximum time since last access, 0 for no limit
+}
+
+// Enabled returns true if the policy sets any limit
+func (p CachePolicy) Enabled() bool {
+	return p.MaxSize > 0 || p.TTL > 0
+}
+
+// EvictionResult reports what an eviction pass removed
+type EvictionResult struct {
+	Removed    []string `json:"removed"`    // module@version of every evicted module
+	FreedBytes int64    `json:"freedBytes"` // Disk space released
+	TotalBytes int64    `json:"totalBytes"` // Disk space still used by cached modules
+}
+
+// cacheEntry is a module version that can be evicted
+type cacheEntry struct {
+	key        string // module@version
+	size       int64
+	lastAccess time.Time
+	cached     bool   // In the isolated module cache
+	localPath  string // Loaded repository path, if loaded
+}
+
+// WithCachePolicy sets the limits applied by Evict
+func WithCachePolicy(policy CachePolicy) ManagerOption {
+	return func(m *Manager) error {
+		m.policy = policy
+		return nil
+	}
+}
+
+// Acquire marks a repository as in use until the returned function is called.
+// Repositories in use, and the modules they depend on, are never evicted. If the
+// repository is being evicted, Acquire waits until its files are removed.
+func (m *Manager) Acquire(moduleAtVersion string) func() {
+	m.mutex.Lock()
+	for {
+		done, evicting := m.evicting[moduleAtVersion]
+		if !evicting {
+			break
+		}
+		m.mutex.Unlock()
+		<-done
+		m.mutex.Lock()
+	}
+	m.inUse[moduleAtVersion]++
+	m.mutex.Unlock()
+
+	return func() {
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+		if _, loaded := m.repos[moduleAtVersion]; loaded {
+			m.lastAccess[moduleAtVersion] = time.Now()
+		}
+		if m.inUse[moduleAtVersion]--; m.inUse[moduleAtVersion] <= 0 {
+			delete(m.inUse, moduleAtVersion)
+		}
+	}
+}
+
+// OnUnload registers fn to be called for every repository unloaded by Evict or
+// ClearCache, so state derived from it can be released. fn is called without
+// holding the manager's mutex.
+func (m *Manager) OnUnload(fn func(moduleAtVersion, localPath string)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.onUnload = append(m.onUnload, fn)
+}
+
+// OnEvict registers fn to be called with the cache policy after every pass of
+// RunEviction, so caches outside the manager are bounded on the same schedule
+func (m *Manager) OnEvict(fn func(policy CachePolicy)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.onEvict = append(m.onEvict, fn)
+}
+
+// Evict removes cached modules and loaded repositories that were not accessed within
+// the policy TTL, then the least recently used ones until the cache fits the policy
+// size. Modules in use are kept even if the cache remains over the limit.
+func (m *Manager) Evict() (*EvictionResult, error) {
+	return m.evict(false)
+}
+
+// ClearCache unloads every repository and removes every cached module that is not
+// in use. Unlike Cleanup, the manager remains usable and modules are downloaded
+// again when next requested.
+func (m *Manager) ClearCache() (*EvictionResult, error) {
+	return m.evict(true)
+}
+
+// evict removes the entries selected by the cache policy, or all entries if all is set
+func (m *Manager) evict(all bool) (*EvictionResult, error) {
+	entries, err := m.cacheEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &EvictionResult{Removed: make([]string, 0)}
+	for _, entry := range entries {
+		result.TotalBytes += entry.size
+	}
+
+	// Least recently used first
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].lastAccess.Before(entries[j].lastAccess)
+	})
+
+	protected := m.inUseModules()
+	now := time.Now()
+	for _, entry := range entries {
+		expired := m.policy.TTL > 0 && now.Sub(entry.lastAccess) > m.policy.TTL
+		oversize := m.policy.MaxSize > 0 && result.TotalBytes > m.policy.MaxSize
+		if !all && !expired && !oversize {
+			continue
+		}
+
+		if err := m.removeEntry(entry, protected); err != nil {
+			if errors.Is(err, ErrRepositoryInUse) {
+				continue
+			}
+			m.logger.Warn("Failed to evict module", "module", entry.key, "error", err)
+			continue
+		}
+		result.Removed = append(result.Removed, entry.key)
+		result.FreedBytes += entry.size
+		result.TotalBytes -= entry.size
+	}
+
+	return result, nil
+}
+
+// RepositoryStatus describes a loaded repository
+type RepositoryStatus struct {
+	ModuleAtVersion string    `json:"moduleAtVersion"`
+	Path            string    `json:"path"`       // Local directory
+	SizeBytes       int64     `json:"sizeBytes"`  // Disk space used by its files and module cache entry
+	LastAccess      time.Time `json:"lastAccess"` // Last request served from it
+	InUse           int       `json:"inUse"`      // Operations in progress
+}
+
+// Repositories returns the loaded repositories, most recently accessed first
+func (m *Manager) Repositories() ([]RepositoryStatus, error) {
+	entries, err := m.cacheEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	repos := make([]RepositoryStatus, 0, len(m.repos))
+	for _, entry := range entries {
+		if entry.localPath == "" {
+			continue
+		}
+		repos = append(repos, RepositoryStatus{
+			ModuleAtVersion: entry.key,
+			Path:            entry.localPath,
+			SizeBytes:       entry.size,
+			LastAccess:      entry.lastAccess,
+			InUse:           m.inUse[entry.key],
+		})
+	}
+	sort.Slice(repos, func(i, j int) bool {
+		return repos[i].LastAccess.After(repos[j].LastAccess)
+	})
+	return repos, nil
+}
+
+// Unload forgets a loaded repository and releases the state derived from it, but
+// keeps its files, so loading it again does not download it. It fails with
+// ErrRepositoryNotLoaded or ErrRepositoryInUse.
+func (m *Manager) Unload(moduleAtVersion string) error {
+	m.mutex.Lock()
+	localPath, loaded := m.repos[moduleAtVersion]
+	if !loaded {
+		m.mutex.Unlock()
+		return fmt.Errorf("%w: %s", ErrRepositoryNotLoaded, moduleAtVersion)
+	}
+	if m.inUse[moduleAtVersion] > 0 {
+		m.mutex.Unlock()
+		return fmt.Errorf("%w: %s", ErrRepositoryInUse, moduleAtVersion)
+	}
+
+	delete(m.repos, moduleAtVersion)
+	delete(m.lastAccess, moduleAtVersion)
+	onUnload := slices.Clone(m.onUnload)
+	m.mutex.Unlock()
+
+	for _, fn := range onUnload {
+		fn(moduleAtVersion, localPath)
+	}
+	return nil
+}
+
+// EvictRepository unloads a repository and deletes its files from the cache, whatever
+// the cache policy. It fails with ErrRepositoryNotLoaded if the version is neither
+// loaded nor cached, and with ErrRepositoryInUse if it, or a repository depending
+// on it, is in use.
+func (m *Manager) EvictRepository(moduleAtVersion string) (*EvictionResult, error) {
+	entries, err := m.cacheEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &EvictionResult{Removed: make([]string, 0)}
+	var target *cacheEntry
+	for _, entry := range entries {
+		result.TotalBytes += entry.size
+		if entry.key == moduleAtVersion {
+			target = entry
+		}
+	}
+	if target == nil {
+		return nil, fmt.Errorf("%w: %s", ErrRepositoryNotLoaded, moduleAtVersion)
+	}
+
+	if err := m.removeEntry(target, m.inUseModules()); err != nil {
+		if errors.Is(err, ErrRepositoryInUse) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to evict %s: %w", moduleAtVersion, err)
+	}
+	result.Removed = append(result.Removed, moduleAtVersion)
+	result.FreedBytes = target.size
+	result.TotalBytes -= target.size
+	return result, nil
+}
+
+// RunEviction applies the cache policy, then calls the functions registered with
+// OnEvict, every interval until ctx is done
+func (m *Manager) RunEviction(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.runEvictionPass()
+		}
+	}
+}
+
+// runEvictionPass applies the cache policy, if it sets any limit, and calls the
+// functions registered with OnEvict
+func (m *Manager) runEvictionPass() {
+	if m.policy.Enabled() {
+		result, err := m.Evict()
+		if err != nil {
+			m.logger.Error("Cache eviction failed", "error", err)
+		} else if len(result.Removed) > 0 {
+			m.logger.Info("Evicted modules from cache", "count", len(result.Removed), "freed_bytes", result.FreedBytes)
+		}
+	}
+
+	m.mutex.Lock()
+	onEvict := slices.Clone(m.onEvict)
+	m.mutex.Unlock()
+	for _, fn := range onEvict {
+		fn(m.policy)
+	}
+}
+
+// cacheEntries lists the cached modules and loaded repositories with their size and
+// last access. The caller must not hold the mutex, as measuring walks their files.
+func (m *Manager) cacheEntries() ([]*cacheEntry, error) {
+	m.mutex.Lock()
+	repos := maps.Clone(m.repos)
+	lastAccess := maps.Clone(m.lastAccess)
+	m.mutex.Unlock()
+
+	entries := make(map[string]*cacheEntry)
+
+	if m.isolatedEnv != nil {
+		modules, err := m.isolatedEnv.CachedModules()
+		if err != nil {
+			return nil, err
+		}
+		for _, mod := range modules {
+			key := mod.Path + "@" + mod.Version
+			entries[key] = &cacheEntry{
+				key:        key,
+				size:       mod.Size,
+				lastAccess: mod.Modified,
+				cached:     true,
+			}
+		}
+	}
+
+	for key, localPath := range repos {
+		entry, exists := entries[key]
+		if !exists {
+			entry = &cacheEntry{key: key}
+			entries[key] = entry
+		}
+		entry.localPath = localPath
+
+		// Cloned repositories live in the cache directory itself
+		if info, err := os.Lstat(localPath); err == nil && info.IsDir() {
+			entry.size += env.DirSize(localPath)
+			if info.ModTime().After(entry.lastAccess) {
+				entry.lastAccess = info.ModTime()
+			}
+		}
+	}
+
+	result := make([]*cacheEntry, 0, len(entries))
+	for key, entry := range entries {
+		if accessed, ok := lastAccess[key]; ok && accessed.After(entry.lastAccess) {
+			entry.lastAccess = accessed
+		}
+		result = append(result, entry)
+	}
+	return result, nil
+}
+
+// inUseModules returns the repositories in use together with every module recorded
+// in their go.sum, which analysis may be reading. The caller must not hold the mutex,
+// as the go.sum files are read without it.
+func (m *Manager) inUseModules() map[string]bool {
+	m.mutex.Lock()
+	inUse := make(map[string]string, len(m.inUse))
+	for key := range m.inUse {
+		inUse[key] = m.repos[key]
+	}
+	m.mutex.Unlock()
+
+	protected := make(map[string]bool)
+	for key, localPath := range inUse {
+		protected[key] = true
+		if localPath == "" {
+			continue
+		}
+		file, err := os.Open(filepath.Join(localPath, "go.sum"))
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			// Lines are "path version hash" or "path version/go.mod hash"
+			if len(fields) == 3 && !strings.HasSuffix(fields[1], "/go.mod") {
+				protected[fields[0]+"@"+fields[1]] = true
+			}
+		}
+		file.Close()
+	}
+	return protected
+}
+
+// removeEntry unloads a repository and deletes its files, unless it is in use or in
+// protected, the modules returned by inUseModules. The files are deleted without
+// holding the mutex, while Acquire waits for the repository.
+func (m *Manager) removeEntry(entry *cacheEntry, protected map[string]bool) error {
+	m.mutex.Lock()
+	if m.inUse[entry.key] > 0 || protected[entry.key] {
+		m.mutex.Unlock()
+		return fmt.Errorf("%w: %s", ErrRepositoryInUse, entry.key)
+	}
+	var onUnload []func(moduleAtVersion, localPath string)
+	if entry.localPath != "" {
+		delete(m.repos, entry.key)
+		delete(m.lastAccess, entry.key)
+		onUnload = slices.Clone(m.onUnload)
+	}
+	done := make(chan struct{})
+	m.evicting[entry.key] = done
+	m.mutex.Unlock()
+
+	for _, fn := range onUnload {
+		fn(entry.key, entry.localPath)
+	}
+
+	defer func() {
+		m.mutex.Lock()
+		delete(m.evicting, entry.key)
+		m.mutex.Unlock()
+		close(done)
+	}()
+
+	if entry.localPath != "" {
+		if err := os.RemoveAll(entry.localPath); err != nil {
+			return err
+		}
+	}
+
+	if entry.cached {
+		modulePath, version := m.parseModuleAtVersion(entry.key)
+		return m.isolatedEnv.RemoveModule(modulePath, version)
+	}
+	return nil
+}
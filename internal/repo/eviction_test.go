@@ -0,0 +1,254 @@
+package repo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gonav/internal/testutil"
+)
+
+func TestManagerEvict(t *testing.T) {
+	proxyDir := t.TempDir()
+	testutil.WriteProxyModule(t, proxyDir, "example.com/lib", "v1.0.0")
+	testutil.WriteProxyModule(t, proxyDir, "example.com/other", "v1.0.0")
+	testutil.UseLocalProxy(t, proxyDir)
+
+	manager, err := NewManager(WithIsolation(true), WithCachePolicy(CachePolicy{TTL: time.Hour}))
+	require.NoError(t, err)
+	defer manager.Cleanup()
+
+	_, err = manager.LoadRepository(context.Background(), "example.com/lib@v1.0.0")
+	require.NoError(t, err)
+	_, err = manager.LoadRepository(context.Background(), "example.com/other@v1.0.0")
+	require.NoError(t, err)
+
+	// Nothing has expired yet
+	result, err := manager.Evict()
+	require.NoError(t, err)
+	assert.Empty(t, result.Removed)
+	assert.Greater(t, result.TotalBytes, int64(0))
+
+	// With a tiny size limit everything goes except the repository in use
+	release := manager.Acquire("example.com/lib@v1.0.0")
+	manager.policy = CachePolicy{MaxSize: 1}
+
+	result, err = manager.Evict()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example.com/other@v1.0.0"}, result.Removed)
+	assert.Greater(t, result.FreedBytes, int64(0))
+	assert.Empty(t, manager.GetRepositoryPath("example.com/other@v1.0.0"))
+	assert.NotEmpty(t, manager.GetRepositoryPath("example.com/lib@v1.0.0"))
+
+	versions, err := manager.GetIsolatedEnv().CachedVersions("example.com/other")
+	require.NoError(t, err)
+	assert.Empty(t, versions)
+
+	stats := manager.Stats()
+	assert.Equal(t, 1, stats["in_use_repositories"])
+	assert.Equal(t, int64(1), stats["cache_max_size"])
+
+	// Once released, the remaining repository can be evicted too
+	release()
+	result, err = manager.Evict()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example.com/lib@v1.0.0"}, result.Removed)
+	assert.Equal(t, int64(0), result.TotalBytes)
+	assert.Empty(t, manager.ListRepositories())
+
+	// Evicted repositories are downloaded again on demand
+	info, err := manager.LoadRepository(context.Background(), "example.com/other@v1.0.0")
+	require.NoError(t, err)
+	assert.NotEmpty(t, info.Files)
+}
+
+func TestManagerEvict_TTL(t *testing.T) {
+	proxyDir := t.TempDir()
+	testutil.WriteProxyModule(t, proxyDir, "example.com/lib", "v1.0.0")
+	testutil.UseLocalProxy(t, proxyDir)
+
+	manager, err := NewManager(WithIsolation(true), WithCachePolicy(CachePolicy{TTL: 50 * time.Millisecond}))
+	require.NoError(t, err)
+	defer manager.Cleanup()
+
+	_, err = manager.LoadRepository(context.Background(), "example.com/lib@v1.0.0")
+	require.NoError(t, err)
+
+	// Accessing the repository keeps it alive
+	time.Sleep(30 * time.Millisecond)
+	manager.GetRepositoryPath("example.com/lib@v1.0.0")
+	time.Sleep(30 * time.Millisecond)
+	result, err := manager.Evict()
+	require.NoError(t, err)
+	assert.Empty(t, result.Removed)
+
+	time.Sleep(60 * time.Millisecond)
+	result, err = manager.Evict()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example.com/lib@v1.0.0"}, result.Removed)
+}
+
+func TestManagerClearCache(t *testing.T) {
+	proxyDir := t.TempDir()
+	testutil.WriteProxyModule(t, proxyDir, "example.com/lib", "v1.0.0")
+	testutil.WriteProxyModule(t, proxyDir, "example.com/other", "v1.0.0")
+	testutil.UseLocalProxy(t, proxyDir)
+
+	manager, err := NewManager(WithIsolation(true), WithCacheDir(t.TempDir()))
+	require.NoError(t, err)
+	defer manager.Cleanup()
+
+	_, err = manager.LoadRepository(context.Background(), "example.com/lib@v1.0.0")
+	require.NoError(t, err)
+	_, err = manager.LoadRepository(context.Background(), "example.com/other@v1.0.0")
+	require.NoError(t, err)
+	otherPath := manager.GetRepositoryPath("example.com/other@v1.0.0")
+
+	unloaded := make(map[string]string)
+	manager.OnUnload(func(moduleAtVersion, localPath string) {
+		// Callbacks run without the manager's mutex, so they may call it
+		assert.Empty(t, manager.GetRepositoryPath(moduleAtVersion))
+		unloaded[moduleAtVersion] = localPath
+	})
+
+	// Clearing ignores the policy but keeps repositories in use
+	release := manager.Acquire("example.com/lib@v1.0.0")
+	result, err := manager.ClearCache()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example.com/other@v1.0.0"}, result.Removed)
+	assert.Equal(t, []string{"example.com/lib@v1.0.0"}, manager.ListRepositories())
+	assert.Equal(t, map[string]string{"example.com/other@v1.0.0": otherPath}, unloaded)
+	release()
+
+	result, err = manager.ClearCache()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example.com/lib@v1.0.0"}, result.Removed)
+
+	// The manager remains usable
+	_, err = manager.LoadRepository(context.Background(), "example.com/lib@v1.0.0")
+	require.NoError(t, err)
+}
+
+func TestManagerUnloadAndEvictRepository(t *testing.T) {
+	proxyDir := t.TempDir()
+	testutil.WriteProxyModule(t, proxyDir, "example.com/lib", "v1.0.0")
+	testutil.WriteProxyModule(t, proxyDir, "example.com/other", "v1.0.0")
+	testutil.UseLocalProxy(t, proxyDir)
+
+	manager, err := NewManager(WithIsolation(true))
+	require.NoError(t, err)
+	defer manager.Cleanup()
+
+	var unloaded []string
+	manager.OnUnload(func(moduleAtVersion, localPath string) {
+		unloaded = append(unloaded, moduleAtVersion)
+	})
+
+	_, err = manager.LoadRepository(context.Background(), "example.com/lib@v1.0.0")
+	require.NoError(t, err)
+	_, err = manager.LoadRepository(context.Background(), "example.com/other@v1.0.0")
+	require.NoError(t, err)
+	manager.GetRepositoryPath("example.com/other@v1.0.0")
+
+	repos, err := manager.Repositories()
+	require.NoError(t, err)
+	require.Len(t, repos, 2)
+	assert.Equal(t, "example.com/other@v1.0.0", repos[0].ModuleAtVersion) // Most recently accessed first
+	for _, repo := range repos {
+		assert.Greater(t, repo.SizeBytes, int64(0))
+		assert.NotEmpty(t, repo.Path)
+		assert.False(t, repo.LastAccess.IsZero())
+	}
+
+	// Repositories in use cannot be unloaded or evicted
+	release := manager.Acquire("example.com/lib@v1.0.0")
+	assert.ErrorIs(t, manager.Unload("example.com/lib@v1.0.0"), ErrRepositoryInUse)
+	_, err = manager.EvictRepository("example.com/lib@v1.0.0")
+	assert.ErrorIs(t, err, ErrRepositoryInUse)
+	release()
+
+	// Unloading keeps the module cached
+	require.NoError(t, manager.Unload("example.com/lib@v1.0.0"))
+	assert.Equal(t, []string{"example.com/lib@v1.0.0"}, unloaded)
+	assert.Empty(t, manager.GetRepositoryPath("example.com/lib@v1.0.0"))
+	assert.ErrorIs(t, manager.Unload("example.com/lib@v1.0.0"), ErrRepositoryNotLoaded)
+	versions, err := manager.GetIsolatedEnv().CachedVersions("example.com/lib")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v1.0.0"}, versions)
+
+	// Evicting deletes the files, of loaded or only cached versions
+	result, err := manager.EvictRepository("example.com/other@v1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example.com/other@v1.0.0"}, result.Removed)
+	assert.Greater(t, result.FreedBytes, int64(0))
+	assert.Equal(t, []string{"example.com/lib@v1.0.0", "example.com/other@v1.0.0"}, unloaded)
+
+	_, err = manager.EvictRepository("example.com/lib@v1.0.0")
+	require.NoError(t, err)
+	versions, err = manager.GetIsolatedEnv().CachedVersions("example.com/lib")
+	require.NoError(t, err)
+	assert.Empty(t, versions)
+
+	_, err = manager.EvictRepository("example.com/lib@v1.0.0")
+	assert.ErrorIs(t, err, ErrRepositoryNotLoaded)
+}
+
+func TestManagerRunEviction(t *testing.T) {
+	manager, err := NewManager(WithCacheDir(t.TempDir()), WithCachePolicy(CachePolicy{TTL: time.Hour}))
+	require.NoError(t, err)
+	defer manager.Cleanup()
+
+	// Registered functions run on every pass with the cache policy
+	policies := make(chan CachePolicy, 1)
+	manager.OnEvict(func(policy CachePolicy) {
+		select {
+		case policies <- policy:
+		default:
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go manager.RunEviction(ctx, 10*time.Millisecond)
+
+	select {
+	case policy := <-policies:
+		assert.Equal(t, CachePolicy{TTL: time.Hour}, policy)
+	case <-time.After(5 * time.Second):
+		t.Fatal("eviction functions not called")
+	}
+}
+
+func TestManagerAcquireWaitsForEviction(t *testing.T) {
+	manager, err := NewManager(WithCacheDir(t.TempDir()))
+	require.NoError(t, err)
+	defer manager.Cleanup()
+
+	done := make(chan struct{})
+	manager.mutex.Lock()
+	manager.evicting["example.com/lib@v1.0.0"] = done
+	manager.mutex.Unlock()
+
+	acquired := make(chan func())
+	go func() {
+		acquired <- manager.Acquire("example.com/lib@v1.0.0")
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("repository acquired while being evicted")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	manager.mutex.Lock()
+	delete(manager.evicting, "example.com/lib@v1.0.0")
+	manager.mutex.Unlock()
+	close(done)
+
+	release := <-acquired
+	assert.Equal(t, 1, manager.Stats()["in_use_repositories"])
+	release()
+}
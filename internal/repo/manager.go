@@ -1,27 +1,79 @@
 package repo
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
 
 	"gonav/internal/env"
+	"gonav/internal/flight"
 )
 
+// ErrNotAvailableOffline is returned when a module is missing from the local
+// module cache and proxy while the manager runs in offline mode
+var ErrNotAvailableOffline = errors.New("not available offline")
+
+// ErrModuleNotFound is returned when the module proxy and the repository host do not
+// have the requested module or version
+var ErrModuleNotFound = errors.New("module not found")
+
+// ErrInvalidModuleVersion is returned for module@version strings that are malformed
+var ErrInvalidModuleVersion = errors.New("invalid module@version")
+
 type Manager struct {
 	cacheDir    string
 	repos       map[string]string // moduleAtVersion -> local path
 	isolatedEnv *env.IsolatedEnv  // Optional isolation environment
+
+	// Access tracking for cache eviction, guarded by mutex together with repos
+	mutex      sync.Mutex
+	lastAccess map[string]time.Time     // moduleAtVersion -> last access
+	inUse      map[string]int           // moduleAtVersion -> operations in progress
+	evicting   map[string]chan struct{} // moduleAtVersion -> closed once its files are removed
+	policy     CachePolicy
+	onUnload   []func(moduleAtVersion, localPath string)
+	onEvict    []func(policy CachePolicy)
+
+	timeouts Timeouts
+
+	// Downloads in flight, so concurrent loads of a version share one download
+	loads flight.Group[string]
+
+	logger *slog.Logger
+
+	// Settings applied when the isolated environment is created
+	isolated  bool
+	envConfig env.Config
 }
 
 type RepositoryInfo struct {
-	ModuleAtVersion string      `json:"moduleAtVersion"`
-	ModulePath      string      `json:"modulePath"`
-	Version         string      `json:"version"`
-	Files           []FileInfo  `json:"files"`
+	ModuleAtVersion string        `json:"moduleAtVersion"`
+	ModulePath      string        `json:"modulePath"`
+	Version         string        `json:"version"`
+	Files           []FileInfo    `json:"files"`
+	Modules         []ModuleEntry `json:"modules,omitempty"` // Root and nested modules
+}
+
+// ModuleEntry describes a module defined by a go.mod file in the repository
+type ModuleEntry struct {
+	Path     string `json:"path"`               // Module path declared in go.mod
+	Dir      string `json:"dir"`                // Relative path from repository root, "" for the root module
+	Vendored bool   `json:"vendored,omitempty"` // True if the module has a vendor/modules.txt
 }
 
 type FileInfo struct {
@@ -29,6 +81,20 @@ type FileInfo struct {
 	IsGo bool   `json:"isGo"`
 }
 
+// ModuleVersions lists the versions available for a module
+type ModuleVersions struct {
+	ModulePath string        `json:"modulePath"`
+	Versions   []VersionInfo `json:"versions"`
+}
+
+// VersionInfo describes a single available version of a module
+type VersionInfo struct {
+	Version string `json:"version"`
+	Pseudo  bool   `json:"pseudo,omitempty"` // True for pseudo-versions (untagged commits)
+	Cached  bool   `json:"cached,omitempty"` // True if the version is in the local module cache
+	Loaded  bool   `json:"loaded"`           // True if the version is loaded in this manager
+}
+
 type GoModDownloadInfo struct {
 	Path     string `json:"Path"`     // Module path (e.g., "github.com/arnodel/edit")
 	Version  string `json:"Version"`  // Resolved version (e.g., "v0.0.0-20220202110212-dfc8d7a13890")
@@ -43,30 +109,90 @@ type GoModDownloadInfo struct {
 // ManagerOption configures a Manager
 type ManagerOption func(*Manager) error
 
+// Timeouts bounds the duration of network operations, 0 for no limit. They apply
+// on top of the deadline of the context passed by the caller.
+type Timeouts struct {
+	Download     time.Duration // go mod download or git clone of a repository
+	ListVersions time.Duration // Listing the versions of a module from the proxy
+}
+
+// WithTimeouts sets the per-operation timeouts
+func WithTimeouts(timeouts Timeouts) ManagerOption {
+	return func(m *Manager) error {
+		m.timeouts = timeouts
+		return nil
+	}
+}
+
 // WithIsolation enables isolated Go environment
 func WithIsolation(isolated bool) ManagerOption {
 	return func(m *Manager) error {
-		if isolated {
-			envDir := filepath.Join(m.cacheDir, "isolated-env")
-			isolatedEnv, err := env.NewIsolated(envDir)
-			if err != nil {
-				return fmt.Errorf("failed to create isolated environment: %w", err)
-			}
-			m.isolatedEnv = isolatedEnv
-			fmt.Printf("Isolated Go environment created at: %s\n", envDir)
+		m.isolated = isolated
+		return nil
+	}
+}
+
+// WithEnvConfig sets the proxy, checksum and credential settings of the isolated
+// Go environment. It has no effect unless isolation is enabled.
+func WithEnvConfig(config env.Config) ManagerOption {
+	return func(m *Manager) error {
+		m.envConfig = config
+		return nil
+	}
+}
+
+// WithCacheDir sets the base directory under which the gonav-cache directory, with
+// loaded repositories and the isolated environment, is created. Its content is reused
+// when a manager is created again on the same directory, so downloaded modules
+// survive restarts until Cleanup is called. The default is the system temp directory.
+func WithCacheDir(baseDir string) ManagerOption {
+	return func(m *Manager) error {
+		if baseDir == "" {
+			return nil
+		}
+		cacheDir, err := CacheDirFor(baseDir)
+		if err != nil {
+			return err
 		}
+		m.cacheDir = cacheDir
+		return nil
+	}
+}
+
+// cacheDirName is the directory created under the base cache directory
+const cacheDirName = "gonav-cache"
+
+// CacheDirFor returns the directory holding the files of a manager created with
+// WithCacheDir(baseDir), or without it if baseDir is "". The isolated environment
+// and every loaded repository are below it.
+func CacheDirFor(baseDir string) (string, error) {
+	if baseDir == "" {
+		return filepath.Join(os.TempDir(), cacheDirName), nil
+	}
+	absDir, err := filepath.Abs(baseDir)
+	if err != nil {
+		return "", fmt.Errorf("invalid cache directory: %w", err)
+	}
+	return filepath.Join(absDir, cacheDirName), nil
+}
+
+// WithLogger sets the logger receiving the manager's log records, slog.Default() otherwise
+func WithLogger(logger *slog.Logger) ManagerOption {
+	return func(m *Manager) error {
+		m.logger = logger
 		return nil
 	}
 }
 
 // NewManager creates a new repository manager with optional configuration
 func NewManager(opts ...ManagerOption) (*Manager, error) {
-	cacheDir := filepath.Join(os.TempDir(), "gonav-cache")
-	os.MkdirAll(cacheDir, 0755)
-
 	m := &Manager{
-		cacheDir: cacheDir,
-		repos:    make(map[string]string),
+		cacheDir:   filepath.Join(os.TempDir(), cacheDirName),
+		repos:      make(map[string]string),
+		lastAccess: make(map[string]time.Time),
+		inUse:      make(map[string]int),
+		evicting:   make(map[string]chan struct{}),
+		logger:     slog.Default(),
 	}
 
 	// Apply options
@@ -76,19 +202,62 @@ func NewManager(opts ...ManagerOption) (*Manager, error) {
 		}
 	}
 
+	if err := os.MkdirAll(m.cacheDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	if m.isolated {
+		envDir := filepath.Join(m.cacheDir, "isolated-env")
+		isolatedEnv, err := env.NewIsolatedWithConfig(envDir, m.envConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create isolated environment: %w", err)
+		}
+		m.isolatedEnv = isolatedEnv
+		m.logger.Info("Isolated Go environment created", "dir", envDir)
+	}
+
 	return m, nil
 }
 
-func (m *Manager) LoadRepository(moduleAtVersion string) (*RepositoryInfo, error) {
+// LoadRepository downloads a repository, unless already loaded, and lists its files.
+// The download is stopped when ctx is done or the download timeout expires.
+func (m *Manager) LoadRepository(ctx context.Context, moduleAtVersion string) (*RepositoryInfo, error) {
+	// Keep the repository from being evicted while it is loaded
+	release := m.Acquire(moduleAtVersion)
+	defer release()
+
 	// Check if already loaded
-	if localPath, exists := m.repos[moduleAtVersion]; exists {
+	if localPath := m.GetRepositoryPath(moduleAtVersion); localPath != "" {
 		return m.buildRepositoryInfo(moduleAtVersion, localPath)
 	}
 
 	// Parse module@version
 	modulePath, version := m.parseModuleAtVersion(moduleAtVersion)
-	if modulePath == "" {
-		return nil, fmt.Errorf("invalid module@version format: %s", moduleAtVersion)
+	if modulePath == "" || version == "" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidModuleVersion, moduleAtVersion)
+	}
+	if err := module.CheckPath(modulePath); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidModuleVersion, err)
+	}
+
+	// Concurrent loads of the same version wait for a single download instead of
+	// removing and replacing each other's files
+	localPath, err, _ := m.loads.Do(ctx, moduleAtVersion, func(ctx context.Context) (string, error) {
+		return m.storeRepository(ctx, moduleAtVersion, modulePath, version)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return m.buildRepositoryInfo(moduleAtVersion, localPath)
+}
+
+// storeRepository downloads a repository into the cache directory and records it
+// as loaded, returning its local path
+func (m *Manager) storeRepository(ctx context.Context, moduleAtVersion, modulePath, version string) (string, error) {
+	// A load that finished while this one was waiting to start already stored it
+	if localPath := m.GetRepositoryPath(moduleAtVersion); localPath != "" {
+		return localPath, nil
 	}
 
 	// Create local path for this repo
@@ -97,22 +266,37 @@ func (m *Manager) LoadRepository(moduleAtVersion string) (*RepositoryInfo, error
 	localPath := filepath.Join(m.cacheDir, safeName)
 
 	// Clone or download the repository
-	err := m.downloadRepository(modulePath, version, localPath)
+	start := time.Now()
+	err := m.downloadRepository(ctx, modulePath, version, localPath)
+	observeDownload(start, err)
 	if err != nil {
-		return nil, fmt.Errorf("failed to download repository: %w", err)
+		return "", fmt.Errorf("failed to download repository: %w", err)
 	}
+	m.logger.InfoContext(ctx, "Downloaded repository", "module", moduleAtVersion, "duration", time.Since(start))
 
 	// Store in cache
+	m.mutex.Lock()
 	m.repos[moduleAtVersion] = localPath
+	m.mutex.Unlock()
 
-	return m.buildRepositoryInfo(moduleAtVersion, localPath)
+	return localPath, nil
 }
 
 func (m *Manager) GetRepositoryPath(moduleAtVersion string) string {
-	return m.repos[moduleAtVersion]
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	localPath, exists := m.repos[moduleAtVersion]
+	if exists {
+		m.lastAccess[moduleAtVersion] = time.Now()
+	}
+	return localPath
 }
 
 func (m *Manager) ListRepositories() []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
 	var repos []string
 	for key := range m.repos {
 		repos = append(repos, key)
@@ -120,6 +304,115 @@ func (m *Manager) ListRepositories() []string {
 	return repos
 }
 
+// ListVersions returns the tagged versions of a module together with the pseudo-versions
+// already present in the local module cache, marking the ones currently loaded
+func (m *Manager) ListVersions(ctx context.Context, modulePath string) (*ModuleVersions, error) {
+	if err := module.CheckPath(modulePath); err != nil {
+		return nil, fmt.Errorf("%w: invalid module path: %w", ErrInvalidModuleVersion, err)
+	}
+
+	tagged, listErr := m.listProxyVersions(ctx, modulePath)
+	if errors.Is(listErr, context.Canceled) {
+		return nil, listErr
+	}
+	if listErr != nil {
+		m.logger.WarnContext(ctx, "Failed to list versions from module proxy", "module", modulePath, "error", listErr)
+	}
+
+	cached, err := m.listCachedVersions(modulePath)
+	if err != nil {
+		m.logger.WarnContext(ctx, "Failed to list cached versions", "module", modulePath, "error", err)
+	}
+
+	versions := make(map[string]*VersionInfo)
+	addVersion := func(version string) *VersionInfo {
+		info, exists := versions[version]
+		if !exists {
+			info = &VersionInfo{
+				Version: version,
+				Pseudo:  module.IsPseudoVersion(version),
+			}
+			versions[version] = info
+		}
+		return info
+	}
+
+	for _, version := range tagged {
+		addVersion(version)
+	}
+	for _, version := range cached {
+		addVersion(version).Cached = true
+	}
+	for _, key := range m.ListRepositories() {
+		loadedPath, version := m.parseModuleAtVersion(key)
+		if loadedPath == modulePath {
+			addVersion(version).Loaded = true
+		}
+	}
+
+	if len(versions) == 0 && listErr != nil {
+		return nil, listErr
+	}
+
+	sorted := make([]string, 0, len(versions))
+	for version := range versions {
+		sorted = append(sorted, version)
+	}
+	semver.Sort(sorted)
+
+	result := &ModuleVersions{
+		ModulePath: modulePath,
+		Versions:   make([]VersionInfo, 0, len(sorted)),
+	}
+	for _, version := range sorted {
+		result.Versions = append(result.Versions, *versions[version])
+	}
+
+	return result, nil
+}
+
+func (m *Manager) listProxyVersions(ctx context.Context, modulePath string) ([]string, error) {
+	ctx, cancel := env.WithTimeout(ctx, m.timeouts.ListVersions)
+	defer cancel()
+
+	if m.isolatedEnv != nil {
+		versions, err := m.isolatedEnv.ListVersions(ctx, modulePath)
+		if err != nil {
+			return nil, env.ContextError(ctx, "listing versions of "+modulePath, m.timeouts.ListVersions, err)
+		}
+		return versions, nil
+	}
+
+	// Use host environment, outside of any module
+	cmd := exec.CommandContext(ctx, "go", "list", "-m", "-versions", "-json", modulePath)
+	cmd.Dir = m.cacheDir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, env.ContextError(ctx, "listing versions of "+modulePath, m.timeouts.ListVersions,
+			fmt.Errorf("go list -versions failed for %s: %w", modulePath, err))
+	}
+
+	var listInfo env.GoListVersionsInfo
+	if err := json.Unmarshal(output, &listInfo); err != nil {
+		return nil, fmt.Errorf("failed to parse go list output: %w", err)
+	}
+
+	return listInfo.Versions, nil
+}
+
+func (m *Manager) listCachedVersions(modulePath string) ([]string, error) {
+	if m.isolatedEnv != nil {
+		return m.isolatedEnv.CachedVersions(modulePath)
+	}
+
+	output, err := exec.Command("go", "env", "GOMODCACHE").Output()
+	if err != nil {
+		return nil, fmt.Errorf("go env GOMODCACHE failed: %w", err)
+	}
+
+	return env.ReadCachedVersions(strings.TrimSpace(string(output)), modulePath)
+}
+
 func (m *Manager) parseModuleAtVersion(moduleAtVersion string) (string, string) {
 	parts := strings.Split(moduleAtVersion, "@")
 	if len(parts) != 2 {
@@ -128,35 +421,82 @@ func (m *Manager) parseModuleAtVersion(moduleAtVersion string) (string, string)
 	return parts[0], parts[1]
 }
 
-func (m *Manager) downloadRepository(modulePath, version, localPath string) error {
+func (m *Manager) downloadRepository(ctx context.Context, modulePath, version, localPath string) error {
+	ctx, cancel := env.WithTimeout(ctx, m.timeouts.Download)
+	defer cancel()
+	operation := "download of " + modulePath + "@" + version
+
 	// Try go mod download first (preferred method for Go modules)
-	localDir, err := m.downloadWithGoMod(modulePath, version)
+	localDir, err := m.downloadWithGoMod(ctx, modulePath, version)
 	if err == nil {
-		// Success with go mod download, create a symlink or copy to our expected location
+		// Success with go mod download, keep the local proxy tree up to date
+		if _, err := m.exportToProxy(modulePath, version, localDir); err != nil {
+			m.logger.WarnContext(ctx, "Failed to populate module proxy directory", "error", err)
+		}
+
+		// Create a symlink or copy to our expected location
 		os.RemoveAll(localPath)
 		return os.Symlink(localDir, localPath)
 	}
 
+	// Do not fall back to git once the caller is gone or the time is up
+	if ctx.Err() != nil {
+		return env.ContextError(ctx, operation, m.timeouts.Download, err)
+	}
+
+	// Never fall back to the network in offline mode
+	if m.IsOffline() {
+		return fmt.Errorf("module %s@%s is %w: %w", modulePath, version, ErrNotAvailableOffline, err)
+	}
+
 	// Fall back to git clone for modules not available via go proxy
-	fmt.Printf("go mod download failed for %s@%s: %v, trying git clone...\n", modulePath, version, err)
+	m.logger.InfoContext(ctx, "go mod download failed, trying git clone", "module", modulePath, "version", version, "error", err)
 	
 	// Remove existing directory if it exists
 	os.RemoveAll(localPath)
 
 	// Use git clone as fallback
 	if strings.HasPrefix(modulePath, "github.com/") {
-		return m.cloneGitRepository(modulePath, version, localPath)
+		if cloneErr := m.cloneGitRepository(ctx, modulePath, version, localPath); cloneErr != nil {
+			// Do not leave a partial clone behind
+			os.RemoveAll(localPath)
+			return env.ContextError(ctx, operation, m.timeouts.Download, classifyDownloadError(
+				fmt.Errorf("%w; git clone fallback: %v", err, cloneErr)))
+		}
+		return nil
+	}
+
+	return classifyDownloadError(fmt.Errorf("unsupported module path and go mod download failed: %s: %w", modulePath, err))
+}
+
+// classifyDownloadError adds ErrModuleNotFound or ErrInvalidModuleVersion to err when
+// the output of the go command says the module or version does not exist or is malformed
+func classifyDownloadError(err error) error {
+	var cmdErr *env.CommandError
+	if !errors.As(err, &cmdErr) {
+		return err
 	}
 
-	return fmt.Errorf("unsupported module path and go mod download failed: %s", modulePath)
+	output := strings.ToLower(cmdErr.Stderr)
+	for _, notFound := range []string{"not found", "unknown revision", "no matching versions", "404", "410 gone", "no such file or directory"} {
+		if strings.Contains(output, notFound) {
+			return fmt.Errorf("%w: %w", ErrModuleNotFound, err)
+		}
+	}
+	for _, invalid := range []string{"malformed", "invalid version", "invalid module path", "invalid char"} {
+		if strings.Contains(output, invalid) {
+			return fmt.Errorf("%w: %w", ErrInvalidModuleVersion, err)
+		}
+	}
+	return err
 }
 
-func (m *Manager) downloadWithGoMod(modulePath, version string) (string, error) {
+func (m *Manager) downloadWithGoMod(ctx context.Context, modulePath, version string) (string, error) {
 	moduleAtVersion := modulePath + "@" + version
 	
 	if m.isolatedEnv != nil {
 		// Use isolated environment
-		downloadInfo, err := m.isolatedEnv.DownloadModule(moduleAtVersion)
+		downloadInfo, err := m.isolatedEnv.DownloadModule(ctx, moduleAtVersion)
 		if err != nil {
 			return "", err
 		}
@@ -164,10 +504,10 @@ func (m *Manager) downloadWithGoMod(modulePath, version string) (string, error)
 	}
 	
 	// Use host environment (existing behavior)
-	cmd := exec.Command("go", "mod", "download", "-json", moduleAtVersion)
+	cmd := exec.CommandContext(ctx, "go", "mod", "download", "-json", moduleAtVersion)
 	output, err := cmd.Output()
 	if err != nil {
-		return "", fmt.Errorf("go mod download failed: %w", err)
+		return "", fmt.Errorf("go mod download failed: %w", env.NewCommandError(cmd, output, err))
 	}
 
 	var downloadInfo GoModDownloadInfo
@@ -187,29 +527,29 @@ func (m *Manager) downloadWithGoMod(modulePath, version string) (string, error)
 	return downloadInfo.Dir, nil
 }
 
-func (m *Manager) cloneGitRepository(modulePath, version, localPath string) error {
+func (m *Manager) cloneGitRepository(ctx context.Context, modulePath, version, localPath string) error {
 	// Convert module path to git URL
 	gitURL := fmt.Sprintf("https://%s.git", modulePath)
 
 	// Clone the repository
-	cmd := exec.Command("git", "clone", "--depth", "1", "--branch", version, gitURL, localPath)
+	cmd := exec.CommandContext(ctx, "git", "clone", "--depth", "1", "--branch", version, gitURL, localPath)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		// If branch doesn't exist, try cloning without branch and then checkout
-		cmd = exec.Command("git", "clone", "--depth", "1", gitURL, localPath)
+		cmd = exec.CommandContext(ctx, "git", "clone", "--depth", "1", gitURL, localPath)
 		output, err = cmd.CombinedOutput()
 		if err != nil {
 			return fmt.Errorf("git clone failed: %s", string(output))
 		}
 
 		// Try to checkout the version
-		cmd = exec.Command("git", "-C", localPath, "fetch", "--depth", "1", "origin", version)
+		cmd = exec.CommandContext(ctx, "git", "-C", localPath, "fetch", "--depth", "1", "origin", version)
 		cmd.Run() // Ignore error, might be a tag
 
-		cmd = exec.Command("git", "-C", localPath, "checkout", version)
+		cmd = exec.CommandContext(ctx, "git", "-C", localPath, "checkout", version)
 		output, err = cmd.CombinedOutput()
 		if err != nil {
-			fmt.Printf("Warning: could not checkout version %s: %s\n", version, string(output))
+			m.logger.WarnContext(ctx, "Could not checkout version", "module", modulePath, "version", version, "output", string(output))
 		}
 	}
 
@@ -220,7 +560,7 @@ func (m *Manager) buildRepositoryInfo(moduleAtVersion, localPath string) (*Repos
 	modulePath, version := m.parseModuleAtVersion(moduleAtVersion)
 	
 	// Find all Go files
-	files, err := m.findGoFiles(localPath)
+	files, err := findGoFiles(localPath)
 	if err != nil {
 		return nil, err
 	}
@@ -230,10 +570,63 @@ func (m *Manager) buildRepositoryInfo(moduleAtVersion, localPath string) (*Repos
 		ModulePath:      modulePath,
 		Version:         version,
 		Files:           files,
+		Modules:         findModules(localPath, files),
 	}, nil
 }
 
-func (m *Manager) findGoFiles(rootPath string) ([]FileInfo, error) {
+// FindModules lists the modules declared by the go.mod files of the repository at
+// rootPath, the root module first
+func FindModules(rootPath string) ([]ModuleEntry, error) {
+	files, err := findGoFiles(rootPath)
+	if err != nil {
+		return nil, err
+	}
+	return findModules(rootPath, files), nil
+}
+
+// findModules lists the modules declared by the go.mod files among the files of the
+// repository at rootPath
+func findModules(rootPath string, files []FileInfo) []ModuleEntry {
+	var modules []ModuleEntry
+	for _, file := range files {
+		if path.Base(file.Path) != "go.mod" {
+			continue
+		}
+		dir := path.Dir(file.Path)
+		if dir == "." {
+			dir = ""
+		}
+		if slices.ContainsFunc(strings.Split(dir, "/"), SkipModuleDir) {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(rootPath, filepath.FromSlash(file.Path)))
+		if err != nil {
+			continue
+		}
+		modulePath := modfile.ModulePath(data)
+		if modulePath == "" {
+			continue
+		}
+		_, err = os.Stat(filepath.Join(rootPath, filepath.FromSlash(dir), "vendor", "modules.txt"))
+		modules = append(modules, ModuleEntry{Path: modulePath, Dir: dir, Vendored: err == nil})
+	}
+
+	// Root module first
+	sort.Slice(modules, func(i, j int) bool {
+		return modules[i].Dir < modules[j].Dir
+	})
+	return modules
+}
+
+// SkipModuleDir reports whether go.mod files below a directory named name are not
+// modules of the repository: those under testdata are fixtures, and those under
+// vendor or node_modules belong to dependencies
+func SkipModuleDir(name string) bool {
+	return name == "testdata" || name == "vendor" || name == "node_modules" || strings.HasPrefix(name, ".")
+}
+
+func findGoFiles(rootPath string) ([]FileInfo, error) {
 	var files []FileInfo
 
 	// Resolve symlinks to the actual path
@@ -256,9 +649,12 @@ func (m *Manager) findGoFiles(rootPath string) ([]FileInfo, error) {
 			return nil
 		}
 
-		// Skip vendor directory
+		// Skip vendor directories, except those created by go mod vendor which
+		// hold the vendored dependencies that references navigate to
 		if info.IsDir() && info.Name() == "vendor" {
-			return filepath.SkipDir
+			if _, err := os.Stat(filepath.Join(path, "modules.txt")); err != nil {
+				return filepath.SkipDir
+			}
 		}
 
 		if !info.IsDir() {
@@ -284,6 +680,16 @@ func (m *Manager) findGoFiles(rootPath string) ([]FileInfo, error) {
 	return files, err
 }
 
+// CacheDir returns the directory holding loaded repositories and the isolated environment
+func (m *Manager) CacheDir() string {
+	return m.cacheDir
+}
+
+// Policy returns the cache policy applied by Evict
+func (m *Manager) Policy() CachePolicy {
+	return m.policy
+}
+
 // GetIsolatedEnv returns the isolated environment if available
 func (m *Manager) GetIsolatedEnv() *env.IsolatedEnv {
 	return m.isolatedEnv
@@ -294,12 +700,55 @@ func (m *Manager) IsIsolated() bool {
 	return m.isolatedEnv != nil
 }
 
+// IsOffline returns true if the manager must not access the network
+func (m *Manager) IsOffline() bool {
+	return m.isolatedEnv != nil && m.isolatedEnv.Config().Offline
+}
+
+// PopulateProxy copies the modules downloaded so far into the configured proxy
+// directory, so they stay available in offline mode. It does nothing when no proxy
+// directory is configured or when running offline. It returns the number of module
+// versions added.
+func (m *Manager) PopulateProxy() (int, error) {
+	if m.isolatedEnv == nil || m.IsOffline() {
+		return 0, nil
+	}
+	proxyDir := m.isolatedEnv.Config().ProxyDir
+	if proxyDir == "" {
+		return 0, nil
+	}
+	return m.isolatedEnv.ExportToProxy(proxyDir)
+}
+
+// exportToProxy copies a downloaded module version and its downloaded go.sum
+// dependencies into the configured proxy directory, under the same conditions as
+// PopulateProxy
+func (m *Manager) exportToProxy(modulePath, version, moduleDir string) (int, error) {
+	if m.isolatedEnv == nil || m.IsOffline() {
+		return 0, nil
+	}
+	proxyDir := m.isolatedEnv.Config().ProxyDir
+	if proxyDir == "" {
+		return 0, nil
+	}
+	return m.isolatedEnv.ExportModuleToProxy(proxyDir, module.Version{Path: modulePath, Version: version}, moduleDir)
+}
+
 // Stats returns information about the manager and its environment
 func (m *Manager) Stats() map[string]interface{} {
 	stats := make(map[string]interface{})
 	stats["cache_dir"] = m.cacheDir
-	stats["loaded_repositories"] = len(m.repos)
+	stats["loaded_repositories"] = len(m.ListRepositories())
 	stats["isolated"] = m.IsIsolated()
+	stats["offline"] = m.IsOffline()
+
+	m.mutex.Lock()
+	stats["in_use_repositories"] = len(m.inUse)
+	m.mutex.Unlock()
+	if m.policy.Enabled() {
+		stats["cache_max_size"] = m.policy.MaxSize
+		stats["cache_ttl"] = m.policy.TTL.String()
+	}
 	
 	if m.isolatedEnv != nil {
 		stats["isolation_stats"] = m.isolatedEnv.Stats()
@@ -308,7 +757,8 @@ func (m *Manager) Stats() map[string]interface{} {
 	return stats
 }
 
-// Cleanup cleans up resources used by the manager
+// Cleanup deletes the isolated environment with every downloaded module. The
+// manager must not be used afterwards.
 func (m *Manager) Cleanup() error {
 	if m.isolatedEnv != nil {
 		return m.isolatedEnv.Cleanup()
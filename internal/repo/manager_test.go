@@ -1,11 +1,20 @@
 package repo
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"gonav/internal/env"
+	"gonav/internal/testutil"
 )
 
 func TestNewManager(t *testing.T) {
@@ -41,6 +50,261 @@ func TestNewManagerWithIsolationDisabled(t *testing.T) {
 	assert.Nil(t, manager.GetIsolatedEnv())
 }
 
+func TestNewManagerWithEnvConfig(t *testing.T) {
+	config := env.Config{
+		GoProxy:   "https://proxy.internal.example.com",
+		GoPrivate: "git.example.com/*",
+	}
+
+	// The configuration applies regardless of option order
+	manager, err := NewManager(WithEnvConfig(config), WithIsolation(true))
+	require.NoError(t, err)
+	defer manager.Cleanup()
+
+	require.True(t, manager.IsIsolated())
+	assert.Equal(t, config, manager.GetIsolatedEnv().Config())
+	assert.Contains(t, manager.GetIsolatedEnv().Environment(), "GOPRIVATE=git.example.com/*")
+
+	// Without isolation the configuration is not used
+	manager, err = NewManager(WithEnvConfig(config))
+	require.NoError(t, err)
+	assert.Nil(t, manager.GetIsolatedEnv())
+}
+
+func TestManagerOffline(t *testing.T) {
+	proxyDir := t.TempDir()
+	testutil.WriteProxyModule(t, proxyDir, "example.com/lib", "v1.0.0")
+
+	manager, err := NewManager(WithIsolation(true), WithEnvConfig(env.Config{Offline: true, ProxyDir: proxyDir}))
+	require.NoError(t, err)
+	defer manager.Cleanup()
+	assert.True(t, manager.IsOffline())
+
+	// Modules in the local proxy are served without network access
+	info, err := manager.LoadRepository(context.Background(), "example.com/lib@v1.0.0")
+	require.NoError(t, err)
+	assert.NotEmpty(t, info.Files)
+
+	// Anything else fails fast instead of falling back to git clone
+	_, err = manager.LoadRepository(context.Background(), "github.com/gorilla/mux@v1.8.0")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrNotAvailableOffline)
+}
+
+func TestManagerLoadRepositoryTimeout(t *testing.T) {
+	proxyDir := t.TempDir()
+	testutil.WriteProxyModule(t, proxyDir, "example.com/lib", "v1.0.0")
+	testutil.UseLocalProxy(t, proxyDir)
+
+	manager, err := NewManager(WithIsolation(true), WithCacheDir(t.TempDir()), WithTimeouts(Timeouts{Download: time.Nanosecond}))
+	require.NoError(t, err)
+	defer manager.Cleanup()
+
+	// The download timeout produces a clear error and no git clone fallback
+	_, err = manager.LoadRepository(context.Background(), "example.com/lib@v1.0.0")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "download of example.com/lib@v1.0.0 timed out after 1ns")
+	assert.Empty(t, manager.ListRepositories())
+
+	// Loads stop when the caller goes away
+	manager, err = NewManager(WithIsolation(true), WithCacheDir(t.TempDir()))
+	require.NoError(t, err)
+	defer manager.Cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = manager.LoadRepository(ctx, "github.com/gorilla/mux@v1.8.0")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = manager.ListVersions(ctx, "example.com/lib")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestManagerLoadRepositoryErrors(t *testing.T) {
+	proxyDir := t.TempDir()
+	testutil.WriteProxyModule(t, proxyDir, "example.com/lib", "v1.0.0")
+	testutil.UseLocalProxy(t, proxyDir)
+
+	manager, err := NewManager(WithIsolation(true), WithCacheDir(t.TempDir()))
+	require.NoError(t, err)
+	defer manager.Cleanup()
+
+	// Malformed module@version strings are rejected before downloading anything
+	for _, moduleAtVersion := range []string{"example.com/lib", "example.com/lib@", "example.com/lib name@v1.0.0", "a@b@c"} {
+		_, err := manager.LoadRepository(context.Background(), moduleAtVersion)
+		assert.ErrorIs(t, err, ErrInvalidModuleVersion, moduleAtVersion)
+	}
+
+	// Missing versions are reported as not found, with the output of the go command
+	_, err = manager.LoadRepository(context.Background(), "example.com/lib@v9.9.9")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrModuleNotFound)
+
+	var cmdErr *env.CommandError
+	require.ErrorAs(t, err, &cmdErr)
+	assert.Contains(t, cmdErr.Command, "go mod download -json example.com/lib@v9.9.9")
+	assert.Contains(t, cmdErr.Stderr, "example.com/lib@v9.9.9")
+	assert.Empty(t, manager.ListRepositories())
+}
+
+func TestManagerLoadRepositoryConcurrent(t *testing.T) {
+	proxyDir := t.TempDir()
+	testutil.WriteProxyModule(t, proxyDir, "example.com/lib", "v1.0.0")
+	testutil.UseLocalProxy(t, proxyDir)
+
+	manager, err := NewManager(WithIsolation(true), WithCacheDir(t.TempDir()))
+	require.NoError(t, err)
+	defer manager.Cleanup()
+
+	// Concurrent loads of the same version share one download and all succeed
+	const loads = 8
+	var wg sync.WaitGroup
+	infos := make([]*RepositoryInfo, loads)
+	errs := make([]error, loads)
+	for i := 0; i < loads; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			infos[i], errs[i] = manager.LoadRepository(context.Background(), "example.com/lib@v1.0.0")
+		}(i)
+	}
+	wg.Wait()
+
+	localPath := manager.GetRepositoryPath("example.com/lib@v1.0.0")
+	require.NotEmpty(t, localPath)
+	for i := 0; i < loads; i++ {
+		require.NoError(t, errs[i])
+		assert.Equal(t, "example.com/lib", infos[i].ModulePath)
+		assert.NotEmpty(t, infos[i].Files)
+	}
+	assert.Equal(t, []string{"example.com/lib@v1.0.0"}, manager.ListRepositories())
+	assert.Equal(t, 0, manager.loads.InFlight())
+
+	_, err = os.Stat(filepath.Join(localPath, "go.mod"))
+	assert.NoError(t, err)
+}
+
+func TestManagerPopulateProxy(t *testing.T) {
+	srcProxy := t.TempDir()
+	testutil.WriteProxyModule(t, srcProxy, "example.com/lib", "v1.0.0")
+	testutil.WriteProxyModule(t, srcProxy, "example.com/other", "v1.0.0")
+	testutil.UseLocalProxy(t, srcProxy)
+
+	proxyDir := filepath.Join(t.TempDir(), "proxy")
+	manager, err := NewManager(WithIsolation(true), WithEnvConfig(env.Config{ProxyDir: proxyDir}))
+	require.NoError(t, err)
+	defer manager.Cleanup()
+
+	// Downloads made online are copied into the proxy directory, without the rest of
+	// the module cache
+	_, err = manager.GetIsolatedEnv().DownloadModule(context.Background(), "example.com/other@v1.0.0")
+	require.NoError(t, err)
+	_, err = manager.LoadRepository(context.Background(), "example.com/lib@v1.0.0")
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(proxyDir, "example.com", "lib", "@v", "v1.0.0.zip"))
+	assert.NoDirExists(t, filepath.Join(proxyDir, "example.com", "other"))
+}
+
+func TestManagerPersistentCacheDir(t *testing.T) {
+	proxyDir := t.TempDir()
+	testutil.WriteProxyModule(t, proxyDir, "example.com/lib", "v1.0.0")
+	testutil.UseLocalProxy(t, proxyDir)
+
+	baseDir := t.TempDir()
+	manager, err := NewManager(WithIsolation(true), WithCacheDir(baseDir))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(baseDir, "gonav-cache"), manager.CacheDir())
+
+	_, err = manager.LoadRepository(context.Background(), "example.com/lib@v1.0.0")
+	require.NoError(t, err)
+
+	// A new manager on the same directory reuses the downloaded modules,
+	// without any access to the module proxy
+	t.Setenv("GOPROXY", "off")
+	restarted, err := NewManager(WithIsolation(true), WithCacheDir(baseDir))
+	require.NoError(t, err)
+	defer restarted.Cleanup()
+
+	versions, err := restarted.GetIsolatedEnv().CachedVersions("example.com/lib")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v1.0.0"}, versions)
+
+	info, err := restarted.LoadRepository(context.Background(), "example.com/lib@v1.0.0")
+	require.NoError(t, err)
+	assert.NotEmpty(t, info.Files)
+
+	// The clean command finds and removes the files, read-only module cache
+	// included, without a manager
+	cacheDir, err := CacheDirFor(baseDir)
+	require.NoError(t, err)
+	assert.Equal(t, restarted.CacheDir(), cacheDir)
+	require.NoError(t, env.RemoveDir(cacheDir))
+	assert.NoDirExists(t, cacheDir)
+	assert.NoError(t, env.RemoveDir(cacheDir))
+}
+
+func TestManagerNestedModules(t *testing.T) {
+	repoDir := t.TempDir()
+	files := map[string]string{
+		"go.mod":                     "module example.com/root\n",
+		"main.go":                    "package main\n",
+		"tools/go.mod":               "module example.com/root/tools\n",
+		"sdk/v2/go.mod":              "module example.com/root/sdk/v2\n",
+		"internal/testdata/x/go.mod": "module fixture\n",
+		"testdata2/go.mod":           "module example.com/root/testdata2\n",
+		"web/node_modules/x/go.mod":  "module dependency\n",
+	}
+	testutil.WriteFiles(t, repoDir, files)
+
+	manager, err := NewManager()
+	require.NoError(t, err)
+
+	info, err := manager.buildRepositoryInfo("example.com/root@v1.0.0", repoDir)
+	require.NoError(t, err)
+	assert.Equal(t, []ModuleEntry{
+		{Path: "example.com/root", Dir: ""},
+		{Path: "example.com/root/sdk/v2", Dir: "sdk/v2"},
+		{Path: "example.com/root/testdata2", Dir: "testdata2"},
+		{Path: "example.com/root/tools", Dir: "tools"},
+	}, info.Modules)
+
+	modules, err := FindModules(repoDir)
+	require.NoError(t, err)
+	assert.Equal(t, info.Modules, modules)
+}
+
+func TestManagerVendoredRepository(t *testing.T) {
+	repoDir := t.TempDir()
+	files := map[string]string{
+		"go.mod":                        "module example.com/app\n",
+		"main.go":                       "package main\n",
+		"vendor/modules.txt":            "# example.com/lib v1.2.0\nexample.com/lib\n",
+		"vendor/example.com/lib/lib.go": "package lib\n",
+		"vendor/example.com/lib/go.mod": "module example.com/lib\n",
+		"tools/vendor/stale/stale.go":   "package stale\n",
+	}
+	testutil.WriteFiles(t, repoDir, files)
+
+	manager, err := NewManager()
+	require.NoError(t, err)
+
+	info, err := manager.buildRepositoryInfo("example.com/app@v1.0.0", repoDir)
+	require.NoError(t, err)
+
+	paths := make([]string, 0, len(info.Files))
+	for _, file := range info.Files {
+		paths = append(paths, file.Path)
+	}
+	// Vendor trees created by go mod vendor are listed, other vendor directories are not
+	assert.Contains(t, paths, "vendor/example.com/lib/lib.go")
+	assert.NotContains(t, paths, "tools/vendor/stale/stale.go")
+
+	assert.Equal(t, []ModuleEntry{
+		{Path: "example.com/app", Dir: "", Vendored: true},
+	}, info.Modules)
+}
+
 func TestManagerStats(t *testing.T) {
 	// Test normal manager stats
 	manager, err := NewManager()
@@ -68,7 +332,7 @@ func TestManagerLoadRepositoryNormal(t *testing.T) {
 	require.NoError(t, err)
 
 	// Test loading a repository (this will use host environment)
-	repoInfo, err := manager.LoadRepository("github.com/arnodel/golua@v0.1.0")
+	repoInfo, err := manager.LoadRepository(context.Background(), "github.com/arnodel/golua@v0.1.0")
 	require.NoError(t, err)
 
 	assert.Equal(t, "github.com/arnodel/golua@v0.1.0", repoInfo.ModuleAtVersion)
@@ -87,7 +351,7 @@ func TestManagerLoadRepositoryIsolated(t *testing.T) {
 	defer manager.Cleanup()
 
 	// Test loading a repository in isolation
-	repoInfo, err := manager.LoadRepository("github.com/arnodel/golua@v0.1.0")
+	repoInfo, err := manager.LoadRepository(context.Background(), "github.com/arnodel/golua@v0.1.0")
 	require.NoError(t, err)
 
 	assert.Equal(t, "github.com/arnodel/golua@v0.1.0", repoInfo.ModuleAtVersion)
@@ -101,6 +365,40 @@ func TestManagerLoadRepositoryIsolated(t *testing.T) {
 	assert.Equal(t, 1, isolationStats["cached_modules"])
 }
 
+func TestManagerListVersions(t *testing.T) {
+	const pseudoVersion = "v0.0.0-20240101000000-abcdefabcdef"
+
+	proxyDir := t.TempDir()
+	testutil.WriteProxyModule(t, proxyDir, "example.com/versioned", "v1.0.0")
+	testutil.WriteProxyModule(t, proxyDir, "example.com/versioned", "v1.1.0")
+	testutil.WriteProxyModule(t, proxyDir, "example.com/versioned", pseudoVersion)
+	testutil.UseLocalProxy(t, proxyDir)
+
+	manager, err := NewManager(WithIsolation(true))
+	require.NoError(t, err)
+	defer manager.Cleanup()
+
+	_, err = manager.LoadRepository(context.Background(), "example.com/versioned@v1.0.0")
+	require.NoError(t, err)
+	_, err = manager.LoadRepository(context.Background(), "example.com/versioned@" + pseudoVersion)
+	require.NoError(t, err)
+
+	versions, err := manager.ListVersions(context.Background(), "example.com/versioned")
+	require.NoError(t, err)
+	assert.Equal(t, "example.com/versioned", versions.ModulePath)
+
+	// Tagged versions come from the proxy, the pseudo-version only from the local cache
+	assert.Equal(t, []VersionInfo{
+		{Version: pseudoVersion, Pseudo: true, Cached: true, Loaded: true},
+		{Version: "v1.0.0", Cached: true, Loaded: true},
+		{Version: "v1.1.0"},
+	}, versions.Versions)
+
+	// Invalid module paths are rejected before running the go command
+	_, err = manager.ListVersions(context.Background(), "not a module")
+	assert.Error(t, err)
+}
+
 func TestManagerHostIsolation(t *testing.T) {
 	// This test verifies that isolated downloads don't affect host
 	// Get host GOMODCACHE before test
@@ -111,7 +409,7 @@ func TestManagerHostIsolation(t *testing.T) {
 	defer manager.Cleanup()
 
 	// Download in isolation
-	_, err = manager.LoadRepository("github.com/arnodel/golua@v0.1.0")
+	_, err = manager.LoadRepository(context.Background(), "github.com/arnodel/golua@v0.1.0")
 	require.NoError(t, err)
 
 	// Verify host environment is unchanged
@@ -149,8 +447,8 @@ func TestManagerCompatibility(t *testing.T) {
 	defer isolatedManager.Cleanup()
 
 	// Load same repository with both managers
-	normalRepo, err1 := normalManager.LoadRepository("github.com/arnodel/golua@v0.1.0")
-	isolatedRepo, err2 := isolatedManager.LoadRepository("github.com/arnodel/golua@v0.1.0")
+	normalRepo, err1 := normalManager.LoadRepository(context.Background(), "github.com/arnodel/golua@v0.1.0")
+	isolatedRepo, err2 := isolatedManager.LoadRepository(context.Background(), "github.com/arnodel/golua@v0.1.0")
 
 	require.NoError(t, err1)
 	require.NoError(t, err2)
@@ -163,4 +461,22 @@ func TestManagerCompatibility(t *testing.T) {
 	// Both should have Go files
 	assert.Greater(t, len(normalRepo.Files), 0)
 	assert.Greater(t, len(isolatedRepo.Files), 0)
-}
\ No newline at end of file
+}
+func TestDownloadResult(t *testing.T) {
+	tests := []struct {
+		err    error
+		result string
+	}{
+		{nil, "success"},
+		{fmt.Errorf("failed to download repository: %w", ErrModuleNotFound), "not_found"},
+		{fmt.Errorf("failed to download repository: %w", ErrInvalidModuleVersion), "invalid"},
+		{fmt.Errorf("%w: example.com/lib@v1.0.0", ErrNotAvailableOffline), "offline"},
+		{fmt.Errorf("downloading example.com/lib@v1.0.0: %w", context.DeadlineExceeded), "timeout"},
+		{fmt.Errorf("downloading example.com/lib@v1.0.0: %w", context.Canceled), "canceled"},
+		{errors.New("exit status 1"), "error"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.result, downloadResult(tt.err), fmt.Sprint(tt.err))
+	}
+}
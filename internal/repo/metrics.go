@@ -0,0 +1,42 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gonav/internal/metrics"
+)
+
+var (
+	moduleDownloads = metrics.Default.NewCounterVec("gonav_module_downloads_total",
+		"Repository downloads, by result (success, not_found, invalid, offline, timeout, canceled or error).", "result")
+
+	moduleDownloadDuration = metrics.Default.NewHistogramVec("gonav_module_download_duration_seconds",
+		"Duration of repository downloads, successful or not.", metrics.DefaultBuckets)
+)
+
+// observeDownload records a repository download started at start
+func observeDownload(start time.Time, err error) {
+	moduleDownloadDuration.With().Observe(time.Since(start).Seconds())
+	moduleDownloads.With(downloadResult(err)).Inc()
+}
+
+// downloadResult returns the result label of a download failing with err
+func downloadResult(err error) string {
+	switch {
+	case err == nil:
+		return "success"
+	case errors.Is(err, ErrModuleNotFound):
+		return "not_found"
+	case errors.Is(err, ErrInvalidModuleVersion):
+		return "invalid"
+	case errors.Is(err, ErrNotAvailableOffline):
+		return "offline"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	}
+	return "error"
+}
@@ -0,0 +1,75 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// ErrInvalidPath is returned for paths that are not clean paths relative to the repository
+	ErrInvalidPath = errors.New("invalid path")
+
+	// ErrPathOutsideRepository is returned for paths that resolve outside the repository,
+	// through ".." elements or symbolic links
+	ErrPathOutsideRepository = errors.New("path outside repository")
+)
+
+// ValidatePath checks that relPath, a slash-separated path taken from a request, names
+// a location inside a repository: it must be relative, and free of ".." elements,
+// backslashes and NUL bytes. The empty path names the repository root.
+func ValidatePath(relPath string) error {
+	switch {
+	case strings.ContainsRune(relPath, 0):
+		return fmt.Errorf("%w: contains a NUL byte", ErrInvalidPath)
+	case strings.Contains(relPath, `\`):
+		return fmt.Errorf("%w: contains a backslash", ErrInvalidPath)
+	case path.IsAbs(relPath) || filepath.IsAbs(relPath) || filepath.VolumeName(relPath) != "":
+		return fmt.Errorf("%w: %q is absolute", ErrInvalidPath, relPath)
+	}
+
+	for _, element := range strings.Split(relPath, "/") {
+		if element == ".." {
+			return fmt.Errorf("%w: %q contains \"..\"", ErrInvalidPath, relPath)
+		}
+	}
+	return nil
+}
+
+// ResolvePath returns the absolute path of relPath inside the repository at root, with
+// symbolic links resolved. Paths rejected by ValidatePath fail with ErrInvalidPath, and
+// paths leading outside the resolved root, such as a symbolic link to /etc, fail with
+// ErrPathOutsideRepository. Missing files fail with an error wrapping fs.ErrNotExist.
+func ResolvePath(root, relPath string) (string, error) {
+	if err := ValidatePath(relPath); err != nil {
+		return "", err
+	}
+
+	// The repository root itself may be a link into the module cache
+	resolvedRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve repository root: %w", err)
+	}
+
+	resolved, err := filepath.EvalSymlinks(filepath.Join(resolvedRoot, filepath.FromSlash(relPath)))
+	if err != nil {
+		// Links whose target is missing are reported as missing files
+		return "", fmt.Errorf("failed to resolve %s: %w", relPath, err)
+	}
+
+	if !isWithin(resolvedRoot, resolved) {
+		return "", fmt.Errorf("%w: %s", ErrPathOutsideRepository, relPath)
+	}
+	return resolved, nil
+}
+
+// isWithin returns true if path is dir or inside it. Both must be clean absolute paths.
+func isWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
@@ -0,0 +1,102 @@
+package repo
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeRepoWithLinks creates a repository next to a secret file, with symbolic links
+// pointing inside and outside of it
+func writeRepoWithLinks(t *testing.T) (string, string) {
+	base := t.TempDir()
+	root := filepath.Join(base, "repo")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "pkg"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "pkg", "lib.go"), []byte("package pkg\n"), 0644))
+
+	secret := filepath.Join(base, "secret.txt")
+	require.NoError(t, os.WriteFile(secret, []byte("secret"), 0644))
+
+	require.NoError(t, os.Symlink(secret, filepath.Join(root, "secret.go")))
+	require.NoError(t, os.Symlink("../secret.txt", filepath.Join(root, "relative.go")))
+	require.NoError(t, os.Symlink(base, filepath.Join(root, "parent")))
+	require.NoError(t, os.Symlink("/etc", filepath.Join(root, "etc")))
+	require.NoError(t, os.Symlink(filepath.Join(root, "pkg", "lib.go"), filepath.Join(root, "alias.go")))
+	require.NoError(t, os.Symlink(filepath.Join(base, "missing"), filepath.Join(root, "dangling.go")))
+	return root, secret
+}
+
+func TestResolvePath(t *testing.T) {
+	root, _ := writeRepoWithLinks(t)
+	resolvedRoot, err := filepath.EvalSymlinks(root)
+	require.NoError(t, err)
+
+	// Paths inside the repository resolve, including links to other files in it
+	for relPath, expected := range map[string]string{
+		"":           resolvedRoot,
+		"main.go":    filepath.Join(resolvedRoot, "main.go"),
+		"pkg":        filepath.Join(resolvedRoot, "pkg"),
+		"pkg/lib.go": filepath.Join(resolvedRoot, "pkg", "lib.go"),
+		"./main.go":  filepath.Join(resolvedRoot, "main.go"),
+		"alias.go":   filepath.Join(resolvedRoot, "pkg", "lib.go"),
+
+		// Leaving through a link and coming back is still inside
+		"parent/repo/main.go": filepath.Join(resolvedRoot, "main.go"),
+	} {
+		resolved, err := ResolvePath(root, relPath)
+		require.NoError(t, err, relPath)
+		assert.Equal(t, expected, resolved, relPath)
+	}
+
+	// A repository root that is itself a link, as repositories linked from the module cache
+	linkedRoot := filepath.Join(t.TempDir(), "linked")
+	require.NoError(t, os.Symlink(root, linkedRoot))
+	resolved, err := ResolvePath(linkedRoot, "pkg/lib.go")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(resolvedRoot, "pkg", "lib.go"), resolved)
+
+	_, err = ResolvePath(root, "missing.go")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	_, err = ResolvePath(root, "dangling.go")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestResolvePath_MaliciousPaths(t *testing.T) {
+	root, _ := writeRepoWithLinks(t)
+
+	invalid := []string{
+		"../secret.txt",
+		"../../../../etc/passwd",
+		"pkg/../../secret.txt",
+		"pkg/../main.go",
+		"..",
+		"/etc/passwd",
+		"//etc/passwd",
+		`..\secret.txt`,
+		`pkg\..\..\secret.txt`,
+		"main.go\x00.txt",
+	}
+	for _, relPath := range invalid {
+		_, err := ResolvePath(root, relPath)
+		assert.ErrorIs(t, err, ErrInvalidPath, "%q", relPath)
+	}
+
+	// Links escaping the repository are refused, wherever they appear in the path
+	escaping := []string{
+		"secret.go",
+		"relative.go",
+		"parent",
+		"parent/secret.txt",
+		"etc/passwd",
+		"etc",
+	}
+	for _, relPath := range escaping {
+		_, err := ResolvePath(root, relPath)
+		assert.ErrorIs(t, err, ErrPathOutsideRepository, "%q", relPath)
+	}
+}
@@ -0,0 +1,90 @@
+// Package testutil holds fixtures shared by the tests of several packages
+package testutil
+
+import (
+	"archive/zip"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/mod/module"
+)
+
+// WriteFiles writes files, by slash-separated path relative to dir, creating their
+// parent directories
+func WriteFiles(t testing.TB, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+// TempFiles writes files, by slash-separated path, to a new temporary directory
+// removed at the end of the test, and returns the directory. Files of later maps
+// replace those of earlier maps with the same path.
+func TempFiles(t testing.TB, files ...map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, f := range files {
+		WriteFiles(t, dir, f)
+	}
+	return dir
+}
+
+// WriteProxyModule adds a module version to a GOPROXY=file:// directory.
+// Pseudo-versions are not added to the version list, like on a real proxy.
+func WriteProxyModule(t testing.TB, proxyDir, modulePath, version string) {
+	t.Helper()
+	WriteProxyModuleFiles(t, proxyDir, modulePath, version, map[string]string{
+		"lib.go": "package lib\n\nfunc Hello() string { return \"hello\" }\n",
+	})
+}
+
+// WriteProxyModuleFiles is WriteProxyModule with the given source files, by
+// slash-separated path relative to the module root. go.mod is written for them.
+func WriteProxyModuleFiles(t testing.TB, proxyDir, modulePath, version string, files map[string]string) {
+	t.Helper()
+	escapedPath, err := module.EscapePath(modulePath)
+	require.NoError(t, err)
+	versionDir := filepath.Join(proxyDir, escapedPath, "@v")
+	require.NoError(t, os.MkdirAll(versionDir, 0755))
+
+	goMod := fmt.Sprintf("module %s\n\ngo 1.21\n", modulePath)
+	info := fmt.Sprintf(`{"Version":%q,"Time":"2024-01-01T00:00:00Z"}`, version)
+	require.NoError(t, os.WriteFile(filepath.Join(versionDir, version+".info"), []byte(info), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(versionDir, version+".mod"), []byte(goMod), 0644))
+
+	zipFile, err := os.Create(filepath.Join(versionDir, version+".zip"))
+	require.NoError(t, err)
+	zw := zip.NewWriter(zipFile)
+	files = maps.Clone(files)
+	files["go.mod"] = goMod
+	for name, content := range files {
+		fw, err := zw.Create(modulePath + "@" + version + "/" + name)
+		require.NoError(t, err)
+		_, err = fw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	require.NoError(t, zipFile.Close())
+
+	if !module.IsPseudoVersion(version) {
+		list, err := os.OpenFile(filepath.Join(versionDir, "list"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		require.NoError(t, err)
+		_, err = list.WriteString(version + "\n")
+		require.NoError(t, err)
+		require.NoError(t, list.Close())
+	}
+}
+
+// UseLocalProxy points the go command at a local file:// proxy for the duration of the test
+func UseLocalProxy(t *testing.T, proxyDir string) {
+	t.Setenv("GOPROXY", "file://"+filepath.ToSlash(proxyDir))
+	t.Setenv("GOSUMDB", "off")
+	t.Setenv("GOFLAGS", "-mod=mod")
+}
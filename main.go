@@ -3,43 +3,106 @@ package main
 import (
 	"context"
 	"encoding/json"
-	"flag"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
-	"net/url"
 	"os"
 	"os/signal"
-	"path/filepath"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"gonav/internal/analyzer"
+	"gonav/internal/config"
+	"gonav/internal/env"
+	"gonav/internal/logging"
+	"gonav/internal/metrics"
 	"gonav/internal/repo"
 )
 
 type Server struct {
 	repoManager   *repo.Manager
 	analyzer      *analyzer.PackageAnalyzer
+	logger        *slog.Logger
 	// Cache for package discoveries per repository
 	discoveryCache map[string]map[string]*analyzer.PackageDiscovery
+	discoveryMutex sync.Mutex
+
+	// Background dependency downloads, listed and canceled by the admin endpoints
+	dependencyLoader *analyzer.DependencyLoader
+	// Package and file analyses of every loaded repository
+	analysisCache *analyzer.AnalysisCache
+	// Downloads of the dependencies missing from cached analyses
+	dependencyQueue *analyzer.DependencyQueue
+	// Bearer token required by the admin endpoints, none if empty
+	adminToken string
 }
 
-func NewServer(repoManager *repo.Manager) *Server {
+func NewServer(repoManager *repo.Manager, logger *slog.Logger) *Server {
+	logger = logging.OrDefault(logger)
+
 	// Always use enhanced analyzer with packages support
-	analyzerInstance := analyzer.New()
-	fmt.Println("Enhanced analyzer with golang.org/x/tools/go/packages enabled")
+	analyzerInstance := analyzer.New().SetLogger(logger)
+	logger.Debug("Enhanced analyzer with golang.org/x/tools/go/packages enabled")
 	
-	return &Server{
-		repoManager:    repoManager,
-		analyzer:       analyzerInstance,
-		discoveryCache: make(map[string]map[string]*analyzer.PackageDiscovery),
+	// Missing dependencies are downloaded in the shared module cache
+	var goEnv []string
+	if isolatedEnv := repoManager.GetIsolatedEnv(); isolatedEnv != nil {
+		goEnv = isolatedEnv.Environment()
+	}
+	dependencyLoader := analyzer.NewDependencyLoader(repoManager.CacheDir(), goEnv)
+	dependencyLoader.SetLogger(logger)
+	analyzerInstance.SetDependencyLoader(dependencyLoader)
+	analysisCache := analyzer.NewAnalysisCache(&analyzer.SimpleDependencyChecker{Logger: logger})
+	analyzerInstance.SetAnalysisCache(analysisCache)
+	queueConfig := analyzer.DefaultDependencyQueueConfig()
+	queueConfig.Logger = logger
+	queueConfig.Env = goEnv
+	dependencyQueue := analyzer.NewDependencyQueue(queueConfig)
+	analyzerInstance.SetDependencyQueue(dependencyQueue)
+
+	s := &Server{
+		repoManager:      repoManager,
+		analyzer:         analyzerInstance,
+		logger:           logger,
+		discoveryCache:   make(map[string]map[string]*analyzer.PackageDiscovery),
+		dependencyLoader: dependencyLoader,
+		analysisCache:    analysisCache,
+		dependencyQueue:  dependencyQueue,
 	}
+
+	// Loaded module graphs are kept in memory until their repository is unloaded
+	repoManager.OnUnload(func(moduleAtVersion, localPath string) {
+		analyzerInstance.ReleaseRepository(localPath)
+
+		s.discoveryMutex.Lock()
+		delete(s.discoveryCache, moduleAtVersion)
+		s.discoveryMutex.Unlock()
+	})
+
+	// Finished dependency jobs and analyses are dropped on the eviction schedule, no
+	// later than the cache TTL
+	repoManager.OnEvict(func(policy repo.CachePolicy) {
+		ttl := finishedJobTTL
+		if policy.TTL > 0 && policy.TTL < ttl {
+			ttl = policy.TTL
+		}
+		if removed := dependencyLoader.EvictJobs(ttl, maxFinishedJobs); removed > 0 {
+			logger.Info("Evicted finished dependency jobs", "count", removed)
+		}
+		if removed := analysisCache.Evict(policy.TTL, maxAnalysisEntries); removed > 0 {
+			logger.Info("Evicted analyses", "count", removed)
+		}
+	})
+
+	return s
 }
 
 // configureAnalyzerForRepository configures the analyzer with repository context for enhanced analysis
-func (s *Server) configureAnalyzerForRepository(repoPath string) {
+func (s *Server) configureAnalyzerForRepository(ctx context.Context, repoPath string) {
 	// Get environment from repository manager (always using isolation)
 	var env []string
 	isolatedEnv := s.repoManager.GetIsolatedEnv()
@@ -49,39 +112,30 @@ func (s *Server) configureAnalyzerForRepository(repoPath string) {
 	
 	// Configure analyzer with repository context
 	s.analyzer.SetRepositoryContext(repoPath, env)
-	fmt.Printf("Configured enhanced analyzer for repository at %s\n", repoPath)
+	s.logger.DebugContext(ctx, "Configured enhanced analyzer", "repo_path", repoPath)
 }
 
 func (s *Server) handleRepo(w http.ResponseWriter, r *http.Request) {
-	// Enable CORS
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-
-	if r.Method == http.MethodOptions {
-		return
-	}
-
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	if !beginGet(w, r) {
 		return
 	}
 
 	// Extract module@version from URL path
-	path := strings.TrimPrefix(r.URL.Path, "/api/repo/")
-	moduleAtVersion, err := url.QueryUnescape(path)
+	moduleAtVersion, err := decodeAPIPath(r, "/api/repo/")
 	if err != nil {
-		http.Error(w, "Invalid module format", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, CodeInvalidModuleVersion, "Invalid module format", nil)
 		return
 	}
 
-	fmt.Printf("Loading repository: '%s'\n", moduleAtVersion)
-	fmt.Printf("Raw URL path was: '%s'\n", strings.TrimPrefix(r.URL.Path, "/api/repo/"))
+	s.logger.InfoContext(r.Context(), "Loading repository", "module", moduleAtVersion)
+
+	// Keep the repository from being evicted while we use it
+	defer s.repoManager.Acquire(moduleAtVersion)()
 
 	// Load repository
-	repoInfo, err := s.repoManager.LoadRepository(moduleAtVersion)
+	repoInfo, err := s.repoManager.LoadRepository(r.Context(), moduleAtVersion)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to load repository: %v", err), http.StatusInternalServerError)
+		s.writeOperationError(w, r, CodeDownloadFailed, "Failed to load repository", err)
 		return
 	}
 
@@ -89,14 +143,16 @@ func (s *Server) handleRepo(w http.ResponseWriter, r *http.Request) {
 	repoPath := s.repoManager.GetRepositoryPath(moduleAtVersion)
 	if repoPath != "" {
 		// Configure analyzer with repository context for enhanced analysis
-		s.configureAnalyzerForRepository(repoPath)
+		s.configureAnalyzerForRepository(r.Context(), repoPath)
 		
 		packageDiscoveries, err := s.analyzer.DiscoverPackages(repoPath)
 		if err != nil {
-			fmt.Printf("Failed to discover packages (continuing anyway): %v\n", err)
+			s.logger.WarnContext(r.Context(), "Failed to discover packages, continuing anyway", "module", moduleAtVersion, "error", err)
 		} else {
+			s.discoveryMutex.Lock()
 			s.discoveryCache[moduleAtVersion] = packageDiscoveries
-			fmt.Printf("Successfully discovered %d packages\n", len(packageDiscoveries))
+			s.discoveryMutex.Unlock()
+			s.logger.DebugContext(r.Context(), "Discovered packages", "module", moduleAtVersion, "count", len(packageDiscoveries))
 		}
 	}
 
@@ -104,29 +160,40 @@ func (s *Server) handleRepo(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(repoInfo)
 }
 
-func (s *Server) handlePackage(w http.ResponseWriter, r *http.Request) {
-	// Enable CORS
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+func (s *Server) handleVersions(w http.ResponseWriter, r *http.Request) {
+	if !beginGet(w, r) {
+		return
+	}
+
+	// Extract module path from URL path
+	modulePath, err := decodeAPIPath(r, "/api/versions/")
+	if err != nil || modulePath == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidModuleVersion, "Invalid module format", nil)
+		return
+	}
 
-	if r.Method == http.MethodOptions {
+	s.logger.InfoContext(r.Context(), "Listing versions", "module", modulePath)
+
+	versions, err := s.repoManager.ListVersions(r.Context(), modulePath)
+	if err != nil {
+		s.writeOperationError(w, r, CodeListVersionsFailed, "Failed to list versions", err)
 		return
 	}
 
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(versions)
+}
+
+func (s *Server) handlePackage(w http.ResponseWriter, r *http.Request) {
+	if !beginGet(w, r) {
 		return
 	}
 
 	// Extract module@version and package path from URL
 	// URL format: /api/package/{module@version}/{package_path}
-	path := strings.TrimPrefix(r.URL.Path, "/api/package/")
-	
-	// First, let's URL decode the entire path
-	decodedPath, err := url.QueryUnescape(path)
+	decodedPath, err := decodeAPIPath(r, "/api/package/")
 	if err != nil {
-		http.Error(w, "Invalid URL encoding", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Invalid URL encoding", nil)
 		return
 	}
 	
@@ -136,7 +203,7 @@ func (s *Server) handlePackage(w http.ResponseWriter, r *http.Request) {
 	
 	atIndex := strings.Index(decodedPath, "@")
 	if atIndex == -1 {
-		http.Error(w, "Invalid module@version format", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, CodeInvalidModuleVersion, "Invalid module@version format", nil)
 		return
 	}
 	
@@ -157,56 +224,51 @@ func (s *Server) handlePackage(w http.ResponseWriter, r *http.Request) {
 		packagePath = decodedPath[moduleAtVersionEnd+1:]
 	}
 
-	fmt.Printf("Analyzing package: '%s' in repository: '%s'\n", packagePath, moduleAtVersion)
+	s.logger.InfoContext(r.Context(), "Analyzing package", "module", moduleAtVersion, "package", packagePath)
+	defer s.repoManager.Acquire(moduleAtVersion)()
 
 	// Get repository path
 	repoPath := s.repoManager.GetRepositoryPath(moduleAtVersion)
 	if repoPath == "" {
-		fmt.Printf("Repository not found for: '%s'\n", moduleAtVersion)
-		http.Error(w, "Repository not loaded", http.StatusNotFound)
+		writeRepoNotLoaded(w, moduleAtVersion)
+		return
+	}
+
+	// The package directory must be inside the repository
+	packageDir, err := repo.ResolvePath(repoPath, packagePath)
+	if err != nil {
+		s.writePathError(w, r, packagePath, err)
+		return
+	}
+	if info, err := os.Stat(packageDir); err != nil || !info.IsDir() {
+		writeError(w, http.StatusBadRequest, CodeInvalidPath, fmt.Sprintf("Not a package directory: %s", packagePath), &ErrorDetails{Path: packagePath})
 		return
 	}
 
 	// Analyze the specific package
-	packageInfo, err := s.analyzer.AnalyzePackage(repoPath, packagePath)
+	packageInfo, err := s.analyzer.AnalyzePackage(r.Context(), repoPath, packagePath)
 	if err != nil {
-		fmt.Printf("Failed to analyze package: %v\n", err)
-		http.Error(w, fmt.Sprintf("Failed to analyze package: %v", err), http.StatusInternalServerError)
+		s.writeOperationError(w, r, CodeAnalysisFailed, "Failed to analyze package", err)
 		return
 	}
 
-	fmt.Printf("Successfully analyzed package with %d symbols and %d files\n", 
-		len(packageInfo.Symbols), len(packageInfo.Files))
+	s.logger.DebugContext(r.Context(), "Analyzed package", "package", packagePath,
+		"symbols", len(packageInfo.Symbols), "files", len(packageInfo.Files))
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(packageInfo)
 }
 
 func (s *Server) handleFile(w http.ResponseWriter, r *http.Request) {
-	// Enable CORS
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-
-	if r.Method == http.MethodOptions {
-		return
-	}
-
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	if !beginGet(w, r) {
 		return
 	}
 
 	// Extract module@version and file path from URL
-	path := strings.TrimPrefix(r.URL.Path, "/api/file/")
-	
 	// The path will be like: github.com%2Fowner%2Frepo%40version/path/to/file.go
-	// We need to find the first unescaped '/' to split module from file path
-	
-	// First, let's URL decode the entire path
-	decodedPath, err := url.QueryUnescape(path)
+	decodedPath, err := decodeAPIPath(r, "/api/file/")
 	if err != nil {
-		http.Error(w, "Invalid URL encoding", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Invalid URL encoding", nil)
 		return
 	}
 	
@@ -216,7 +278,7 @@ func (s *Server) handleFile(w http.ResponseWriter, r *http.Request) {
 	
 	atIndex := strings.Index(decodedPath, "@")
 	if atIndex == -1 {
-		http.Error(w, "Invalid module@version format", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, CodeInvalidModuleVersion, "Invalid module@version format", nil)
 		return
 	}
 	
@@ -224,7 +286,7 @@ func (s *Server) handleFile(w http.ResponseWriter, r *http.Request) {
 	versionStart := atIndex + 1
 	slashAfterVersion := strings.Index(decodedPath[versionStart:], "/")
 	if slashAfterVersion == -1 {
-		http.Error(w, "Invalid file path format", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, CodeInvalidPath, "Invalid file path format", nil)
 		return
 	}
 	
@@ -232,30 +294,40 @@ func (s *Server) handleFile(w http.ResponseWriter, r *http.Request) {
 	moduleAtVersion := decodedPath[:moduleAtVersionEnd]
 	filePath := decodedPath[moduleAtVersionEnd+1:]
 
-	fmt.Printf("Loading file: '%s' from repository: '%s'\n", filePath, moduleAtVersion)
+	s.logger.InfoContext(r.Context(), "Loading file", "module", moduleAtVersion, "file", filePath)
+	defer s.repoManager.Acquire(moduleAtVersion)()
 
 	// Get repository path
 	repoPath := s.repoManager.GetRepositoryPath(moduleAtVersion)
 	if repoPath == "" {
-		fmt.Printf("Repository not found for: '%s'\n", moduleAtVersion)
-		fmt.Printf("Available repositories: %v\n", s.repoManager.ListRepositories())
-		http.Error(w, "Repository not loaded", http.StatusNotFound)
+		writeRepoNotLoaded(w, moduleAtVersion)
 		return
 	}
 
-	fmt.Printf("Repository path: '%s'\n", repoPath)
-
-	// Parse file
-	fullPath := filepath.Join(repoPath, filePath)
-	fmt.Printf("Attempting to parse file at: '%s'\n", fullPath)
+	// Every file access stays confined to the repository directory
+	fullPath, err := repo.ResolvePath(repoPath, filePath)
+	if err != nil {
+		s.writePathError(w, r, filePath, err)
+		return
+	}
+	if info, err := os.Stat(fullPath); err != nil || !info.Mode().IsRegular() {
+		writeError(w, http.StatusBadRequest, CodeInvalidPath, fmt.Sprintf("Not a file: %s", filePath), &ErrorDetails{Path: filePath})
+		return
+	}
 	
 	// Analyze the specific file
-	analyzerFileInfo, err := s.analyzer.AnalyzeSingleFile(repoPath, filePath)
+	analyzerFileInfo, err := s.analyzer.AnalyzeSingleFile(r.Context(), repoPath, filePath)
 	if err != nil {
-		fmt.Printf("Failed to analyze file %s: %v\n", filePath, err)
+		s.logger.WarnContext(r.Context(), "Failed to analyze file", "file", filePath, "error", err)
+
+		// Timeouts are reported instead of silently serving the file without references
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+			s.writeOperationError(w, r, CodeAnalysisFailed, "Failed to analyze file", err)
+			return
+		}
 	} else {
-		fmt.Printf("Returning analyzed file info with %d symbols and %d references\n", 
-			len(analyzerFileInfo.Symbols), len(analyzerFileInfo.References))
+		s.logger.DebugContext(r.Context(), "Analyzed file", "file", filePath,
+			"symbols", len(analyzerFileInfo.Symbols), "references", len(analyzerFileInfo.References))
 		
 		// Convert analyzer format to frontend-expected format with scope-aware data
 		frontendFileInfo := map[string]interface{}{
@@ -271,84 +343,194 @@ func (s *Server) handleFile(w http.ResponseWriter, r *http.Request) {
 		if analyzerFileInfo.Definitions != nil && len(analyzerFileInfo.Definitions) > 0 {
 			frontendFileInfo["definitions"] = analyzerFileInfo.Definitions
 		}
-		
-		fmt.Printf("Converted to frontend format with %d references\n", 
-			len(analyzerFileInfo.References))
-		
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(frontendFileInfo)
-		return
-	}
 
-	// Fallback: read file manually if not in analysis
-	if _, err := os.Stat(fullPath); os.IsNotExist(err) {
-		fmt.Printf("File does not exist at: '%s'\n", fullPath)
-		
-		// List files in the directory for debugging
-		dir := filepath.Dir(fullPath)
-		fmt.Printf("Files in directory '%s':\n", dir)
-		if entries, err := os.ReadDir(dir); err == nil {
-			for _, entry := range entries {
-				fmt.Printf("  - %s\n", entry.Name())
-			}
+		if len(analyzerFileInfo.Outline) > 0 {
+			frontendFileInfo["outline"] = analyzerFileInfo.Outline
+		}
+
+		if analyzerFileInfo.SemanticTokens != nil {
+			frontendFileInfo["semanticTokens"] = analyzerFileInfo.SemanticTokens
+		}
+
+		if len(analyzerFileInfo.Diagnostics) > 0 {
+			frontendFileInfo["diagnostics"] = analyzerFileInfo.Diagnostics
 		}
 		
-		http.Error(w, fmt.Sprintf("File not found: %s", filePath), http.StatusNotFound)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(frontendFileInfo)
 		return
 	}
 
-	// Simple file content without analysis
+	// Fallback: simple file content without analysis, from the resolved path
 	content, err := os.ReadFile(fullPath)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to read file: %v", err), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, CodeInternal, fmt.Sprintf("Failed to read file: %v", err), &ErrorDetails{Path: filePath})
 		return
 	}
 
-	// Return basic file info without cross-references
+	// Return basic file info without cross-references, outlined from the syntax alone
 	basicFileInfo := map[string]interface{}{
 		"source":     string(content),
 		"references": make([]interface{}, 0),
 	}
+	if outline := analyzer.ParseOutline(filePath, content); len(outline) > 0 {
+		basicFileInfo["outline"] = outline
+	}
+	if diagnostics := analyzer.ParseDiagnostics(filePath, content); len(diagnostics) > 0 {
+		basicFileInfo["diagnostics"] = diagnostics
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(basicFileInfo)
 }
 
-
-func (s *Server) setupRoutes() *http.ServeMux {
+func (s *Server) setupRoutes() http.Handler {
 	mux := http.NewServeMux()
 
-	// API routes
-	mux.HandleFunc("/api/repo/", s.handleRepo)
-	mux.HandleFunc("/api/package/", s.handlePackage)
-	mux.HandleFunc("/api/file/", s.handleFile)
+	// API routes, with their durations recorded by endpoint
+	mux.Handle("/api/repo/", instrument("/api/repo/", http.HandlerFunc(s.handleRepo)))
+	mux.Handle("/api/versions/", instrument("/api/versions/", http.HandlerFunc(s.handleVersions)))
+	mux.Handle("/api/package/", instrument("/api/package/", http.HandlerFunc(s.handlePackage)))
+	mux.Handle("/api/file/", instrument("/api/file/", http.HandlerFunc(s.handleFile)))
+	mux.Handle("/api/shadowing/", instrument("/api/shadowing/", http.HandlerFunc(s.handleShadowing)))
+	mux.Handle("/api/hierarchy/", instrument("/api/hierarchy/", http.HandlerFunc(s.handleHierarchy)))
+	mux.Handle("/api/deprecated/", instrument("/api/deprecated/", http.HandlerFunc(s.handleDeprecated)))
+
+	// Administration, optionally protected by a token
+	mux.Handle("/api/admin/", instrument("/api/admin/", http.HandlerFunc(s.handleAdmin)))
+
+	// Metrics in the Prometheus text format
+	mux.Handle("/metrics", metrics.Default.Handler())
 
 	// Serve static files for development
-	mux.Handle("/", http.FileServer(http.Dir("frontend/dist")))
+	mux.Handle("/", instrument("static", http.FileServer(http.Dir("frontend/dist"))))
+
+	return s.logRequests(mux)
+}
+
+// newRepoManager creates the repository manager described by the configuration
+func newRepoManager(cfg *config.Config, logger *slog.Logger) (*repo.Manager, error) {
+	return repo.NewManager(
+		repo.WithIsolation(true),
+		repo.WithLogger(logger),
+		repo.WithEnvConfig(cfg.Env),
+		repo.WithCacheDir(cfg.Cache.Dir),
+		repo.WithCachePolicy(repo.CachePolicy{
+			MaxSize: cfg.Cache.MaxSizeMB << 20,
+			TTL:     time.Duration(cfg.Cache.TTL),
+		}),
+		repo.WithTimeouts(repo.Timeouts{
+			Download:     time.Duration(cfg.Timeouts.Download),
+			ListVersions: time.Duration(cfg.Timeouts.Versions),
+		}),
+	)
+}
+
+// runCommand runs a command given on the command line instead of the server
+func runCommand(cfg *config.Config, args []string) error {
+	switch args[0] {
+	case "import":
+		// Seed the local module proxy from a directory of module zips
+		if len(args) != 2 {
+			return fmt.Errorf("usage: gonav -proxy-dir <dir> import <zip dir>")
+		}
+		if cfg.Env.ProxyDir == "" {
+			return fmt.Errorf("import requires a proxy directory (-proxy-dir)")
+		}
+		if err := os.MkdirAll(cfg.Env.ProxyDir, 0755); err != nil {
+			return fmt.Errorf("failed to create proxy directory: %w", err)
+		}
 
-	return mux
+		imported, err := env.ImportModuleZips(cfg.Env.ProxyDir, args[1])
+		for _, mod := range imported {
+			fmt.Printf("Imported %s@%s\n", mod.Path, mod.Version)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Imported %d modules into %s\n", len(imported), cfg.Env.ProxyDir)
+		return nil
+	case "clean":
+		// Remove the module cache, typically a persistent one. The cache directory holds
+		// the isolated environment, so nothing needs to be set up to find them.
+		cacheDir, err := repo.CacheDirFor(cfg.Cache.Dir)
+		if err != nil {
+			return err
+		}
+		if err := env.RemoveDir(cacheDir); err != nil {
+			return fmt.Errorf("failed to remove cache directory: %w", err)
+		}
+		fmt.Printf("Removed %s\n", cacheDir)
+		return nil
+	default:
+		return fmt.Errorf("unknown command: %s", args[0])
+	}
 }
 
 func main() {
-	// Parse command line flags (no custom flags, but this handles --help)
-	flag.Parse()
+	// Parse command line flags and the optional configuration file
+	cfg, args, err := config.Parse(os.Args[0], os.Args[1:])
+	if err != nil {
+		log.Fatal("Failed to load configuration:", err)
+	}
+
+	logger, err := logging.New(os.Stderr, cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		log.Fatal("Failed to create logger:", err)
+	}
+	slog.SetDefault(logger)
+
+	if len(args) > 0 {
+		if err := runCommand(cfg, args); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
 	// Create repository manager with isolated environment (always enabled)
-	repoManager, err := repo.NewManager(repo.WithIsolation(true))
+	repoManager, err := newRepoManager(cfg, logger)
 	if err != nil {
 		log.Fatal("Failed to create isolated repository manager:", err)
 	}
-	fmt.Println("Running with isolated Go environment")
+	logger.Info("Running with isolated Go environment", "cache_dir", repoManager.CacheDir())
+	if repoManager.IsOffline() {
+		logger.Info("Running in offline mode", "goproxy", cfg.Env.OfflineProxy())
+	}
 	
 	// Ensure cleanup on exit
 	defer func() {
-		fmt.Println("Cleaning up isolated environment...")
+		if count, err := repoManager.PopulateProxy(); err != nil {
+			logger.Warn("Failed to populate module proxy directory", "dir", cfg.Env.ProxyDir, "error", err)
+		} else if count > 0 {
+			logger.Info("Added module versions to proxy directory", "count", count, "dir", cfg.Env.ProxyDir)
+		}
+
+		// A persistent cache is only removed with the clean command
+		if cfg.Cache.Persistent {
+			logger.Info("Keeping module cache", "dir", repoManager.CacheDir())
+			return
+		}
+
+		logger.Info("Cleaning up isolated environment")
 		if err := repoManager.Cleanup(); err != nil {
-			fmt.Printf("Warning: failed to cleanup isolated environment: %v\n", err)
+			logger.Warn("Failed to clean up isolated environment", "error", err)
 		}
 	}()
 
-	server := NewServer(repoManager)
+	// Apply the cache policy in the background until shutdown
+	evictionCtx, stopEviction := context.WithCancel(context.Background())
+	defer stopEviction()
+	go repoManager.RunEviction(evictionCtx, time.Duration(cfg.Cache.Interval))
+
+	registerCacheMetrics(metrics.Default, repoManager)
+
+	server := NewServer(repoManager, logger)
+	registerServerMetrics(metrics.Default, server)
+	server.adminToken = cfg.Admin.Token
+	if server.adminToken == "" {
+		logger.Warn("Admin endpoints are not protected, set an admin token to require one")
+	}
+	server.analyzer.SetLoadTimeout(time.Duration(cfg.Timeouts.Analysis))
 	mux := server.setupRoutes()
 
 	port := os.Getenv("PORT")
@@ -367,11 +549,7 @@ func main() {
 
 	// Start server in a goroutine
 	go func() {
-		fmt.Printf("Server starting on port %s\n", port)
-		fmt.Printf("Frontend will be served from: frontend/dist\n")
-		fmt.Printf("API available at: /api/repo/{module@version} and /api/file/{module@version}/{path}\n")
-		fmt.Printf("Running in isolated mode\n")
-		fmt.Printf("Running with enhanced golang.org/x/tools/go/packages analysis\n")
+		logger.Info("Server starting", "port", port, "frontend", "frontend/dist")
 		
 		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Fatal("Server failed to start:", err)
@@ -380,7 +558,7 @@ func main() {
 
 	// Wait for interrupt signal
 	<-stop
-	fmt.Println("Shutting down server...")
+	logger.Info("Shutting down server")
 
 	// Create context with timeout for graceful shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -390,6 +568,9 @@ func main() {
 	if err := httpServer.Shutdown(ctx); err != nil {
 		log.Fatal("Server forced to shutdown:", err)
 	}
+	if err := server.dependencyQueue.Shutdown(5 * time.Second); err != nil {
+		logger.Warn("Failed to stop dependency downloads", "error", err)
+	}
 
-	fmt.Println("Server stopped gracefully")
+	logger.Info("Server stopped gracefully")
 }
\ No newline at end of file
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"gonav/internal/analyzer"
+	"gonav/internal/metrics"
+	"gonav/internal/repo"
+)
+
+var requestDuration = metrics.Default.NewHistogramVec("gonav_http_request_duration_seconds",
+	"Duration of HTTP requests, by endpoint, method and status.", metrics.DefaultBuckets, "endpoint", "method", "status")
+
+// instrument records the duration of the requests served by handler in the request
+// duration histogram, labeled with endpoint
+func instrument(endpoint string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w}
+		handler.ServeHTTP(recorder, r)
+
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		requestDuration.With(endpoint, r.Method, strconv.Itoa(status)).Observe(time.Since(start).Seconds())
+	})
+}
+
+// cacheUsageInterval is how long the disk usage of the cache is reused before being
+// measured again, as measuring walks the whole module cache
+const cacheUsageInterval = 30 * time.Second
+
+// registerCacheMetrics exposes in registry the disk usage of the isolated environment
+// of repoManager, from its statistics
+func registerCacheMetrics(registry *metrics.Registry, repoManager *repo.Manager) {
+	isolatedEnv := repoManager.GetIsolatedEnv()
+	if isolatedEnv == nil {
+		return
+	}
+
+	var mu sync.Mutex
+	var measured time.Time
+	var stats map[string]interface{}
+	stat := func(key string) func() []metrics.Sample {
+		return func() []metrics.Sample {
+			mu.Lock()
+			defer mu.Unlock()
+			if stats == nil || time.Since(measured) > cacheUsageInterval {
+				stats = isolatedEnv.Stats()
+				measured = time.Now()
+			}
+			switch value := stats[key].(type) {
+			case int:
+				return []metrics.Sample{{Value: float64(value)}}
+			case int64:
+				return []metrics.Sample{{Value: float64(value)}}
+			}
+			return nil
+		}
+	}
+
+	registry.NewFunc("gonav_cache_disk_usage_bytes",
+		"Disk space used by the isolated environment, module and build caches included.", metrics.TypeGauge, nil, stat("disk_usage_bytes"))
+	registry.NewFunc("gonav_cache_module_bytes",
+		"Disk space used by the downloaded module versions.", metrics.TypeGauge, nil, stat("module_cache_bytes"))
+	registry.NewFunc("gonav_cache_modules",
+		"Module versions in the module cache.", metrics.TypeGauge, nil, stat("cached_modules"))
+}
+
+// registerServerMetrics exposes in registry the modules kept in memory by the analyzer
+// of s, the dependency loading jobs of its dependency loader and the statistics of its
+// dependency queue
+func registerServerMetrics(registry *metrics.Registry, s *Server) {
+	registry.NewFunc("gonav_loaded_modules",
+		"Modules whose packages are kept in memory by the analyzer.", metrics.TypeGauge, nil, func() []metrics.Sample {
+			return []metrics.Sample{{Value: float64(s.analyzer.LoadedModules())}}
+		})
+	registry.NewFunc("gonav_dependency_jobs",
+		"Dependency loading jobs, running or finished, by status.", metrics.TypeGauge, []string{"status"}, func() []metrics.Sample {
+			return dependencyJobSamples(s.dependencyLoader.ListActiveJobs())
+		})
+
+	queueStat := func(value func(stats analyzer.DependencyQueueStats) float64) func() []metrics.Sample {
+		return func() []metrics.Sample {
+			return []metrics.Sample{{Value: value(s.dependencyQueue.GetStats())}}
+		}
+	}
+	registry.NewFunc("gonav_dependency_queue_requests_total",
+		"Dependency download requests submitted to the queue.", metrics.TypeCounter, nil,
+		queueStat(func(stats analyzer.DependencyQueueStats) float64 { return float64(stats.TotalRequests) }))
+	registry.NewFunc("gonav_dependency_queue_completed_total",
+		"Dependency download requests processed by the queue, failed ones included.", metrics.TypeCounter, nil,
+		queueStat(func(stats analyzer.DependencyQueueStats) float64 { return float64(stats.CompletedRequests) }))
+	registry.NewFunc("gonav_dependency_queue_failed_total",
+		"Dependency download requests with at least one failed download.", metrics.TypeCounter, nil,
+		queueStat(func(stats analyzer.DependencyQueueStats) float64 { return float64(stats.FailedRequests) }))
+	registry.NewFunc("gonav_dependency_queue_active_downloads",
+		"Dependency download requests being processed.", metrics.TypeGauge, nil,
+		queueStat(func(stats analyzer.DependencyQueueStats) float64 { return float64(stats.ActiveDownloads) }))
+	registry.NewFunc("gonav_dependency_queue_length",
+		"Dependency download requests waiting in the queue.", metrics.TypeGauge, nil,
+		queueStat(func(stats analyzer.DependencyQueueStats) float64 { return float64(stats.QueueLength) }))
+	registry.NewFunc("gonav_dependency_queue_average_duration_seconds",
+		"Average processing time of the dependency download requests.", metrics.TypeGauge, nil,
+		queueStat(func(stats analyzer.DependencyQueueStats) float64 { return stats.AverageTime.Seconds() }))
+}
+
+// dependencyJobSamples counts jobs by status
+func dependencyJobSamples(jobs []*analyzer.LoadingJob) []metrics.Sample {
+	counts := make(map[analyzer.LoadingStatus]int)
+	for _, job := range jobs {
+		counts[job.Status]++
+	}
+	samples := make([]metrics.Sample, 0, len(counts))
+	for status, count := range counts {
+		samples = append(samples, metrics.Sample{LabelValues: []string{string(status)}, Value: float64(count)})
+	}
+	return samples
+}
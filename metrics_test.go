@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gonav/internal/analyzer"
+	"gonav/internal/metrics"
+)
+
+func TestInstrument(t *testing.T) {
+	handler := instrument("/api/test/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/test/missing" {
+			writeError(w, http.StatusNotFound, CodeFileNotFound, "File not found", nil)
+			return
+		}
+		w.Write([]byte("{}"))
+	}))
+
+	for _, path := range []string{"/api/test/a", "/api/test/b", "/api/test/missing"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(t, uint64(2), requestDuration.With("/api/test/", http.MethodGet, "200").Count())
+	assert.Equal(t, uint64(1), requestDuration.With("/api/test/", http.MethodGet, "404").Count())
+
+	recorder := httptest.NewRecorder()
+	metrics.Default.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := recorder.Body.String()
+	assert.Contains(t, body, `gonav_http_request_duration_seconds_count{endpoint="/api/test/",method="GET",status="404"} 1`)
+	for _, name := range []string{"gonav_packages_load_duration_seconds", "gonav_module_load_results_total",
+		"gonav_module_downloads_total", "gonav_analysis_cache_results_total"} {
+		assert.Contains(t, body, "# TYPE "+name+" ")
+	}
+}
+
+func TestDependencyJobSamples(t *testing.T) {
+	samples := dependencyJobSamples([]*analyzer.LoadingJob{
+		{ID: "a", Status: analyzer.LoadingStatusInProgress},
+		{ID: "b", Status: analyzer.LoadingStatusComplete},
+		{ID: "c", Status: analyzer.LoadingStatusComplete},
+	})
+	assert.ElementsMatch(t, []metrics.Sample{
+		{LabelValues: []string{"in_progress"}, Value: 1},
+		{LabelValues: []string{"complete"}, Value: 2},
+	}, samples)
+}
+
+func TestRegisterServerMetrics(t *testing.T) {
+	registry := metrics.NewRegistry()
+	registerServerMetrics(registry, newAdminTestServer(t, ""))
+
+	recorder := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := recorder.Body.String()
+	for _, sample := range []string{"gonav_loaded_modules 0", "gonav_dependency_queue_requests_total 0",
+		"gonav_dependency_queue_completed_total 0", "gonav_dependency_queue_failed_total 0",
+		"gonav_dependency_queue_active_downloads 0", "gonav_dependency_queue_length 0",
+		"gonav_dependency_queue_average_duration_seconds 0"} {
+		assert.Contains(t, body, sample+"\n")
+	}
+}
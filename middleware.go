@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"gonav/internal/logging"
+)
+
+// RequestIDHeader carries the ID of a request, given by the client or generated
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds request IDs given by clients
+const maxRequestIDLength = 64
+
+// statusRecorder records the status written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// logRequests assigns an ID to every request, returned in the X-Request-ID header
+// and carried by the request context, and logs the requests once served
+func (s *Server) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		requestID := sanitizeRequestID(r.Header.Get(RequestIDHeader))
+		if requestID == "" {
+			requestID = logging.NewRequestID()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+		r = r.WithContext(logging.WithRequestID(r.Context(), requestID))
+
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		s.logger.InfoContext(r.Context(), "Served request", "method", r.Method, "path", r.URL.Path,
+			"status", status, "duration", time.Since(start))
+	})
+}
+
+// sanitizeRequestID returns the request ID given by a client, or "" if it is too
+// long or contains characters other than letters, digits, '-', '_' and '.'
+func sanitizeRequestID(id string) string {
+	if len(id) > maxRequestIDLength {
+		return ""
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return ""
+		}
+	}
+	return id
+}
+
+// beginGet sets the CORS headers of the read-only API endpoints and reports whether
+// the request should be served. Preflight requests are answered with the headers
+// alone, and methods other than GET are rejected.
+func beginGet(w http.ResponseWriter, r *http.Request) bool {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-Request-ID")
+
+	if r.Method == http.MethodOptions {
+		return false
+	}
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w)
+		return false
+	}
+	return true
+}
+
+// decodeAPIPath returns the request path following prefix, unescaped once more the
+// same way by every endpoint, so that a module@version decodes identically everywhere
+func decodeAPIPath(r *http.Request, prefix string) (string, error) {
+	return url.QueryUnescape(strings.TrimPrefix(r.URL.Path, prefix))
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gonav/internal/logging"
+)
+
+func TestLogRequests(t *testing.T) {
+	var output bytes.Buffer
+	logger, err := logging.New(&output, "info", logging.FormatJSON)
+	require.NoError(t, err)
+
+	var handlerID string
+	server := &Server{logger: logger}
+	handler := server.logRequests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerID = logging.RequestID(r.Context())
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	tests := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{name: "generated", header: "", keep: false},
+		{name: "from client", header: "client-id_1.2", keep: true},
+		{name: "invalid characters", header: "id\nforged=1", keep: false},
+		{name: "too long", header: strings.Repeat("a", maxRequestIDLength+1), keep: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output.Reset()
+			request := httptest.NewRequest(http.MethodGet, "/api/repo/example.com/lib@v1.0.0", nil)
+			if tt.header != "" {
+				request.Header.Set(RequestIDHeader, tt.header)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			requestID := recorder.Header().Get(RequestIDHeader)
+			require.NotEmpty(t, requestID)
+			assert.Equal(t, requestID, handlerID)
+			if tt.keep {
+				assert.Equal(t, tt.header, requestID)
+			} else {
+				assert.NotEqual(t, tt.header, requestID)
+			}
+
+			var record map[string]any
+			require.NoError(t, json.Unmarshal(output.Bytes(), &record))
+			assert.Equal(t, "Served request", record["msg"])
+			assert.Equal(t, requestID, record[logging.RequestIDKey])
+			assert.Equal(t, "/api/repo/example.com/lib@v1.0.0", record["path"])
+			assert.Equal(t, float64(http.StatusTeapot), record["status"])
+		})
+	}
+}
+
+func TestBeginGet(t *testing.T) {
+	server := newAdminTestServer(t, "")
+	handler := server.setupRoutes()
+
+	// Every read-only endpoint answers preflight requests and rejects other methods alike
+	for _, prefix := range []string{"/api/repo/", "/api/versions/", "/api/package/", "/api/file/",
+		"/api/shadowing/", "/api/hierarchy/", "/api/deprecated/"} {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodOptions, prefix+"example.com/lib@v1.0.0", nil))
+		assert.Equal(t, http.StatusOK, recorder.Code, prefix)
+		assert.Equal(t, "*", recorder.Header().Get("Access-Control-Allow-Origin"), prefix)
+		assert.Equal(t, "GET, OPTIONS", recorder.Header().Get("Access-Control-Allow-Methods"), prefix)
+
+		recorder = httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, prefix+"example.com/lib@v1.0.0", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code, prefix)
+	}
+}
+
+func TestDecodeAPIPath(t *testing.T) {
+	for path, expected := range map[string]string{
+		"/api/file/example.com/lib@v1.0.0/lib.go":                "example.com/lib@v1.0.0/lib.go",
+		"/api/file/example.com%2Flib%40v1.0.0/lib.go":            "example.com/lib@v1.0.0/lib.go",
+		"/api/file/example.com/lib@v1.0.0%252Bincompatible/a.go": "example.com/lib@v1.0.0+incompatible/a.go",
+		"/api/file/example.com/lib@v1.0.0+build/a.go":            "example.com/lib@v1.0.0 build/a.go",
+	} {
+		decoded, err := decodeAPIPath(httptest.NewRequest(http.MethodGet, path, nil), "/api/file/")
+		require.NoError(t, err, path)
+		assert.Equal(t, expected, decoded, path)
+	}
+
+	// The router already decoded the path once, so '+' must reach it as %252B. A
+	// module@version decodes the same on every endpoint taking one.
+	server := newAdminTestServer(t, "")
+	handler := server.setupRoutes()
+	for _, path := range []string{
+		"/api/file/example.com/lib@v1.0.0%252Bincompatible/lib.go",
+		"/api/package/example.com/lib@v1.0.0%252Bincompatible",
+		"/api/shadowing/example.com/lib@v1.0.0%252Bincompatible",
+		"/api/hierarchy/example.com/lib@v1.0.0%252Bincompatible?type=T",
+		"/api/deprecated/example.com/lib@v1.0.0%252Bincompatible",
+	} {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusNotFound, recorder.Code, path)
+		apiErr := decodeError(t, recorder)
+		assert.Equal(t, CodeRepoNotLoaded, apiErr.Code, path)
+		assert.Contains(t, apiErr.Message, "example.com/lib@v1.0.0+incompatible", path)
+	}
+}
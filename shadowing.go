@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"gonav/internal/analyzer"
+)
+
+// ShadowingReport is the body of GET /api/shadowing/{module@version}
+type ShadowingReport struct {
+	Module   string                         `json:"module"`
+	Name     string                         `json:"name,omitempty"` // Name filter, if any
+	Count    int                            `json:"count"`
+	Shadowed []*analyzer.ShadowedDefinition `json:"shadowed"`
+}
+
+// handleShadowing lists the definitions of a loaded repository that shadow an outer
+// declaration, in every module of the repository. ?name=err restricts the report to
+// definitions with that name.
+func (s *Server) handleShadowing(w http.ResponseWriter, r *http.Request) {
+	if !beginGet(w, r) {
+		return
+	}
+
+	moduleAtVersion, err := decodeAPIPath(r, "/api/shadowing/")
+	if err != nil || !strings.Contains(moduleAtVersion, "@") {
+		writeError(w, http.StatusBadRequest, CodeInvalidModuleVersion, "Invalid module@version format", nil)
+		return
+	}
+	name := r.URL.Query().Get("name")
+
+	s.logger.InfoContext(r.Context(), "Reporting shadowed definitions", "module", moduleAtVersion, "name", name)
+	defer s.repoManager.Acquire(moduleAtVersion)()
+
+	repoPath := s.repoManager.GetRepositoryPath(moduleAtVersion)
+	if repoPath == "" {
+		writeRepoNotLoaded(w, moduleAtVersion)
+		return
+	}
+
+	shadowed, err := s.analyzer.ShadowingReport(r.Context(), repoPath, name)
+	if err != nil {
+		s.writeOperationError(w, r, CodeAnalysisFailed, "Failed to analyze module", err)
+		return
+	}
+
+	writeJSON(w, ShadowingReport{
+		Module:   moduleAtVersion,
+		Name:     name,
+		Count:    len(shadowed),
+		Shadowed: shadowed,
+	})
+}
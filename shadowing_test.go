@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShadowingEndpoint(t *testing.T) {
+	server := newFixtureServer(t, "example.com/lib@v1.0.0", map[string]string{
+		"lib.go": `package lib
+
+import "errors"
+
+func Parse() error {
+	var err error
+	if true {
+		err := errors.New("inner")
+		_ = err
+	}
+	return err
+}
+
+func Count() int {
+	n := 0
+	return n
+}
+`,
+	})
+
+	var report ShadowingReport
+	getJSON(t, server, "/api/shadowing/example.com/lib@v1.0.0?name=err", &report)
+	assert.Equal(t, "example.com/lib@v1.0.0", report.Module)
+	assert.Equal(t, "err", report.Name)
+	assert.Equal(t, 1, report.Count)
+	require.Len(t, report.Shadowed, 1)
+	assert.Equal(t, "lib.go", report.Shadowed[0].File)
+	assert.Equal(t, "err", report.Shadowed[0].Name)
+	assert.Equal(t, 8, report.Shadowed[0].Line)
+}
+
+func TestShadowingEndpointErrors(t *testing.T) {
+	server := newAdminTestServer(t, "")
+	checkEndpointErrors(t, server.setupRoutes(), []endpointError{
+		{http.MethodGet, "/api/shadowing/example.com/lib@v1.0.0?name=err", http.StatusNotFound, CodeRepoNotLoaded},
+		{http.MethodGet, "/api/shadowing/example.com/lib", http.StatusBadRequest, CodeInvalidModuleVersion},
+		{http.MethodPost, "/api/shadowing/example.com/lib@v1.0.0", http.StatusMethodNotAllowed, CodeMethodNotAllowed},
+	})
+}